@@ -0,0 +1,298 @@
+// Package adjust computes split and dividend adjusted prices from raw bars
+// and a list of corporate actions, for data sets that do not ship adjusted
+// columns like WIKI does. Factors follow the CRSP method used by WIKI: a
+// split of ratio r scales earlier prices by 1/r, a dividend d scales them by
+// 1 - d/c where c is the close before the ex-date. Volumes are divided by
+// the same factor so that traded value is unchanged.
+//
+// Backward adjustment leaves the latest prices as traded and scales history,
+// which is what WIKI's Adj. columns hold. Forward adjustment leaves the
+// earliest prices as traded and scales later ones.
+package adjust
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+)
+
+// Methods
+const (
+	Backward = "backward"
+	Forward  = "forward"
+)
+
+// Methods lists the supported methods
+var Methods = []string{Backward, Forward}
+
+// Options configures an adjustment
+type Options struct {
+	// Method is Backward or Forward, Backward if empty
+	Method string
+	// SplitsOnly ignores dividends
+	SplitsOnly bool
+}
+
+// Factors returns the factor the prices of each date are multiplied by.
+// Dates are YYYY-MM-DD in any order; closes are the raw closes of the dates,
+// used to size dividends, and nil closes are skipped. Dividends without a
+// positive close before their ex-date, or at least as large as that close,
+// cannot be sized and are ignored.
+func Factors(dates []string, closes []*float64, acts []corporate.Action, o Options) ([]float64, error) {
+	if len(closes) != len(dates) {
+		return nil, fmt.Errorf("%d closes for %d dates", len(closes), len(dates))
+	}
+	switch o.Method {
+	case "", Backward, Forward:
+	default:
+		return nil, fmt.Errorf("unknown method %q, options are %s", o.Method, strings.Join(Methods, ", "))
+	}
+
+	order := make([]int, len(dates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return dates[order[a]] < dates[order[b]] })
+
+	// factor of each action, the dividends sized by the close before them
+	steps := make([]float64, len(acts))
+	for n, a := range acts {
+		steps[n] = 1
+		switch a.Type {
+		case corporate.Split:
+			if a.Ratio > 0 {
+				steps[n] = 1 / a.Ratio
+			}
+		case corporate.Dividend:
+			if o.SplitsOnly {
+				continue
+			}
+			prev := previousClose(dates, closes, order, a.Date)
+			if prev > 0 && a.Amount < prev {
+				steps[n] = 1 - a.Amount/prev
+			}
+		}
+	}
+
+	factors := make([]float64, len(dates))
+	for _, i := range order {
+		f := 1.0
+		for n, a := range acts {
+			if a.Date > dates[i] {
+				f *= steps[n]
+			}
+		}
+		factors[i] = f
+	}
+
+	if o.Method == Forward && len(order) > 0 {
+		first := factors[order[0]]
+		for i := range factors {
+			factors[i] /= first
+		}
+	}
+	return factors, nil
+}
+
+// previousClose returns the last close dated before date, or 0
+func previousClose(dates []string, closes []*float64, order []int, date string) float64 {
+	prev := 0.0
+	for _, i := range order {
+		if dates[i] >= date {
+			break
+		}
+		if closes[i] != nil {
+			prev = *closes[i]
+		}
+	}
+	return prev
+}
+
+// Wiki returns copies of objs, in the same order, with the Adj. columns
+// computed from the raw columns and acts, e.g. from corporate.Extract
+func Wiki(objs []api.Wiki, acts []corporate.Action, o Options) ([]api.Wiki, error) {
+	dates := make([]string, len(objs))
+	closes := make([]*float64, len(objs))
+	for i, obj := range objs {
+		if obj.Date != nil {
+			dates[i] = *obj.Date
+		}
+		closes[i] = obj.Close
+	}
+	factors, err := Factors(dates, closes, acts, o)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]api.Wiki, len(objs))
+	for i, obj := range objs {
+		f := factors[i]
+		obj.AdjOpen = scale(obj.Open, f)
+		obj.AdjHigh = scale(obj.High, f)
+		obj.AdjLow = scale(obj.Low, f)
+		obj.AdjClose = scale(obj.Close, f)
+		obj.AdjVolume = scale(obj.Volume, 1/f)
+		out[i] = obj
+	}
+	return out, nil
+}
+
+// Prices and Volumes are the columns Table adjusts by default
+var (
+	Prices  = []string{"Open", "High", "Low", "Close", "Settle"}
+	Volumes = []string{"Volume", "Total Volume", "TotalVolume"}
+)
+
+// Table sets an adjusted column for each of the Prices and Volumes columns of
+// t, named e.g. AdjClose, replacing it if t has one and adding it after the
+// other columns otherwise. Dividends are sized by the Close column, or Settle
+// if there is none. It works on tables of any database, e.g. CBOETable or
+// DataSetTable.
+func Table(t *api.Table, acts []corporate.Action, o Options) error {
+	closeCol := t.Column("Close")
+	if closeCol < 0 {
+		closeCol = t.Column("Settle")
+	}
+
+	dates := make([]string, len(t.Rows))
+	closes := make([]*float64, len(t.Rows))
+	for i, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return fmt.Errorf("%s: row has %d values, want %d", t.Symbol, len(row), len(t.Columns))
+		}
+		dates[i], _ = row[0].(string)
+		if closeCol >= 0 {
+			if v, ok := row[closeCol].(float64); ok {
+				closes[i] = &v
+			}
+		}
+	}
+	factors, err := Factors(dates, closes, acts, o)
+	if err != nil {
+		return err
+	}
+
+	type column struct {
+		from, to int
+		volume   bool
+	}
+	cols := []column{}
+	names := make([]string, len(t.Columns))
+	copy(names, t.Columns)
+	for i, name := range t.Columns {
+		c := column{from: i, to: t.Column("Adj" + squash(name))}
+		switch {
+		case contains(Prices, name):
+		case contains(Volumes, name):
+			c.volume = true
+		default:
+			continue
+		}
+		if c.to < 0 {
+			c.to = len(names)
+			names = append(names, "Adj"+squash(name))
+		}
+		cols = append(cols, c)
+	}
+	t.Columns = names
+
+	for i, row := range t.Rows {
+		row = append(row, make([]interface{}, len(names)-len(row))...)
+		for _, c := range cols {
+			f := factors[i]
+			if c.volume {
+				f = 1 / f
+			}
+			var v interface{}
+			if x, ok := row[c.from].(float64); ok {
+				v = x * f
+			}
+			row[c.to] = v
+		}
+		t.Rows[i] = row
+	}
+	return nil
+}
+
+// Difference is the largest relative difference between two adjusted series
+type Difference struct {
+	Date     string
+	Column   string
+	Want     float64
+	Got      float64
+	Relative float64
+}
+
+// Compare returns the largest relative difference between the Adj. columns
+// of want, e.g. as shipped by WIKI, and got, e.g. returned by Wiki. Rows are
+// matched by date; values missing on either side are not compared.
+func Compare(want, got []api.Wiki) Difference {
+	byDate := make(map[string]api.Wiki, len(got))
+	for _, obj := range got {
+		if obj.Date != nil {
+			byDate[*obj.Date] = obj
+		}
+	}
+
+	var d Difference
+	for _, w := range want {
+		if w.Date == nil {
+			continue
+		}
+		g, ok := byDate[*w.Date]
+		if !ok {
+			continue
+		}
+		for _, c := range []struct {
+			name      string
+			want, got *float64
+		}{
+			{"AdjOpen", w.AdjOpen, g.AdjOpen}, {"AdjHigh", w.AdjHigh, g.AdjHigh}, {"AdjLow", w.AdjLow, g.AdjLow},
+			{"AdjClose", w.AdjClose, g.AdjClose}, {"AdjVolume", w.AdjVolume, g.AdjVolume},
+		} {
+			if c.want == nil || c.got == nil {
+				continue
+			}
+			rel := math.Abs(*c.got - *c.want)
+			if *c.want != 0 {
+				rel /= math.Abs(*c.want)
+			}
+			if rel > d.Relative {
+				d = Difference{Date: *w.Date, Column: c.name, Want: *c.want, Got: *c.got, Relative: rel}
+			}
+		}
+	}
+	return d
+}
+
+func scale(v *float64, f float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	x := *v * f
+	return &x
+}
+
+// squash drops the spaces and dots of a column name, e.g. "Total Volume"
+func squash(name string) string {
+	out := []rune{}
+	for _, r := range name {
+		if r != ' ' && r != '.' {
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package adjust_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAdjust(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Adjust Suite")
+}
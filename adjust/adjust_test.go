@@ -0,0 +1,170 @@
+package adjust_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/twold/go-quandl/adjust"
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/internal/fixture"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// wiki returns a row as WIKI ships it, newest first, with the adjusted
+// columns of the raw ones times factor
+func wiki(date string, open, high, low, close, volume, dividend, split, factor float64) api.Wiki {
+	return api.Wiki{
+		Date: &date, Open: fixture.Float(open), High: fixture.Float(high), Low: fixture.Float(low), Close: fixture.Float(close), Volume: fixture.Float(volume),
+		ExDividend: fixture.Float(dividend), SplitRatio: fixture.Float(split),
+		AdjOpen: fixture.Float(open * factor), AdjHigh: fixture.Float(high * factor), AdjLow: fixture.Float(low * factor), AdjClose: fixture.Float(close * factor),
+		AdjVolume: fixture.Float(volume / factor),
+	}
+}
+
+// aapl is a WIKI/AAPL download around the 7-for-1 split of 2014-06-09
+// and the 3.29 dividend going ex on 2014-05-08, as saved by
+//
+//	curl -o testdata/WIKI-AAPL-2014.csv "https://www.quandl.com/api/v3/datasets/WIKI/AAPL.csv?start_date=2014-04-01&end_date=2014-07-31&api_key=$QUANDL_API_KEY"
+var aapl = filepath.Join("testdata", "WIKI-AAPL-2014.csv")
+
+// readWiki reads the rows of a Quandl csv download of a WIKI data set
+func readWiki(name string) ([]api.Wiki, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	recs, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]api.Wiki, 0, len(recs))
+	for _, rec := range recs[1:] {
+		values := make([]*float64, len(rec))
+		for i, v := range rec[1:] {
+			x, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = &x
+		}
+		date := rec[0]
+		objs = append(objs, api.Wiki{
+			Date: &date, Open: values[0], High: values[1], Low: values[2], Close: values[3], Volume: values[4],
+			ExDividend: values[5], SplitRatio: values[6],
+			AdjOpen: values[7], AdjHigh: values[8], AdjLow: values[9], AdjClose: values[10], AdjVolume: values[11],
+		})
+	}
+	return objs, nil
+}
+
+var _ = Describe("Adjust", func() {
+	// a 2.08 dividend on a 104 close, factor 0.98, then a 2-for-1 split
+	rows := []api.Wiki{
+		wiki("2018-01-08", 52.5, 54, 52, 53, 3000, 0, 1, 1),
+		wiki("2018-01-05", 51, 53, 50, 52, 4000, 0, 2, 1),
+		wiki("2018-01-04", 103, 104, 101, 103, 500, 2.08, 1, 0.5),
+		wiki("2018-01-03", 100, 105, 99, 104, 980, 0, 1, 0.49),
+		wiki("2018-01-02", 98, 101, 97, 100, 4900, 0, 1, 0.49),
+	}
+	acts := corporate.Extract("FB", rows)
+
+	strip := func(objs []api.Wiki) []api.Wiki {
+		out := make([]api.Wiki, len(objs))
+		for i, obj := range objs {
+			obj.AdjOpen, obj.AdjHigh, obj.AdjLow, obj.AdjClose, obj.AdjVolume = nil, nil, nil, nil, nil
+			out[i] = obj
+		}
+		return out
+	}
+
+	It("should reproduce the adjusted columns of WIKI", func() {
+		got, err := Wiki(strip(rows), acts, Options{})
+		Expect(err).Should(BeNil())
+		Expect(len(got)).Should(Equal(len(rows)))
+		Expect(*got[4].Date).Should(Equal("2018-01-02"))
+		Expect(*got[4].AdjClose).Should(BeNumerically("~", 49, 1e-9))
+		Expect(*got[4].AdjVolume).Should(BeNumerically("~", 10000, 1e-6))
+		Expect(*got[0].AdjClose).Should(Equal(53.0))
+
+		d := Compare(rows, got)
+		Expect(d.Relative).Should(BeNumerically("<", 1e-12), "%+v", d)
+		Expect(rows[0].AdjClose).ShouldNot(BeNil())
+	})
+
+	It("should match the adjusted closes of WIKI around a split and a dividend", func() {
+		objs, err := readWiki(aapl)
+		if os.IsNotExist(err) {
+			Skip(aapl + " is not downloaded")
+		}
+		Expect(err).Should(BeNil())
+		Expect(len(objs)).Should(BeNumerically(">", 40))
+
+		got, err := Wiki(strip(objs), corporate.Extract("AAPL", objs), Options{})
+		Expect(err).Should(BeNil())
+
+		// WIKI adjusts for the actions after the download too, which scale
+		// every row alike
+		k := *objs[0].AdjClose / *objs[0].Close
+		for i := range got {
+			Expect(*got[i].AdjClose*k).Should(BeNumerically("~", *objs[i].AdjClose, 1e-4**objs[i].AdjClose), *objs[i].Date)
+		}
+	})
+
+	It("should report the largest difference", func() {
+		got, err := Wiki(strip(rows), acts, Options{SplitsOnly: true})
+		Expect(err).Should(BeNil())
+		Expect(*got[4].AdjClose).Should(BeNumerically("~", 50, 1e-9))
+
+		d := Compare(rows, got)
+		Expect([]string{"2018-01-02", "2018-01-03"}).Should(ContainElement(d.Date))
+		Expect(d.Relative).Should(BeNumerically("~", 0.02/0.98, 1e-9))
+	})
+
+	It("should adjust forward from the first price", func() {
+		got, err := Wiki(strip(rows), acts, Options{Method: Forward})
+		Expect(err).Should(BeNil())
+		Expect(*got[4].AdjClose).Should(Equal(100.0))
+		Expect(*got[2].AdjClose).Should(BeNumerically("~", 103/0.98, 1e-9))
+		Expect(*got[0].AdjClose).Should(BeNumerically("~", 53/0.49, 1e-9))
+		Expect(*got[0].AdjVolume).Should(BeNumerically("~", 3000*0.49, 1e-9))
+	})
+
+	It("should ignore dividends it cannot size", func() {
+		factors, err := Factors([]string{"2018-01-02", "2018-01-03"}, []*float64{nil, fixture.Float(10)},
+			[]corporate.Action{{Date: "2018-01-03", Type: corporate.Dividend, Amount: 1}}, Options{})
+		Expect(err).Should(BeNil())
+		Expect(factors).Should(Equal([]float64{1, 1}))
+
+		_, err = Factors(nil, nil, nil, Options{Method: "sideways"})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should adjust tables of databases without adjusted columns", func() {
+		date1, date2 := "2018-01-02", "2018-01-03"
+		t := api.CBOETable("VX1", []api.CBOE{
+			{TradeDate: &date2, Settle: fixture.Float(20), TotalVolume: fixture.Float(100)},
+			{TradeDate: &date1, Close: fixture.Float(10), Settle: fixture.Float(10), TotalVolume: fixture.Float(100)},
+		})
+		err := Table(t, []corporate.Action{{Date: date2, Type: corporate.Split, Ratio: 0.5}}, Options{})
+		Expect(err).Should(BeNil())
+
+		Expect(t.Columns[len(api.CBOEColumns):]).Should(Equal([]string{"AdjOpen", "AdjHigh", "AdjLow", "AdjClose", "AdjSettle", "AdjTotalVolume"}))
+		Expect(t.Rows[1][t.Column("AdjSettle")]).Should(Equal(20.0))
+		Expect(t.Rows[1][t.Column("AdjTotalVolume")]).Should(Equal(50.0))
+		Expect(t.Rows[1][t.Column("AdjOpen")]).Should(BeNil())
+		Expect(t.Rows[0][t.Column("AdjSettle")]).Should(Equal(20.0))
+
+		// WIKI tables have the columns already
+		w := api.WikiTable("FB", strip(rows))
+		Expect(Table(w, acts, Options{})).Should(BeNil())
+		Expect(w.Columns).Should(Equal(api.WikiColumns))
+		Expect(w.Rows[4][w.Column("AdjClose")]).Should(BeNumerically("~", 49, 1e-9))
+	})
+})
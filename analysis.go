@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/arrowsink"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/panel"
+	"github.com/twold/go-quandl/risk"
+)
+
+// loadFlags adds the flags of commands reading rows that are saved under
+// -path, or fetched with -fetch
+func (o *options) loadFlags(fs *flag.FlagSet, fetch *bool) {
+	o.authFlags(fs)
+	o.pathFlag(fs)
+	o.layoutFlag(fs)
+	o.symbolFlags(fs)
+	fs.BoolVar(fetch, "fetch", false, "fetch the rows from Quandl instead of reading the saved ones")
+}
+
+// loader returns a func giving the WIKI rows of a symbol, fetched if fetch is
+// set and saved otherwise
+func (o *options) loader(fetch bool) (func(symbol string) ([]api.Wiki, error), error) {
+	if fetch {
+		svc := o.service(endpoints.DATA)
+		return func(symbol string) ([]api.Wiki, error) {
+			resp, err := svc.Fetch(symbol)
+			if err != nil {
+				return nil, err
+			}
+			objs, ok := resp.Data.([]api.Wiki)
+			if !ok {
+				return nil, fmt.Errorf("only WIKI data sets are supported")
+			}
+			return objs, nil
+		}, nil
+	}
+	r, err := o.reader()
+	if err != nil {
+		return nil, err
+	}
+	return func(symbol string) ([]api.Wiki, error) {
+		return r.Load(symbol, "", "")
+	}, nil
+}
+
+// create returns a writer to the file name, or to stdout if name is empty,
+// and the file to close
+func create(name string) (io.Writer, *os.File, error) {
+	if name == "" {
+		return os.Stdout, nil, nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+func runRisk(args []string) error {
+	var (
+		o         options
+		fetch     bool
+		benchmark string
+		riskFree  float64
+		periods   float64
+		prices    string
+		series    bool
+		window    int
+		to        string
+		out       string
+	)
+	fs := newFlagSet("risk", "[SYMBOL...]")
+	o.loadFlags(fs, &fetch)
+	fs.StringVar(&benchmark, "benchmark", "", "symbol the beta and correlation are measured against, e.g. SPY")
+	fs.Float64Var(&riskFree, "risk_free", 0, "annual risk free rate of the Sharpe and Sortino ratios, e.g. 0.02")
+	fs.Float64Var(&periods, "periods", risk.TradingDays, "periods per year the metrics are annualized by")
+	fs.StringVar(&prices, "prices", "adjusted", "closes the returns are computed from, 'adjusted' or 'raw'")
+	fs.BoolVar(&series, "series", false, "write the returns, rolling volatility and drawdown of each date instead of the summary")
+	fs.IntVar(&window, "window", 21, "-series: returns of the rolling volatility")
+	fs.StringVar(&to, "to", "csv", "output format, 'csv', 'json', 'arrow' (IPC stream) or 'feather'")
+	fs.StringVar(&out, "o", "", "output file, defaults to stdout")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case prices != "adjusted" && prices != "raw":
+		return usagef("risk: unknown prices %q, options are adjusted, raw", prices)
+	case periods <= 0:
+		return usagef("risk: -periods must be positive")
+	case window < 2:
+		return usagef("risk: -window must be 2 at least")
+	}
+	switch to {
+	case "csv", "arrow":
+	case "json":
+		if series {
+			return usagef("risk: -series needs -to=csv, arrow or feather")
+		}
+	case "feather":
+		if out == "" {
+			return usagef("risk: -to=feather requires -o")
+		}
+	default:
+		return usagef("risk: unknown format %q", to)
+	}
+
+	symbols, err := o.symbols(fs.Args())
+	if err != nil {
+		return err
+	}
+	load, err := o.loader(fetch)
+	if err != nil {
+		return err
+	}
+	adjusted := prices == "adjusted"
+	opts := risk.Options{PeriodsPerYear: periods, RiskFree: riskFree}
+	if benchmark != "" {
+		objs, err := load(benchmark)
+		if err != nil {
+			return fmt.Errorf("%s: %v", benchmark, err)
+		}
+		b := risk.FromWiki(benchmark, objs, adjusted)
+		opts.Benchmark = &b
+	}
+
+	metrics := []risk.Metrics{}
+	tables := []*api.Table{}
+	err = forEach(symbols, func(symbol string) error {
+		objs, err := load(symbol)
+		if err != nil {
+			return err
+		}
+		s := risk.FromWiki(symbol, objs, adjusted)
+		if series {
+			tables = append(tables, risk.SeriesTable(s, window, periods))
+			return nil
+		}
+		m, err := risk.Compute(s, opts)
+		if err != nil {
+			logger.Warn("Skipped symbol.", logging.Symbol, symbol, logging.Error, err)
+			return nil
+		}
+		metrics = append(metrics, m)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !series {
+		tables = risk.Summary(metrics)
+	}
+
+	w, f, err := create(out)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		defer f.Close()
+	}
+	switch to {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "	")
+		return enc.Encode(metrics)
+	case "arrow":
+		return arrowsink.WriteStream(w, tables)
+	case "feather":
+		return arrowsink.WriteFeather(f, tables)
+	}
+	return writeTablesCSV(w, tables)
+}
+
+func runPanel(args []string) error {
+	var (
+		o     options
+		fetch bool
+		opts  panel.Options
+		to    string
+		out   string
+	)
+	fs := newFlagSet("panel", "[SYMBOL...]")
+	o.loadFlags(fs, &fetch)
+	fs.StringVar(&opts.Column, "column", "AdjClose", "column of each symbol, e.g. 'Close' or 'Adj. Volume'")
+	fs.StringVar(&opts.Join, "join", panel.Outer, "dates kept, 'outer' for any symbol's or 'inner' for those every symbol has")
+	fs.StringVar(&opts.Fill, "fill", panel.None, "gaps of an outer join, 'none', 'ffill' or 'interpolate'")
+	fs.StringVar(&to, "to", "csv", "output format, 'csv', 'parquet', 'arrow' (IPC stream) or 'feather'")
+	fs.StringVar(&out, "o", "", "output file, defaults to stdout")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	switch to {
+	case "csv", "arrow":
+	case "parquet", "feather":
+		if out == "" {
+			return usagef("panel: -to=%s requires -o", to)
+		}
+	default:
+		return usagef("panel: unknown format %q", to)
+	}
+	if _, err := panel.New(nil, opts); err != nil {
+		return usagef("panel: %v", err)
+	}
+
+	symbols, err := o.symbols(fs.Args())
+	if err != nil {
+		return err
+	}
+	load, err := o.loader(fetch)
+	if err != nil {
+		return err
+	}
+	p, err := panel.Load(symbols, load, opts)
+	if err != nil {
+		return err
+	}
+	logger.Info("Built panel.", "column", p.Column, "symbols", len(p.Symbols), "dates", len(p.Dates))
+
+	if to == "parquet" {
+		return p.WriteParquet(out)
+	}
+	w, f, err := create(out)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		defer f.Close()
+	}
+	switch to {
+	case "arrow":
+		return arrowsink.WriteStream(w, []*api.Table{p.Table()})
+	case "feather":
+		return arrowsink.WriteFeather(f, []*api.Table{p.Table()})
+	}
+	return p.WriteCSV(w)
+}
@@ -1,9 +1,15 @@
 package api
 
 import (
-	"errors"
+	"encoding/json"
+	"time"
 
 	"github.com/twold/go-quandl/client"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/quality"
 )
 
 type InputFile []List
@@ -14,12 +20,17 @@ type List struct {
 	Symbol string
 }
 
-func ReadInputList(path, inputFile, sector string) ([]string, error) {
+// ReadInputFile returns every entry of path/input/inputFile
+func ReadInputFile(path, inputFile string) ([]List, error) {
 	b, err := readFile(path, inputFile)
 	if err != nil {
 		return nil, err
 	}
-	list, err := unmarshalInputFile(b)
+	return unmarshalInputFile(b)
+}
+
+func ReadInputList(path, inputFile, sector string) ([]string, error) {
+	list, err := ReadInputFile(path, inputFile)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +61,14 @@ type Service struct {
 
 	format *string
 
+	log logging.Logger
+
+	stats metrics.Metrics
+
+	validator *quality.Validator
+
+	enricher *enrich.Enricher
+
 	DataSetData DataSet `json:"dataset_data" type:"struct"` // WIKI uses dataset_data
 
 	DataSet `json:"dataset" type:"struct"` // CBOE uses dataset
@@ -77,10 +96,53 @@ type DataSet struct {
 	StartDate *string `json:"start_date" type:"string"`
 
 	Transform *string `json:"transform" type:"string"`
+
+	// Source is set by Fetch
+	Source Source `json:"-"`
 }
 
 type Getter interface {
+	// Get fetches the data set and saves it under path/output
 	Get(path, symbol string) (*DataSet, error)
+	// Fetch returns the data set without saving it
+	Fetch(symbol string) (*DataSet, error)
+	Meta(symbol string) (*Metadata, error)
+	Search(query string, page, perPage int) (*SearchResult, error)
+}
+
+// Metadata describes a data set, see /api/v3/datasets/<DB>/<SYMBOL>/metadata.json
+type Metadata struct {
+	ID                  *int64    `json:"id" type:"int64"`
+	DatasetCode         *string   `json:"dataset_code" type:"string"`
+	DatabaseCode        *string   `json:"database_code" type:"string"`
+	Name                *string   `json:"name" type:"string"`
+	Description         *string   `json:"description" type:"string"`
+	RefreshedAt         *string   `json:"refreshed_at" type:"string"`
+	NewestAvailableDate *string   `json:"newest_available_date" type:"string"`
+	OldestAvailableDate *string   `json:"oldest_available_date" type:"string"`
+	ColumnNames         []*string `json:"column_names" type:"list"`
+	Frequency           *string   `json:"frequency" type:"string"`
+	Type                *string   `json:"type" type:"string"`
+	Premium             *bool     `json:"premium" type:"bool"`
+	DatabaseID          *int64    `json:"database_id" type:"int64"`
+}
+
+// SearchResult is one page of /api/v3/datasets.json?query=
+type SearchResult struct {
+	Datasets []Metadata `json:"datasets" type:"list"`
+	Meta     SearchMeta `json:"meta" type:"struct"`
+}
+
+type SearchMeta struct {
+	Query            *string `json:"query" type:"string"`
+	PerPage          *int    `json:"per_page" type:"int"`
+	CurrentPage      *int    `json:"current_page" type:"int"`
+	PrevPage         *int    `json:"prev_page" type:"int"`
+	NextPage         *int    `json:"next_page" type:"int"`
+	TotalPages       *int    `json:"total_pages" type:"int"`
+	TotalCount       *int    `json:"total_count" type:"int"`
+	CurrentFirstItem *int    `json:"current_first_item" type:"int"`
+	CurrentLastItem  *int    `json:"current_last_item" type:"int"`
 }
 
 type CBOE struct {
@@ -97,6 +159,9 @@ type CBOE struct {
 	TotalVolume         *float64 `json:"Total Volume" type:"float64"`
 	EFP                 *float64 `json:"EFP" type:"float64"`
 	PrevDayOpenInterest *float64 `json:"Prev. Day Open Interest" type:"float64"`
+
+	// fields derived from TradeDate, set by the Enrich option
+	*enrich.Fields
 }
 
 type Wiki struct {
@@ -104,7 +169,7 @@ type Wiki struct {
 
 	Date       *string  `json:"Date" type:"string" parquet:"name=Date, inname=Date, type=UTF8, repetitiontype=OPTIONAL"`
 	DayOfWeek  *string  `json:"DayOfWeek" type:"string" parquet:"name=DayOfWeek, inname=DayOfWeek, type=UTF8, repetitiontype=OPTIONAL"`
-	Open       *float64 `json:"Open" type:"float64" parquet:"name=Open, innname=Open, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Open       *float64 `json:"Open" type:"float64" parquet:"name=Open, inname=Open, type=DOUBLE, repetitiontype=OPTIONAL"`
 	High       *float64 `json:"High" type:"float64" parquet:"name=High, inname=High, type=DOUBLE, repetitiontype=OPTIONAL"`
 	Low        *float64 `json:"Low" type:"float64" parquet:"name=Low, inname=Low, type=DOUBLE, repetitiontype=OPTIONAL"`
 	Close      *float64 `json:"Close" type:"float64" parquet:"name=Close, inname=Close, type=DOUBLE, repetitiontype=OPTIONAL"`
@@ -112,15 +177,19 @@ type Wiki struct {
 	ExDividend *float64 `json:"Ex-Dividend" type:"float64" parquet:"name=ExDividend, inname=ExDividend, type=DOUBLE, repetitiontype=OPTIONAL"`
 	SplitRatio *float64 `json:"Split Ratio" type:"float64" parquet:"name=SplitRatio, inname=SplitRatio, type=DOUBLE, repetitiontype=OPTIONAL"`
 	AdjOpen    *float64 `json:"Adj. Open" type:"float64" parquet:"name=AdjOpen, inname=AdjOpen, type=DOUBLE, repetitiontype=OPTIONAL"`
-	AdjHigh    *float64 `json:"Adj. High" type:"float64" parquet:"name=AdjHigh, iname=AdjHigh,  type=DOUBLE, repetitiontype=OPTIONAL"`
+	AdjHigh    *float64 `json:"Adj. High" type:"float64" parquet:"name=AdjHigh, inname=AdjHigh, type=DOUBLE, repetitiontype=OPTIONAL"`
 	AdjLow     *float64 `json:"Adj. Low" type:"float64" parquet:"name=AdjLow, inname=AdjLow, type=DOUBLE, repetitiontype=OPTIONAL"`
 	AdjClose   *float64 `json:"Adj. Close" type:"float64" parquet:"name=AdjClose, inname=AdjClose, type=DOUBLE, repetitiontype=OPTIONAL"`
 	AdjVolume  *float64 `json:"Adj. Volume" type:"float64" parquet:"name=AdjVolume, inname=AdjVolume, type=DOUBLE, repetitiontype=OPTIONAL"`
+
+	// fields derived from Date, set by the Enrich option; they are not
+	// part of the parquet schema so that files written before stay readable
+	*enrich.Fields
 }
 
 // dataType options are "data" and "metadata"
 // format options are "csv", "json" and "xml"
-func New(dataType, dbCode, format, key *string) Getter {
+func New(dataType, dbCode, format, key *string, opts ...Option) Getter {
 
 	// save all input values to client
 	svc := &Service{
@@ -141,10 +210,16 @@ func New(dataType, dbCode, format, key *string) Getter {
 		svc.dbCode = &def
 	}
 
+	// set default to data
+	if svc.dataType == nil {
+		def := "data"
+		svc.dataType = &def
+	}
+
 	switch *svc.dbCode {
 	case "WIKI":
 		// create client
-		return &Wiki{
+		w := &Wiki{
 			Service: &Service{
 				Client: client.New("datasets").
 					Auth(key).
@@ -153,9 +228,11 @@ func New(dataType, dbCode, format, key *string) Getter {
 					Format(*svc.format),
 			},
 		}
+		w.apply(opts)
+		return w
 	}
 
-	return &CBOE{
+	c := &CBOE{
 		Service: &Service{
 			Client: client.New("datasets").
 				Auth(key).
@@ -163,39 +240,49 @@ func New(dataType, dbCode, format, key *string) Getter {
 				Format(*svc.format),
 		},
 	}
+	c.apply(opts)
+	return c
 }
 
 func (c *Wiki) Get(path, symbol string) (*DataSet, error) {
-	resp, err := c.Do("GET", symbol)
+	ds, err := c.Fetch(symbol)
 	if err != nil {
 		return nil, err
 	}
 
-	b, err := read(resp.Body)
+	err = writeLocalFiles(c.logger(), c.metrics(), path, ds.Source, ds.Data.([]Wiki), false)
 	if err != nil {
 		return nil, err
 	}
+	return ds, nil
+}
 
-	// handle errors
-	errQ, err := unmarshalError(b)
+func (c *Wiki) Fetch(symbol string) (*DataSet, error) {
+	l, m, start := c.logger(), c.metrics(), time.Now()
+	resp, err := c.Do("GET", symbol)
 	if err != nil {
 		return nil, err
 	}
 
-	// handle Quandl specific errors
-	if errQ.Message != nil {
-		return nil, errors.New(*errQ.Message)
+	src := Source{Symbol: symbol, DBCode: resp.Database(), URL: resp.URL(), FetchedAt: time.Now().UTC()}
+	fields := []interface{}{logging.Symbol, symbol, logging.DBCode, src.DBCode, logging.URL, src.URL}
+	l.Debug("Reading API response.", fields...)
+	b, err := readResponse(c.metrics(), resp)
+	if err != nil {
+		return nil, err
 	}
 
-	// unmarshal struct to seperate data from API metadata
-	c, err = c.unmarshal(b)
+	// unmarshal struct to seperate data from API metadata, keeping the
+	// receiver for the options it was built with
+	dat, err := c.unmarshal(b)
 	if err != nil {
 		return nil, err
 	}
 
 	// save updated struct as byte slice
 	// ensure that timeseries data is indexed properly and field names are added
-	byt, err := formatDataSet(c.DataSetData.ColumnNames, c.DataSetData.RawData)
+	l.Debug("Transforming data set.", fields...)
+	byt, err := formatDataSet(dat.DataSetData.ColumnNames, dat.DataSetData.RawData)
 	if err != nil {
 		return nil, err
 	}
@@ -205,54 +292,112 @@ func (c *Wiki) Get(path, symbol string) (*DataSet, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	err = writeLocalFiles(path, symbol, d)
+	d, err = c.check(src, d)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.enrich(src, d); err != nil {
+		return nil, err
+	}
 
-	c.DataSetData.Data = d
-	return &c.DataSetData, nil
+	dat.DataSetData.Data = d
+	dat.DataSetData.Source = src
+	m.Add(metrics.RowsDecoded, float64(len(d)), metrics.DB, resp.Database())
+	l.Info("Fetched data set.", append(fields, logging.Rows, len(d), logging.Duration, time.Since(start))...)
+	return &dat.DataSetData, nil
 }
 
 // unfinished
 func (c *CBOE) Get(path, symbol string) (*DataSet, error) {
+	return c.Fetch(symbol)
+}
+
+func (c *CBOE) Fetch(symbol string) (*DataSet, error) {
+	l, m, start := c.logger(), c.metrics(), time.Now()
 	resp, err := c.Do("GET", symbol)
 	if err != nil {
 		return nil, err
 	}
 
-	b, err := read(resp.Body)
+	src := Source{Symbol: symbol, DBCode: resp.Database(), URL: resp.URL(), FetchedAt: time.Now().UTC()}
+	fields := []interface{}{logging.Symbol, symbol, logging.DBCode, src.DBCode, logging.URL, src.URL}
+	l.Debug("Reading API response.", fields...)
+	b, err := readResponse(c.metrics(), resp)
 	if err != nil {
 		return nil, err
 	}
 
-	// handle errors
-	errQ, err := unmarshalError(b)
+	// unmarshal struct to seperate data from API metadata, keeping the
+	// receiver for the options it was built with
+	dat, err := c.unmarshal(b)
 	if err != nil {
 		return nil, err
 	}
 
-	// handle Quandl specific errors
-	if errQ.Message != nil {
-		return nil, errors.New(*errQ.Message)
+	l.Debug("Transforming data set.", fields...)
+	byt, err := formatDataSet(dat.DataSet.ColumnNames, dat.DataSet.RawData)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := c.unmarshalData(byt)
+	if err != nil {
+		return nil, err
 	}
+	d, err = c.check(src, d)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.enrich(src, d); err != nil {
+		return nil, err
+	}
+	dat.DataSetData.Data = d
+	dat.DataSetData.Source = src
+	m.Add(metrics.RowsDecoded, float64(len(d)), metrics.DB, resp.Database())
+	l.Info("Fetched data set.", append(fields, logging.Rows, len(d), logging.Duration, time.Since(start))...)
+	return &dat.DataSetData, nil
+}
 
-	// unmarshal struct to seperate data from API metadata
-	c, err = c.unmarshal(b)
+// Meta returns the metadata of a data set in the service's database
+func (s *Service) Meta(symbol string) (*Metadata, error) {
+	cl := *s.Client
+	resp, err := cl.DataType(endpoints.METADATA).Format(endpoints.JSON).Do("GET", symbol)
 	if err != nil {
 		return nil, err
 	}
 
-	byt, err := formatDataSet(c.DataSet.ColumnNames, c.DataSet.RawData)
+	b, err := readResponse(s.metrics(), resp)
 	if err != nil {
 		return nil, err
 	}
 
-	d, err := c.unmarshalData(byt)
+	var dat struct {
+		Metadata `json:"dataset" type:"struct"`
+	}
+	err = json.Unmarshal(b, &dat)
+	if err != nil {
+		return nil, err
+	}
+	return &dat.Metadata, nil
+}
+
+// Search returns one page of data sets in the service's database matching query
+func (s *Service) Search(query string, page, perPage int) (*SearchResult, error) {
+	cl := *s.Client
+	resp, err := cl.Format(endpoints.JSON).Search("GET", query, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := readResponse(s.metrics(), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var dat SearchResult
+	err = json.Unmarshal(b, &dat)
 	if err != nil {
 		return nil, err
 	}
-	c.DataSetData.Data = d
-	return &c.DataSetData, nil
+	return &dat, nil
 }
@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/quality"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// body is a WIKI response whose second bar has its low above its high
+const body = `{"dataset_data": {
+	"column_names": ["Date", "Open", "High", "Low", "Close", "Volume"],
+	"data": [
+		["2018-01-02", 10, 11, 9, 10.5, 100],
+		["2018-01-03", 10, 9, 11, 10, 100]
+	]
+}}`
+
+// stub answers every request with body
+type stub struct{}
+
+func (stub) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+var _ = Describe("Api", func() {
+	var transport http.RoundTripper
+
+	BeforeEach(func() {
+		transport = http.DefaultClient.Transport
+		http.DefaultClient.Transport = stub{}
+	})
+
+	AfterEach(func() {
+		http.DefaultClient.Transport = transport
+	})
+
+	Context("When a validator is set", func() {
+		It("drops the bars it is configured to", func() {
+			v, err := quality.New(map[string]string{quality.LowAboveHigh: quality.DropRow})
+			Expect(err).Should(BeNil())
+
+			actual, err := New(nil, nil, nil, nil, Validate(v)).Fetch("FB")
+			Expect(err).Should(BeNil())
+			objs := actual.Data.([]Wiki)
+			Expect(len(objs)).Should(Equal(1))
+			Expect(*objs[0].Date).Should(Equal("2018-01-02"))
+			Expect(v.Report().Symbols[0].Dropped).Should(Equal(1))
+		})
+
+		It("fails the symbol it is configured to", func() {
+			v, err := quality.New(map[string]string{quality.LowAboveHigh: quality.FailSymbol})
+			Expect(err).Should(BeNil())
+
+			_, err = New(nil, nil, nil, nil, Validate(v)).Fetch("FB")
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(Equal("FB failed quality rules low_above_high"))
+		})
+	})
+
+	Context("When an enricher is set", func() {
+		It("adds its fields to the bars", func() {
+			e, err := enrich.New(enrich.Options{Fields: []string{enrich.ISOWeek, enrich.DaysSince}})
+			Expect(err).Should(BeNil())
+
+			actual, err := New(nil, nil, nil, nil, Enrich(e)).Fetch("FB")
+			Expect(err).Should(BeNil())
+			objs := actual.Data.([]Wiki)
+			Expect(len(objs)).Should(Equal(2))
+			Expect(*objs[0].ISOWeek).Should(Equal("2018-W01"))
+			Expect(objs[0].DaysSince).Should(BeNil())
+			Expect(*objs[1].DaysSince).Should(Equal(1))
+			Expect(objs[1].Month).Should(BeNil())
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/quality"
+)
+
+// check validates the rows of src, returning those that are kept
+func (c *Wiki) check(src Source, objs []Wiki) ([]Wiki, error) {
+	if c.validator == nil {
+		return objs, nil
+	}
+	bars := make([]quality.Bar, len(objs))
+	for i, obj := range objs {
+		bars[i] = quality.Bar{Date: deref(obj.Date), Open: obj.Open, High: obj.High, Low: obj.Low, Close: obj.Close, Volume: obj.Volume}
+	}
+	r, err := c.validate(src, bars)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := objs[:0]
+	for i, obj := range objs {
+		if r.Keep(i) {
+			kept = append(kept, obj)
+		}
+	}
+	return kept, nil
+}
+
+// check validates the rows of src, returning those that are kept
+func (c *CBOE) check(src Source, objs []CBOE) ([]CBOE, error) {
+	if c.validator == nil {
+		return objs, nil
+	}
+	bars := make([]quality.Bar, len(objs))
+	for i, obj := range objs {
+		bars[i] = quality.Bar{Date: deref(obj.TradeDate), Open: obj.Open, High: obj.High, Low: obj.Low, Close: obj.Close, Volume: obj.TotalVolume}
+	}
+	r, err := c.validate(src, bars)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := objs[:0]
+	for i, obj := range objs {
+		if r.Keep(i) {
+			kept = append(kept, obj)
+		}
+	}
+	return kept, nil
+}
+
+func (s *Service) validate(src Source, bars []quality.Bar) (*quality.SymbolReport, error) {
+	l, m := s.logger(), s.metrics()
+	r, err := s.validator.Check(src.Symbol, src.DBCode, bars)
+	if len(r.Issues) > 0 {
+		l.Warn("Rows broke quality rules.", logging.Symbol, src.Symbol, "issues", len(r.Issues), "dropped", r.Dropped, "first_date", r.Issues[0].Date)
+	}
+	m.Add(metrics.RowsDropped, float64(r.Dropped), metrics.DB, src.DBCode)
+	return r, err
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"github.com/twold/go-quandl/enrich"
+)
+
+// enrich sets the fields of the service's enricher on the rows of src
+func (c *Wiki) enrich(src Source, objs []Wiki) error {
+	if c.enricher == nil {
+		return nil
+	}
+	return EnrichWiki(c.enricher, src.DBCode, objs)
+}
+
+// enrich sets the fields of the service's enricher on the rows of src
+func (c *CBOE) enrich(src Source, objs []CBOE) error {
+	if c.enricher == nil {
+		return nil
+	}
+	dates := make([]string, len(objs))
+	for i, obj := range objs {
+		dates[i] = deref(obj.TradeDate)
+	}
+	fields, err := c.enricher.Dates(src.DBCode, dates)
+	if err != nil {
+		return err
+	}
+	for i := range objs {
+		objs[i].Fields = fields[i]
+	}
+	return nil
+}
+
+// Enriched reports whether any of objs carries enrich fields, which sinks
+// with a fixed schema such as parquet files do not save
+func Enriched(objs []Wiki) bool {
+	for _, obj := range objs {
+		if obj.Fields != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// EnrichWiki sets the fields of e on objs, rows of the database dbCode, e.g.
+// rows loaded from files saved without them
+func EnrichWiki(e *enrich.Enricher, dbCode string, objs []Wiki) error {
+	dates := make([]string, len(objs))
+	for i, obj := range objs {
+		dates[i] = deref(obj.Date)
+	}
+	fields, err := e.Dates(dbCode, dates)
+	if err != nil {
+		return err
+	}
+	for i := range objs {
+		objs[i].Fields = fields[i]
+	}
+	return nil
+}
+
+// Enrich adds the fields of e as columns of t, derived from its first column.
+// It works on tables of any database, e.g. from DataSetTable.
+func (t *Table) Enrich(e *enrich.Enricher) error {
+	dates := make([]string, len(t.Rows))
+	for i, row := range t.Rows {
+		if len(row) > 0 {
+			dates[i], _ = row[0].(string)
+		}
+	}
+	fields, err := e.Dates(t.DBCode, dates)
+	if err != nil {
+		return err
+	}
+	t.addFields(fields)
+	return nil
+}
+
+// addFields appends a column for each field set on any of fields, the
+// fields of the rows of t
+func (t *Table) addFields(fields []*enrich.Fields) {
+	names := enrich.Present(fields)
+	if len(names) == 0 {
+		return
+	}
+	cols := make([]string, len(t.Columns), len(t.Columns)+len(names))
+	copy(cols, t.Columns)
+	for _, name := range names {
+		cols = append(cols, enrich.Columns[name])
+	}
+	t.Columns = cols
+	for i, f := range fields {
+		for _, name := range names {
+			t.Rows[i] = append(t.Rows[i], f.Value(name))
+		}
+	}
+}
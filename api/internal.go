@@ -2,24 +2,26 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"reflect"
-	"strings"
 	"time"
 
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/client"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/manifest"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/redact"
 	"github.com/xitongsys/parquet-go/ParquetFile"
 	"github.com/xitongsys/parquet-go/ParquetWriter"
 )
 
 func formatDataSet(columnNames []*string, data []interface{}) ([]byte, error) {
-
-	log.Printf("Transforming data set.\n")
-
 	str := "["
 	for n, objs := range data {
 		str = fmt.Sprintf("%s{", str)
@@ -67,8 +69,32 @@ func formatDataSet(columnNames []*string, data []interface{}) ([]byte, error) {
 	return []byte(str), nil
 }
 
+// readResponse reads and closes the response body and returns Quandl errors
+func readResponse(m metrics.Metrics, c *client.Client) ([]byte, error) {
+	if c.HTTPResponse != nil {
+		defer c.HTTPResponse.Body.Close()
+	}
+
+	b, err := read(c.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.Add(metrics.BytesDownloaded, float64(len(b)), metrics.DB, c.Database())
+
+	// handle errors
+	errQ, err := unmarshalError(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle Quandl specific errors
+	if errQ.Message != nil {
+		return nil, errors.New(redact.String(*errQ.Message))
+	}
+	return b, nil
+}
+
 func read(body io.ReadCloser) ([]byte, error) {
-	log.Printf("Reading API response.\n")
 	b, err := ioutil.ReadAll(body)
 	if err != nil {
 		return nil, err
@@ -148,81 +174,133 @@ func readFile(path, fileName string) ([]byte, error) {
 	return b, nil
 }
 
-func writeToFiles(path, symbol string, objs []Wiki) error {
-	err := os.Mkdir(filepath.Join(path, "output", symbol), 0777)
+func writeToFiles(l logging.Logger, m metrics.Metrics, path string, src Source, objs []Wiki, overwrite bool) error {
+	start, n := time.Now(), 0
+	dir := filepath.Join(path, "output", src.Symbol)
+	man, err := openManifest(dir)
 	if err != nil {
-		if check := strings.Contains(err.Error(), "file exists"); check == false {
-			return err
-		}
+		return err
 	}
 
+	covered := man.Covered(dir)
 	for _, obj := range objs {
-
-		name := filepath.Join(path, "output", symbol, fmt.Sprintf("%v.txt", *obj.Date))
-		if _, err := os.Stat(name); os.IsNotExist(err) == false {
+		base := fmt.Sprintf("%v.parquet", *obj.Date)
+		if !overwrite && (man.Complete(dir, base) || covered(*obj.Date)) {
 			continue
 		}
 
-		fw, err := ParquetFile.NewLocalFileWriter(name)
+		name := filepath.Join(dir, base)
+		tmp := atomicfile.TempName(name)
+		err := writeParquet(tmp, obj)
+		if err == nil {
+			err = atomicfile.Commit(tmp, name)
+		}
 		if err != nil {
-			log.Println("Can't create file", err)
+			l.Error("Write error", logging.File, name, logging.Error, err)
+			os.Remove(tmp)
 			return err
 		}
 
-		//write
-		pw, err := ParquetWriter.NewParquetWriter(fw, new(Wiki), 4)
+		size, sum, err := manifest.SumFile(name)
 		if err != nil {
-			log.Println("Can't create parquet writer", err)
 			return err
 		}
+		man.Add(base, entry(src, *obj.Date, size, sum))
+		l.Debug("Write finished.", logging.Symbol, src.Symbol, logging.File, name)
+		m.Add(metrics.FilesWritten, 1, metrics.Sink, "parquet")
+		n++
+	}
 
-		if err = pw.Write(obj); err != nil {
-			log.Println("Write error", err)
-			return err
-		}
+	if err := saveManifest(man, dir, src); err != nil {
+		return err
+	}
+	l.Info("Wrote parquet files.", logging.Symbol, src.Symbol, logging.Rows, n, logging.Duration, time.Since(start))
+	return nil
+}
 
-		if err = pw.WriteStop(); err != nil {
-			log.Println("WriteStop error", err)
-			return err
-		}
-		log.Printf("Write Finished for file %+v\n", name)
-		fw.Close()
+func writeParquet(name string, obj Wiki) error {
+	fw, err := ParquetFile.NewLocalFileWriter(name)
+	if err != nil {
+		return err
+	}
 
+	//write
+	pw, err := ParquetWriter.NewParquetWriter(fw, new(Wiki), 4)
+	if err == nil {
+		err = pw.Write(obj)
 	}
-	return nil
+	if err == nil {
+		err = pw.WriteStop()
+	}
+	if cerr := fw.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
-func writeLocalFiles(path, symbol string, objs []Wiki) error {
-	log.Printf("Writing local files.\n")
-	err := os.Mkdir(filepath.Join(path, "output", symbol), 0777)
+func writeLocalFiles(l logging.Logger, m metrics.Metrics, path string, src Source, objs []Wiki, overwrite bool) error {
+	start, n := time.Now(), 0
+	dir := filepath.Join(path, "output", src.Symbol)
+	man, err := openManifest(dir)
 	if err != nil {
-		if check := strings.Contains(err.Error(), "file exists"); check == false {
-			return err
-		}
+		return err
 	}
 
+	covered := man.Covered(dir)
 	for _, obj := range objs {
-		b, err := json.MarshalIndent(obj, "", "	")
-		if err != nil {
-			return err
-		}
-
-		name := filepath.Join(path, "output", symbol, fmt.Sprintf("%v.json", *obj.Date))
-		if _, err := os.Stat(name); os.IsNotExist(err) == false {
+		// files not in the manifest were left by an interrupted run,
+		// dates of compacted files are not written again; an overwritten
+		// daily file wins over the compacted row
+		base := fmt.Sprintf("%v.json", *obj.Date)
+		if !overwrite && (man.Complete(dir, base) || covered(*obj.Date)) {
 			continue
 		}
 
-		f, err := os.Create(name)
+		b, err := json.MarshalIndent(obj, "", "	")
 		if err != nil {
 			return err
 		}
 
-		_, err = f.Write(b)
+		name := filepath.Join(dir, base)
+		err = atomicfile.WriteFile(name, b, 0666)
 		if err != nil {
 			return err
 		}
-		f.Close()
+		man.Add(base, entry(src, *obj.Date, int64(len(b)), manifest.Sum(b)))
+		l.Debug("Write finished.", logging.Symbol, src.Symbol, logging.File, name)
+		m.Add(metrics.FilesWritten, 1, metrics.Sink, "json")
+		n++
 	}
 
+	if err := saveManifest(man, dir, src); err != nil {
+		return err
+	}
+	l.Info("Wrote local files.", logging.Symbol, src.Symbol, logging.Rows, n, logging.Duration, time.Since(start))
 	return nil
 }
+
+func openManifest(dir string) (*manifest.Manifest, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return manifest.Load(dir)
+}
+
+// saveManifest commits the files written for src
+func saveManifest(man *manifest.Manifest, dir string, src Source) error {
+	man.DBCode = src.DBCode
+	man.SourceURL = src.URL
+	man.FetchedAt = src.FetchedAt
+	return man.Save(dir)
+}
+
+func entry(src Source, date string, size int64, sum string) manifest.Entry {
+	return manifest.Entry{
+		Rows:      1,
+		FirstDate: date,
+		LastDate:  date,
+		Size:      size,
+		SHA256:    sum,
+		FetchedAt: src.FetchedAt,
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/quality"
+)
+
+// Option configures the Service returned by New
+type Option func(*Service)
+
+// DateRange limits data requests to start..end, formatted as YYYY-MM-DD.
+// Either bound may be empty.
+func DateRange(start, end string) Option {
+	return func(s *Service) {
+		params := url.Values{}
+		if start != "" {
+			params.Set("start_date", start)
+		}
+		if end != "" {
+			params.Set("end_date", end)
+		}
+		s.Client.Params(params)
+	}
+}
+
+// Retry resends failed requests up to attempts more times, doubling backoff
+// after every try
+func Retry(attempts int, backoff time.Duration) Option {
+	return func(s *Service) {
+		s.Client.Retry(attempts, backoff)
+	}
+}
+
+// Logger sends the service's log messages to l, by default nothing is logged
+func Logger(l logging.Logger) Option {
+	return func(s *Service) {
+		s.log = l
+		s.Client.Logger(l)
+	}
+}
+
+// Metrics records requests, retries, rows and bytes in m
+func Metrics(m metrics.Metrics) Option {
+	return func(s *Service) {
+		s.stats = m
+		s.Client.Metrics(m)
+	}
+}
+
+// Validate checks decoded rows against the rules of v before they are
+// returned or saved, dropping rows or failing the symbol as configured
+func Validate(v *quality.Validator) Option {
+	return func(s *Service) {
+		s.validator = v
+	}
+}
+
+// Enrich adds the fields of e to the decoded rows, after they are validated
+func Enrich(e *enrich.Enricher) Option {
+	return func(s *Service) {
+		s.enricher = e
+	}
+}
+
+func (s *Service) logger() logging.Logger {
+	return logging.OrDiscard(s.log)
+}
+
+func (s *Service) metrics() metrics.Metrics {
+	return metrics.OrDiscard(s.stats)
+}
+
+func (s *Service) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(s)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"time"
+
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+)
+
+// Sink saves the rows fetched for a symbol
+type Sink interface {
+	Write(src Source, objs []Wiki) error
+}
+
+// Source describes where a data set was fetched from
+type Source struct {
+	Symbol    string
+	DBCode    string
+	URL       string
+	FetchedAt time.Time
+}
+
+// JSONFiles saves one file per day as Path/output/<SYMBOL>/<YYYY-MM-DD>.json.
+// Files are replaced atomically and recorded in output/<SYMBOL>/manifest.json.
+// Dates already saved are skipped unless Overwrite is set, e.g. to save rows
+// restated by Quandl.
+type JSONFiles struct {
+	Path      string
+	Overwrite bool
+	Log       logging.Logger
+	Metrics   metrics.Metrics
+}
+
+func (s *JSONFiles) Write(src Source, objs []Wiki) error {
+	return writeLocalFiles(logging.OrDiscard(s.Log), metrics.OrDiscard(s.Metrics), s.Path, src, objs, s.Overwrite)
+}
+
+// ParquetFiles saves one file per day as Path/output/<SYMBOL>/<YYYY-MM-DD>.parquet.
+// Files are replaced atomically and recorded in output/<SYMBOL>/manifest.json.
+// Dates already saved are skipped unless Overwrite is set. Enrich fields are
+// not part of the parquet schema, rows carrying them are saved without them
+// and a warning is logged.
+type ParquetFiles struct {
+	Path      string
+	Overwrite bool
+	Log       logging.Logger
+	Metrics   metrics.Metrics
+}
+
+func (s *ParquetFiles) Write(src Source, objs []Wiki) error {
+	l := logging.OrDiscard(s.Log)
+	if Enriched(objs) {
+		l.Warn("Enrich fields are not saved in parquet files.", logging.Symbol, src.Symbol)
+	}
+	return writeToFiles(l, metrics.OrDiscard(s.Metrics), s.Path, src, objs, s.Overwrite)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"github.com/twold/go-quandl/enrich"
+)
+
+// Table holds the rows of a data set in column order, for databases without
+// a typed row struct and for sinks that write columns. The first column is
+// the date as a string, the others are float64, string or nil. Enriched rows
+// add a column per derived field after the columns of the data set.
+type Table struct {
+	Symbol  string
+	DBCode  string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// WikiColumns are the columns of a table of Wiki rows
+var WikiColumns = []string{
+	"Date", "DayOfWeek", "Open", "High", "Low", "Close", "Volume", "ExDividend", "SplitRatio",
+	"AdjOpen", "AdjHigh", "AdjLow", "AdjClose", "AdjVolume",
+}
+
+// CBOEColumns are the columns of a table of CBOE rows
+var CBOEColumns = []string{
+	"TradeDate", "DayOfWeek", "Open", "High", "Low", "Close", "Settle", "Change", "TotalVolume",
+	"EFP", "PrevDayOpenInterest",
+}
+
+// WikiTable returns objs as a table
+func WikiTable(symbol string, objs []Wiki) *Table {
+	t := &Table{Symbol: symbol, DBCode: "WIKI", Columns: WikiColumns, Rows: make([][]interface{}, 0, len(objs))}
+	fields := make([]*enrich.Fields, 0, len(objs))
+	for _, o := range objs {
+		t.Rows = append(t.Rows, []interface{}{
+			text(o.Date), text(o.DayOfWeek), value(o.Open), value(o.High), value(o.Low), value(o.Close),
+			value(o.Volume), value(o.ExDividend), value(o.SplitRatio), value(o.AdjOpen), value(o.AdjHigh),
+			value(o.AdjLow), value(o.AdjClose), value(o.AdjVolume),
+		})
+		fields = append(fields, o.Fields)
+	}
+	t.addFields(fields)
+	return t
+}
+
+// CBOETable returns objs as a table
+func CBOETable(symbol string, objs []CBOE) *Table {
+	t := &Table{Symbol: symbol, DBCode: "CBOE", Columns: CBOEColumns, Rows: make([][]interface{}, 0, len(objs))}
+	fields := make([]*enrich.Fields, 0, len(objs))
+	for _, o := range objs {
+		t.Rows = append(t.Rows, []interface{}{
+			text(o.TradeDate), text(o.DayOfWeek), value(o.Open), value(o.High), value(o.Low), value(o.Close),
+			value(o.Settle), value(o.Change), value(o.TotalVolume), value(o.EFP), value(o.PrevDayOpenInterest),
+		})
+		fields = append(fields, o.Fields)
+	}
+	t.addFields(fields)
+	return t
+}
+
+// DataSetTable returns the raw rows of a fetched data set of any database,
+// named by its column_names
+func DataSetTable(ds *DataSet) *Table {
+	t := &Table{Symbol: ds.Source.Symbol, DBCode: ds.Source.DBCode, Rows: make([][]interface{}, 0, len(ds.RawData))}
+	for _, name := range ds.ColumnNames {
+		col := ""
+		if name != nil {
+			col = *name
+		}
+		t.Columns = append(t.Columns, col)
+	}
+	for _, raw := range ds.RawData {
+		row, ok := raw.([]interface{})
+		if !ok {
+			continue
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// Column returns the index of the column name, or -1
+func (t *Table) Column(name string) int {
+	for i, c := range t.Columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func text(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func value(f *float64) interface{} {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
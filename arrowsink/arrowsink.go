@@ -0,0 +1,229 @@
+// Package arrowsink writes tables as Apache Arrow record batches, either as
+// an IPC stream or as a Feather v2 file that pandas and polars open with
+// read_feather. The date column becomes date32, text columns utf8 and the
+// others float64, named after the data set's column_names. A Symbol column
+// comes first so that tables of several symbols can share a file.
+package arrowsink
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/logging"
+)
+
+// DateLayout is the format of the date column of tables
+const DateLayout = "2006-01-02"
+
+// Schema returns the arrow schema shared by tables, which must have the same
+// columns
+func Schema(tables []*api.Table) (*arrow.Schema, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables")
+	}
+	cols := tables[0].Columns
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s has no columns", tables[0].Symbol)
+	}
+	for _, t := range tables[1:] {
+		if !equal(t.Columns, cols) {
+			return nil, fmt.Errorf("table %s has other columns than table %s", t.Symbol, tables[0].Symbol)
+		}
+	}
+
+	fields := []arrow.Field{{Name: "Symbol", Type: arrow.BinaryTypes.String}}
+	fields = append(fields, arrow.Field{Name: cols[0], Type: arrow.FixedWidthTypes.Date32, Nullable: true})
+	for i, name := range cols[1:] {
+		typ := arrow.DataType(arrow.PrimitiveTypes.Float64)
+		if isText(tables, i+1) {
+			typ = arrow.BinaryTypes.String
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: typ, Nullable: true})
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// Record returns the rows of t as a record of schema. The caller releases it.
+func Record(schema *arrow.Schema, t *api.Table) (array.Record, error) {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Reserve(len(t.Rows))
+
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return nil, fmt.Errorf("%s: row has %d values, want %d", t.Symbol, len(row), len(t.Columns))
+		}
+		b.Field(0).(*array.StringBuilder).Append(t.Symbol)
+		for i, v := range row {
+			if err := appendValue(b.Field(i+1), v); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", t.Symbol, t.Columns[i], err)
+			}
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+func appendValue(b array.Builder, v interface{}) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.Date32Builder:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("date %v is not a string", v)
+		}
+		d, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return err
+		}
+		b.Append(arrow.Date32(d.Unix() / 86400))
+	case *array.StringBuilder:
+		b.Append(fmt.Sprint(v))
+	case *array.Float64Builder:
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%v is not a number", v)
+		}
+		b.Append(f)
+	}
+	return nil
+}
+
+// WriteStream writes tables to w as an Arrow IPC stream, one record batch
+// per table
+func WriteStream(w io.Writer, tables []*api.Table) error {
+	schema, err := Schema(tables)
+	if err != nil {
+		return err
+	}
+	iw := ipc.NewWriter(w, ipc.WithSchema(schema))
+	if err := write(iw, schema, tables); err != nil {
+		iw.Close()
+		return err
+	}
+	return iw.Close()
+}
+
+// WriteFeather writes tables to w as a Feather v2 file, i.e. the Arrow IPC
+// file format, one record batch per table
+func WriteFeather(w io.WriteSeeker, tables []*api.Table) error {
+	schema, err := Schema(tables)
+	if err != nil {
+		return err
+	}
+	fw, err := ipc.NewFileWriter(w, ipc.WithSchema(schema))
+	if err != nil {
+		return err
+	}
+	if err := write(fw, schema, tables); err != nil {
+		fw.Close()
+		return err
+	}
+	return fw.Close()
+}
+
+type recordWriter interface {
+	Write(rec array.Record) error
+}
+
+func write(w recordWriter, schema *arrow.Schema, tables []*api.Table) error {
+	for _, t := range tables {
+		rec, err := Record(schema, t)
+		if err != nil {
+			return err
+		}
+		err = w.Write(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Files saves the rows of each symbol as Path/arrow/<SYMBOL>.feather, or as
+// an IPC stream in <SYMBOL>.arrows if Stream is set. It is an api.Sink.
+type Files struct {
+	Path   string
+	Stream bool
+	Log    logging.Logger
+}
+
+func (s *Files) Write(src api.Source, objs []api.Wiki) error {
+	return s.WriteTable(api.WikiTable(src.Symbol, objs))
+}
+
+// WriteTable replaces the file of t.Symbol atomically
+func (s *Files) WriteTable(t *api.Table) error {
+	l, start := logging.OrDiscard(s.Log), time.Now()
+	ext := ".feather"
+	if s.Stream {
+		ext = ".arrows"
+	}
+	dir := filepath.Join(s.Path, "arrow")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, t.Symbol+ext)
+
+	tmp := atomicfile.TempName(name)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if s.Stream {
+		err = WriteStream(f, []*api.Table{t})
+	} else {
+		err = WriteFeather(f, []*api.Table{t})
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = atomicfile.Commit(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	l.Info("Wrote arrow file.", logging.Symbol, t.Symbol, logging.File, name, logging.Rows, len(t.Rows), logging.Duration, time.Since(start))
+	return nil
+}
+
+// isText reports whether column i of the tables holds strings
+func isText(tables []*api.Table, i int) bool {
+	for _, t := range tables {
+		for _, row := range t.Rows {
+			if i >= len(row) || row[i] == nil {
+				continue
+			}
+			_, ok := row[i].(string)
+			return ok
+		}
+	}
+	return false
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
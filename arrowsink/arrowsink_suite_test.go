@@ -0,0 +1,13 @@
+package arrowsink_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestArrowsink(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Arrowsink Suite")
+}
@@ -0,0 +1,85 @@
+package arrowsink_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+
+	"github.com/twold/go-quandl/api"
+	. "github.com/twold/go-quandl/arrowsink"
+	"github.com/twold/go-quandl/internal/fixture"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Arrow", func() {
+	fb := api.WikiTable("FB", []api.Wiki{fixture.Wiki("2018-01-02", 1.5), fixture.Wiki("2018-01-03", 2)})
+	aapl := api.WikiTable("AAPL", []api.Wiki{fixture.Wiki("1980-12-12", 0.5)})
+
+	It("should derive the schema from the columns", func() {
+		schema, err := Schema([]*api.Table{fb, aapl})
+		Expect(err).Should(BeNil())
+		Expect(schema.Field(0).Name).Should(Equal("Symbol"))
+		Expect(schema.Field(1).Name).Should(Equal("Date"))
+		Expect(schema.Field(1).Type).Should(Equal(arrow.FixedWidthTypes.Date32))
+		Expect(schema.Field(2).Type).Should(Equal(arrow.BinaryTypes.String))
+		Expect(schema.Field(6).Name).Should(Equal("Close"))
+		Expect(schema.Field(6).Type).Should(Equal(arrow.PrimitiveTypes.Float64))
+
+		raw := &api.Table{Symbol: "VX1", Columns: []string{"Trade Date", "Settle"}}
+		_, err = Schema([]*api.Table{fb, raw})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should write an IPC stream", func() {
+		var buf bytes.Buffer
+		Expect(WriteStream(&buf, []*api.Table{fb, aapl})).Should(BeNil())
+
+		r, err := ipc.NewReader(&buf)
+		Expect(err).Should(BeNil())
+		defer r.Release()
+		rows := int64(0)
+		for r.Next() {
+			rows += r.Record().NumRows()
+		}
+		Expect(r.Err()).Should(BeNil())
+		Expect(rows).Should(Equal(int64(3)))
+	})
+
+	It("should write a feather file", func() {
+		dir, err := ioutil.TempDir("", "arrow")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+
+		sink := &Files{Path: dir}
+		Expect(sink.Write(api.Source{Symbol: "FB"}, []api.Wiki{fixture.Wiki("2018-01-02", 1.5), fixture.Wiki("2018-01-03", 2)})).Should(BeNil())
+
+		f, err := os.Open(filepath.Join(dir, "arrow", "FB.feather"))
+		Expect(err).Should(BeNil())
+		defer f.Close()
+		r, err := ipc.NewFileReader(f)
+		Expect(err).Should(BeNil())
+		defer r.Close()
+		Expect(r.NumRecords()).Should(Equal(1))
+
+		rec, err := r.Record(0)
+		Expect(err).Should(BeNil())
+		Expect(rec.NumRows()).Should(Equal(int64(2)))
+		Expect(rec.Column(0).(*array.String).Value(1)).Should(Equal("FB"))
+		// 2018-01-02 is day 17533 since 1970-01-01
+		Expect(rec.Column(1).(*array.Date32).Value(0)).Should(Equal(arrow.Date32(17533)))
+		Expect(rec.Column(6).(*array.Float64).Value(0)).Should(Equal(1.5))
+		Expect(rec.Column(3).IsNull(0)).Should(BeTrue())
+	})
+
+	It("should reject bad dates", func() {
+		bad := &api.Table{Symbol: "X", Columns: []string{"Date"}, Rows: [][]interface{}{{"02/01/2018"}}}
+		Expect(WriteStream(ioutil.Discard, []*api.Table{bad})).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,92 @@
+// Package atomicfile writes files so that readers, and a process restarted
+// after a crash, see either the old content or the complete new content.
+//
+// Data is written to a temporary file in the target directory, synced, and
+// renamed over the target. The directory is synced afterwards so the rename
+// itself survives a power loss.
+package atomicfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// TempPrefix starts the name of every temporary file
+const TempPrefix = ".tmp-"
+
+// syncFile flushes a temporary file before it is renamed
+var syncFile = (*os.File).Sync
+
+// WriteFile atomically replaces name with data
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), TempPrefix+filepath.Base(name)+"-")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = syncFile(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return rename(tmp, name)
+}
+
+// TempName returns a temporary name next to name for writers that create the
+// file themselves, see Commit
+func TempName(name string) string {
+	return filepath.Join(filepath.Dir(name), fmt.Sprintf("%s%s-%d", TempPrefix, filepath.Base(name), os.Getpid()))
+}
+
+// Commit syncs the closed file tmp and renames it to name
+func Commit(tmp, name string) error {
+	f, err := os.OpenFile(tmp, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	err = syncFile(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return rename(tmp, name)
+}
+
+// IsTemp reports whether name is a leftover temporary file
+func IsTemp(name string) bool {
+	base := filepath.Base(name)
+	return len(base) > len(TempPrefix) && base[:len(TempPrefix)] == TempPrefix
+}
+
+func rename(tmp, name string) error {
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(name))
+	return nil
+}
+
+// syncDir makes the rename durable. It is best effort as not every file
+// system can sync a directory.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
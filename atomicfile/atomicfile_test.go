@@ -0,0 +1,136 @@
+package atomicfile_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/twold/go-quandl/atomicfile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Atomicfile", func() {
+	var (
+		dir  string
+		name string
+	)
+
+	read := func() string {
+		b, err := ioutil.ReadFile(name)
+		Expect(err).Should(BeNil())
+		return string(b)
+	}
+
+	temps := func() []string {
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).Should(BeNil())
+		found := []string{}
+		for _, fi := range files {
+			if IsTemp(fi.Name()) {
+				found = append(found, fi.Name())
+			}
+		}
+		return found
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "atomicfile")
+		Expect(err).Should(BeNil())
+		name = filepath.Join(dir, "2018-01-02.json")
+		Expect(ioutil.WriteFile(name, []byte("old"), 0666)).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("When writing a file", func() {
+		It("replaces the content and leaves no temporary file", func() {
+			Expect(WriteFile(name, []byte("new"), 0640)).Should(Succeed())
+			Expect(read()).Should(Equal("new"))
+			fi, err := os.Stat(name)
+			Expect(err).Should(BeNil())
+			Expect(fi.Mode().Perm()).Should(Equal(os.FileMode(0640)))
+			Expect(temps()).Should(BeEmpty())
+		})
+
+		It("renames only after the temporary file is synced", func() {
+			synced := []string{}
+			defer SetSync(func(f *os.File) error {
+				// the target still has the old content while syncing
+				synced = append(synced, filepath.Base(f.Name()), read())
+				return f.Sync()
+			})()
+
+			Expect(WriteFile(name, []byte("new"), 0666)).Should(Succeed())
+			Expect(synced).Should(HaveLen(2))
+			Expect(IsTemp(synced[0])).Should(BeTrue())
+			Expect(synced[1]).Should(Equal("old"))
+			Expect(read()).Should(Equal("new"))
+		})
+
+		It("removes the temporary file and keeps the target if the sync fails", func() {
+			defer SetSync(func(*os.File) error {
+				return errors.New("disk full")
+			})()
+
+			Expect(WriteFile(name, []byte("new"), 0666)).Should(MatchError("disk full"))
+			Expect(read()).Should(Equal("old"))
+			Expect(temps()).Should(BeEmpty())
+		})
+
+		It("removes the temporary file if the rename fails", func() {
+			target := filepath.Join(dir, "2018-01-03.json")
+			Expect(os.Mkdir(target, 0777)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(target, "keep"), nil, 0666)).Should(Succeed())
+
+			Expect(WriteFile(target, []byte("new"), 0666)).ShouldNot(Succeed())
+			Expect(temps()).Should(BeEmpty())
+		})
+
+		It("fails if the directory does not exist", func() {
+			Expect(WriteFile(filepath.Join(dir, "missing", "a.json"), []byte("new"), 0666)).ShouldNot(Succeed())
+		})
+	})
+
+	Context("When committing a file written by the caller", func() {
+		It("syncs and renames it", func() {
+			tmp := TempName(name)
+			Expect(filepath.Dir(tmp)).Should(Equal(dir))
+			Expect(IsTemp(tmp)).Should(BeTrue())
+			Expect(ioutil.WriteFile(tmp, []byte("new"), 0666)).Should(Succeed())
+
+			synced := 0
+			defer SetSync(func(f *os.File) error {
+				synced++
+				return f.Sync()
+			})()
+			Expect(Commit(tmp, name)).Should(Succeed())
+			Expect(synced).Should(Equal(1))
+			Expect(read()).Should(Equal("new"))
+			Expect(temps()).Should(BeEmpty())
+		})
+
+		It("removes it and keeps the target if the sync fails", func() {
+			tmp := TempName(name)
+			Expect(ioutil.WriteFile(tmp, []byte("new"), 0666)).Should(Succeed())
+
+			defer SetSync(func(*os.File) error {
+				return errors.New("disk full")
+			})()
+			Expect(Commit(tmp, name)).Should(MatchError("disk full"))
+			Expect(read()).Should(Equal("old"))
+			Expect(temps()).Should(BeEmpty())
+		})
+	})
+
+	It("recognizes temporary files", func() {
+		Expect(IsTemp("/data/output/FB/.tmp-2018-01-02.json-123")).Should(BeTrue())
+		Expect(IsTemp("/data/output/FB/2018-01-02.json")).Should(BeFalse())
+		Expect(IsTemp(TempPrefix)).Should(BeFalse())
+	})
+})
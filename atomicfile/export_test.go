@@ -0,0 +1,10 @@
+package atomicfile
+
+import "os"
+
+// SetSync replaces the sync of temporary files, returning a func restoring it
+func SetSync(sync func(*os.File) error) func() {
+	old := syncFile
+	syncFile = sync
+	return func() { syncFile = old }
+}
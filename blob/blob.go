@@ -0,0 +1,82 @@
+// Package blob saves data sets to object storage such as S3, MinIO or GCS
+// through its S3 interoperability API. Stores implement a small interface so
+// that the sink can be tested against Memory or Dir.
+package blob
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotExist is returned by Get for a missing key
+var ErrNotExist = errors.New("blob: key does not exist")
+
+// MinPartSize is the smallest part S3 accepts in a multipart upload, other
+// than the last one
+const MinPartSize = 5 << 20
+
+// Store is a flat namespace of objects with slash separated keys
+type Store interface {
+	Put(key string, b []byte) error
+	// Get returns ErrNotExist for a missing key
+	Get(key string) ([]byte, error)
+	// List returns the keys starting with prefix in order
+	List(prefix string) ([]string, error)
+	Delete(key string) error
+	// CreateMultipart starts an upload of key in parts
+	CreateMultipart(key string) (Upload, error)
+}
+
+// Upload is a multipart upload. The object appears once Complete returns.
+type Upload interface {
+	// Put uploads part n, counting from 1. b may be reused after it returns.
+	Put(n int, b []byte) error
+	Complete() error
+	Abort() error
+}
+
+// Copy uploads r to key, in parts of partSize bytes if it is larger than
+// one part. partSize defaults to MinPartSize.
+func Copy(s Store, key string, r io.Reader, partSize int) error {
+	if partSize <= 0 {
+		partSize = MinPartSize
+	}
+	buf, next := make([]byte, partSize), make([]byte, partSize)
+	n, err := read(r, buf)
+	if err != nil {
+		return err
+	}
+	m, err := read(r, next)
+	if err != nil {
+		return err
+	}
+	if m == 0 {
+		return s.Put(key, buf[:n])
+	}
+
+	u, err := s.CreateMultipart(key)
+	if err != nil {
+		return err
+	}
+	for part := 1; n > 0; part++ {
+		if err := u.Put(part, buf[:n]); err != nil {
+			u.Abort()
+			return err
+		}
+		buf, next, n = next, buf, m
+		if m, err = read(r, next); err != nil {
+			u.Abort()
+			return err
+		}
+	}
+	return u.Complete()
+}
+
+// read fills b unless r ends first
+func read(r io.Reader, b []byte) (int, error) {
+	n, err := io.ReadFull(r, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
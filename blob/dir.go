@@ -0,0 +1,157 @@
+package blob
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/twold/go-quandl/atomicfile"
+)
+
+// Dir is a Store of files under a directory, keys being their slash
+// separated paths. Objects are replaced atomically.
+type Dir string
+
+func (d Dir) name(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "..") {
+		return "", fmt.Errorf("blob: bad key %q", key)
+	}
+	return filepath.Join(string(d), filepath.FromSlash(key)), nil
+}
+
+func (d Dir) Put(key string, b []byte) error {
+	name, err := d.name(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(name, b, 0644)
+}
+
+func (d Dir) Get(key string) ([]byte, error) {
+	name, err := d.name(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return b, err
+}
+
+func (d Dir) List(prefix string) ([]string, error) {
+	keys := []string{}
+	err := filepath.Walk(string(d), func(name string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || fi.IsDir() || atomicfile.IsTemp(name) {
+			return err
+		}
+		rel, err := filepath.Rel(string(d), name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (d Dir) Delete(key string) error {
+	name, err := d.name(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CreateMultipart keeps the parts in temporary files next to the object
+func (d Dir) CreateMultipart(key string) (Upload, error) {
+	name, err := d.name(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	return &dirUpload{name: name, parts: map[int]string{}}, nil
+}
+
+type dirUpload struct {
+	name  string
+	parts map[int]string
+}
+
+func (u *dirUpload) Put(n int, b []byte) error {
+	if n < 1 {
+		return fmt.Errorf("blob: part number %d", n)
+	}
+	part := fmt.Sprintf("%s.part%d", atomicfile.TempName(u.name), n)
+	if err := ioutil.WriteFile(part, b, 0644); err != nil {
+		return err
+	}
+	u.parts[n] = part
+	return nil
+}
+
+func (u *dirUpload) Complete() error {
+	defer u.Abort()
+	nums := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	tmp := atomicfile.TempName(u.name)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	for _, n := range nums {
+		if err = appendFile(f, u.parts[n]); err != nil {
+			break
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = atomicfile.Commit(tmp, u.name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (u *dirUpload) Abort() error {
+	for _, part := range u.parts {
+		os.Remove(part)
+	}
+	u.parts = map[int]string{}
+	return nil
+}
+
+func appendFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
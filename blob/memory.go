@@ -0,0 +1,92 @@
+package blob
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a Store in memory, for tests
+type Memory struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{objects: map[string][]byte{}}
+}
+
+func (m *Memory) Put(key string, b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), b...)
+	return nil
+}
+
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (m *Memory) List(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := []string{}
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *Memory) CreateMultipart(key string) (Upload, error) {
+	return &memoryUpload{m: m, key: key, parts: map[int][]byte{}}, nil
+}
+
+type memoryUpload struct {
+	m     *Memory
+	key   string
+	parts map[int][]byte
+}
+
+func (u *memoryUpload) Put(n int, b []byte) error {
+	if n < 1 {
+		return fmt.Errorf("blob: part number %d", n)
+	}
+	u.parts[n] = append([]byte(nil), b...)
+	return nil
+}
+
+func (u *memoryUpload) Complete() error {
+	nums := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	var b []byte
+	for _, n := range nums {
+		b = append(b, u.parts[n]...)
+	}
+	return u.m.Put(u.key, b)
+}
+
+func (u *memoryUpload) Abort() error {
+	u.parts = nil
+	return nil
+}
@@ -0,0 +1,304 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 is a bucket of an S3 compatible service, addressed path style as
+// Endpoint/Bucket/key so that it works with MinIO and GCS as well. Requests
+// are signed with AWS Signature Version 4.
+type S3 struct {
+	// Endpoint, e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (s *S3) Put(key string, b []byte) error {
+	resp, err := s.do("PUT", key, nil, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Get(key string) ([]byte, error) {
+	resp, err := s.do("GET", key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s *S3) Delete(key string) error {
+	resp, err := s.do("DELETE", key, nil, nil)
+	if err == ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) List(prefix string) ([]string, error) {
+	keys := []string{}
+	q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		var res struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := s.decode("GET", "", q, nil, &res); err != nil {
+			return nil, err
+		}
+		for _, c := range res.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		q.Set("continuation-token", res.NextContinuationToken)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *S3) CreateMultipart(key string) (Upload, error) {
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.decode("POST", key, url.Values{"uploads": {""}}, nil, &res); err != nil {
+		return nil, err
+	}
+	return &s3Upload{s: s, key: key, id: res.UploadID, etags: map[int]string{}}, nil
+}
+
+type s3Upload struct {
+	s     *S3
+	key   string
+	id    string
+	etags map[int]string
+}
+
+func (u *s3Upload) Put(n int, b []byte) error {
+	q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {u.id}}
+	resp, err := u.s.do("PUT", u.key, q, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	u.etags[n] = resp.Header.Get("ETag")
+	return nil
+}
+
+func (u *s3Upload) Complete() error {
+	type part struct {
+		PartNumber int
+		ETag       string
+	}
+	var req struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}
+	for n, etag := range u.etags {
+		req.Parts = append(req.Parts, part{PartNumber: n, ETag: etag})
+	}
+	sort.Slice(req.Parts, func(i, j int) bool { return req.Parts[i].PartNumber < req.Parts[j].PartNumber })
+
+	b, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	// S3 may answer 200 with an error document
+	var res struct {
+		Code    string
+		Message string
+	}
+	if err := u.s.decode("POST", u.key, url.Values{"uploadId": {u.id}}, b, &res); err != nil {
+		return err
+	}
+	if res.Code != "" {
+		return fmt.Errorf("blob: complete %s: %s: %s", u.key, res.Code, res.Message)
+	}
+	return nil
+}
+
+func (u *s3Upload) Abort() error {
+	resp, err := u.s.do("DELETE", u.key, url.Values{"uploadId": {u.id}}, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// decode sends a request and decodes its xml response into v
+func (s *S3) decode(method, key string, q url.Values, body []byte, v interface{}) error {
+	resp, err := s.do(method, key, q, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// do sends a signed request for key, or for the bucket if key is empty
+func (s *S3) do(method, key string, q url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	p := "/" + s.Bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u.Path += p
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = strings.Replace(q.Encode(), "+", "%20", -1)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	s.Sign(req, hex.EncodeToString(sum[:]), time.Now())
+
+	c := s.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && key != "" && len(q) == 0 && (method == "GET" || method == "DELETE") {
+		resp.Body.Close()
+		return nil, ErrNotExist
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var e struct {
+			Code    string
+			Message string
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		xml.Unmarshal(b, &e)
+		return nil, fmt.Errorf("blob: %s %s: %s %s: %s", method, key, resp.Status, e.Code, e.Message)
+	}
+	return resp, nil
+}
+
+// Sign adds the AWS Signature Version 4 headers for payloadHash, the hex
+// SHA-256 of the body, to req. Every header already set is signed.
+func (s *S3) Sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	stamp, day := t.Format("20060102T150405Z"), t.Format("20060102")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canon := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canon))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	for _, v := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signed, sig))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escape is the URI encoding of Signature Version 4
+func escape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// escapePath escapes each segment of p
+func escapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = escape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
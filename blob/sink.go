@@ -0,0 +1,214 @@
+package blob
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/manifest"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/store"
+)
+
+// Sink saves rows like api.JSONFiles, or api.ParquetFiles if Parquet is set,
+// under the same keys Prefix/output/<SYMBOL>/<YYYY-MM-DD>.<ext>. Objects
+// whose checksum matches the manifest are not uploaded again, and the
+// manifest is stored last as the commit point. Parquet objects do not save
+// enrich fields, a warning is logged for rows carrying them.
+type Sink struct {
+	Store   Store
+	Prefix  string
+	Parquet bool
+	Log     logging.Logger
+	Metrics metrics.Metrics
+}
+
+func (s *Sink) Write(src api.Source, objs []api.Wiki) error {
+	l, m, start := logging.OrDiscard(s.Log), metrics.OrDiscard(s.Metrics), time.Now()
+	dir := Key(s.Prefix, "output", src.Symbol)
+	man, err := loadManifest(s.Store, dir, src.Symbol)
+	if err != nil {
+		return err
+	}
+
+	ext, sink := ".json", "blob-json"
+	if s.Parquet {
+		ext, sink = ".parquet", "blob-parquet"
+		if api.Enriched(objs) {
+			l.Warn("Enrich fields are not saved in parquet files.", logging.Symbol, src.Symbol)
+		}
+	}
+
+	n := 0
+	for _, obj := range objs {
+		if obj.Date == nil {
+			continue
+		}
+		b, err := encode(obj, s.Parquet)
+		if err != nil {
+			return err
+		}
+		name := *obj.Date + ext
+		sum := manifest.Sum(b)
+		if e, ok := man.Files[name]; ok && e.SHA256 == sum {
+			continue
+		}
+
+		if err := s.Store.Put(Key(dir, name), b); err != nil {
+			return err
+		}
+		man.Add(name, manifest.Entry{
+			Rows:      1,
+			FirstDate: *obj.Date,
+			LastDate:  *obj.Date,
+			Size:      int64(len(b)),
+			SHA256:    sum,
+			FetchedAt: src.FetchedAt,
+		})
+		m.Add(metrics.FilesWritten, 1, metrics.Sink, sink)
+		l.Debug("Write finished.", logging.Symbol, src.Symbol, logging.File, Key(dir, name))
+		n++
+	}
+
+	man.DBCode, man.SourceURL, man.FetchedAt = src.DBCode, src.URL, src.FetchedAt
+	b, err := man.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := s.Store.Put(Key(dir, manifest.Name), b); err != nil {
+		return err
+	}
+	l.Info("Uploaded objects.", logging.Symbol, src.Symbol, logging.Rows, n, logging.Duration, time.Since(start))
+	return nil
+}
+
+// Key joins the parts of a key, skipping empty ones
+func Key(parts ...string) string {
+	return path.Join(parts...)
+}
+
+func loadManifest(s Store, dir, symbol string) (*manifest.Manifest, error) {
+	b, err := s.Get(Key(dir, manifest.Name))
+	if err == ErrNotExist {
+		return &manifest.Manifest{Symbol: symbol, Files: map[string]manifest.Entry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Parse(symbol, b)
+}
+
+// encode returns a daily file as the local sinks write it
+func encode(obj api.Wiki, parquet bool) ([]byte, error) {
+	if !parquet {
+		return json.MarshalIndent(obj, "", "	")
+	}
+
+	f, err := ioutil.TempFile("", atomicfile.TempPrefix+"blob-*.parquet")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := store.WriteParquet(f.Name(), []api.Wiki{obj}); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(f.Name())
+}
+
+// Push uploads the files saved under path/output for symbols, all symbols
+// if none are given, to the same keys under prefix, subdirectories and the
+// revision logs under path/revisions included. Large files such as compacted
+// parquet files are uploaded in parts of partSize bytes. The manifest of a
+// symbol is uploaded after its files.
+func Push(s Store, prefix, path string, partSize int, symbols ...string) (int, error) {
+	return PushLayout(s, prefix, path, nil, partSize, symbols...)
+}
+
+// PushLayout is Push for the files saved under path by a store.Partitioned
+// sink with layout t, the default output/<SYMBOL> files if t is nil
+func PushLayout(s Store, prefix, path string, t *layout.Template, partSize int, symbols ...string) (int, error) {
+	if len(symbols) == 0 {
+		r, err := store.OpenLayout(path, t)
+		if err != nil {
+			return 0, err
+		}
+		if symbols, err = r.Symbols(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, pushed := 0, map[string]bool{}
+	for _, symbol := range symbols {
+		var files []string
+		var err error
+		if t == nil {
+			files, err = symbolFiles(filepath.Join(path, "output", symbol))
+		} else {
+			files, err = store.Partitions(path, t, symbol)
+		}
+		if err != nil {
+			return n, err
+		}
+		revs := filepath.Join(path, store.RevisionDir, symbol+".ndjson")
+		if _, err := os.Stat(revs); err == nil {
+			files = append(files, revs)
+		}
+
+		for _, name := range files {
+			if pushed[name] {
+				// a partition of a layout without symbols holds them all
+				continue
+			}
+			rel, err := filepath.Rel(path, name)
+			if err != nil {
+				return n, err
+			}
+			if err := pushFile(s, Key(prefix, filepath.ToSlash(rel)), name, partSize); err != nil {
+				return n, err
+			}
+			pushed[name] = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+// symbolFiles returns the files under the folder of a symbol, its manifest
+// last
+func symbolFiles(dir string) ([]string, error) {
+	files, man := []string{}, ""
+	err := filepath.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case fi.IsDir() || atomicfile.IsTemp(fi.Name()):
+			return nil
+		case name == filepath.Join(dir, manifest.Name):
+			man = name
+			return nil
+		}
+		files = append(files, name)
+		return nil
+	})
+	if man != "" {
+		files = append(files, man)
+	}
+	return files, err
+}
+
+func pushFile(s Store, key, name string, partSize int) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Copy(s, key, f, partSize)
+}
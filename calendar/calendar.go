@@ -0,0 +1,330 @@
+// Package calendar knows the trading sessions of the exchanges behind the
+// Quandl databases: NYSE and NASDAQ for WIKI equities, CFE for the CBOE
+// futures. Calendars know the holidays, the early closes and the historical
+// special closures from 1970 on, and are used to find missing sessions, to
+// step a number of trading days and to plan incremental fetches.
+//
+// Dates are days: times are reduced to their year, month and day and
+// returned at midnight UTC, like the dates of the saved files.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // session times do not depend on the host's zoneinfo
+)
+
+// DateLayout is the format of dates
+const DateLayout = "2006-01-02"
+
+// Day is a holiday or an early close
+type Day struct {
+	Date string `json:"date" type:"string"`
+	Name string `json:"name" type:"string"`
+}
+
+// Calendar is the trading schedule of an exchange
+type Calendar struct {
+	Name string
+	// Location of the session times
+	Location *time.Location
+	// Open, Close and EarlyClose are the session times after midnight
+	Open       time.Duration
+	Close      time.Duration
+	EarlyClose time.Duration
+	// First is the first session, zero if it is older than the rules
+	First time.Time
+
+	holidays func(year int) []Day
+	early    func(year int) []Day
+}
+
+// Calendars by name
+var (
+	NYSE = &Calendar{
+		Name:       "nyse",
+		Location:   load("America/New_York"),
+		Open:       9*time.Hour + 30*time.Minute,
+		Close:      16 * time.Hour,
+		EarlyClose: 13 * time.Hour,
+		holidays:   nyseHolidays,
+		early:      nyseEarlyCloses,
+	}
+
+	// NASDAQ closes on the NYSE holidays, with the same hours
+	NASDAQ = &Calendar{
+		Name:       "nasdaq",
+		Location:   NYSE.Location,
+		Open:       NYSE.Open,
+		Close:      NYSE.Close,
+		EarlyClose: NYSE.EarlyClose,
+		First:      Date(1971, time.February, 8),
+		holidays:   nyseHolidays,
+		early:      nyseEarlyCloses,
+	}
+
+	// CFE, the CBOE Futures Exchange, closes on the NYSE holidays; Chicago
+	// hours are those of the VIX futures settlement session
+	CFE = &Calendar{
+		Name:       "cfe",
+		Location:   load("America/Chicago"),
+		Open:       8*time.Hour + 30*time.Minute,
+		Close:      15*time.Hour + 15*time.Minute,
+		EarlyClose: 12*time.Hour + 15*time.Minute,
+		First:      Date(2004, time.March, 26),
+		holidays:   nyseHolidays,
+		early:      nyseEarlyCloses,
+	}
+)
+
+// Calendars lists the calendars by name
+var Calendars = map[string]*Calendar{NYSE.Name: NYSE, NASDAQ.Name: NASDAQ, CFE.Name: CFE}
+
+// Get returns the calendar named name, case insensitive
+func Get(name string) (*Calendar, error) {
+	c, ok := Calendars[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(Calendars))
+		for n := range Calendars {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown calendar %q, options are %s", name, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+// ForDB returns the calendar of a Quandl database code, NYSE by default
+func ForDB(dbCode string) *Calendar {
+	if dbCode == "CBOE" {
+		return CFE
+	}
+	return NYSE
+}
+
+func load(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Date returns the day year-month-day at midnight UTC
+func Date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// day drops the time of t
+func day(t time.Time) time.Time {
+	return Date(t.Year(), t.Month(), t.Day())
+}
+
+// Parse parses a YYYY-MM-DD date
+func Parse(s string) (time.Time, error) {
+	return time.Parse(DateLayout, s)
+}
+
+// Holidays returns the weekdays of year the exchange is closed, holidays and
+// special closures, ordered by date
+func (c *Calendar) Holidays(year int) []Day {
+	days := c.holidays(year)
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// EarlyCloses returns the sessions of year that close early, ordered by date
+func (c *Calendar) EarlyCloses(year int) []Day {
+	days := c.early(year)
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// Holiday returns the name of the holiday on d, or ""
+func (c *Calendar) Holiday(d time.Time) string {
+	s := d.Format(DateLayout)
+	for _, h := range c.holidays(d.Year()) {
+		if h.Date == s {
+			return h.Name
+		}
+	}
+	return ""
+}
+
+// IsSession reports whether the exchange trades on d
+func (c *Calendar) IsSession(d time.Time) bool {
+	d = day(d)
+	if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		return false
+	}
+	if !c.First.IsZero() && d.Before(c.First) {
+		return false
+	}
+	return c.Holiday(d) == ""
+}
+
+// IsEarlyClose reports whether the session on d closes early
+func (c *Calendar) IsEarlyClose(d time.Time) bool {
+	s := d.Format(DateLayout)
+	for _, e := range c.early(d.Year()) {
+		if e.Date == s {
+			return c.IsSession(d)
+		}
+	}
+	return false
+}
+
+// Hours returns the open and close of the session on d in the exchange's
+// location, ok is false if there is no session
+func (c *Calendar) Hours(d time.Time) (open, close time.Time, ok bool) {
+	if !c.IsSession(d) {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, c.Location)
+	end := c.Close
+	if c.IsEarlyClose(d) {
+		end = c.EarlyClose
+	}
+	return midnight.Add(c.Open), midnight.Add(end), true
+}
+
+// Sessions returns the sessions from start to end, both included. It can be
+// used as store.VerifyOptions.Sessions.
+func (c *Calendar) Sessions(start, end time.Time) []time.Time {
+	start, end = day(start), day(end)
+	closed := map[string]bool{}
+	year := 0
+
+	days := []time.Time{}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Year() != year {
+			year = d.Year()
+			closed = map[string]bool{}
+			for _, h := range c.holidays(year) {
+				closed[h.Date] = true
+			}
+		}
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || closed[d.Format(DateLayout)] {
+			continue
+		}
+		if !c.First.IsZero() && d.Before(c.First) {
+			continue
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+// Count returns the number of sessions from start to end, both included
+func (c *Calendar) Count(start, end time.Time) int {
+	return len(c.Sessions(start, end))
+}
+
+// Offset returns the session n sessions after d, or before it if n is
+// negative. Offset 0 is d itself if it is a session and the next session
+// otherwise. The zero time is returned before the first session.
+func (c *Calendar) Offset(d time.Time, n int) time.Time {
+	d = day(d)
+	if n == 0 {
+		if c.IsSession(d) {
+			return d
+		}
+		return c.Offset(d, 1)
+	}
+
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		d = d.AddDate(0, 0, step)
+		if step < 0 && !c.First.IsZero() && d.Before(c.First) {
+			return time.Time{}
+		}
+		if c.IsSession(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// Next returns the first session after d
+func (c *Calendar) Next(d time.Time) time.Time {
+	return c.Offset(d, 1)
+}
+
+// Prev returns the last session before d
+func (c *Calendar) Prev(d time.Time) time.Time {
+	return c.Offset(d, -1)
+}
+
+// Missing returns the sessions between the first and the last of dates that
+// are not in dates. Dates are YYYY-MM-DD and need not be sorted.
+func (c *Calendar) Missing(dates []string) ([]string, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+	have := make(map[string]bool, len(dates))
+	first, last := dates[0], dates[0]
+	for _, d := range dates {
+		have[d] = true
+		if d < first {
+			first = d
+		}
+		if d > last {
+			last = d
+		}
+	}
+	start, err := Parse(first)
+	if err != nil {
+		return nil, err
+	}
+	end, err := Parse(last)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, d := range c.Sessions(start, end) {
+		if s := d.Format(DateLayout); !have[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing, nil
+}
+
+// LastClosed returns the last session that has closed at now
+func (c *Calendar) LastClosed(now time.Time) time.Time {
+	local := now.In(c.Location)
+	d := day(local)
+	if _, close, ok := c.Hours(d); ok && !local.Before(close) {
+		return d
+	}
+	return c.Prev(d)
+}
+
+// Plan returns the dates to fetch after the last saved session last, up to
+// the last session closed at now. Start is "" if nothing is saved yet and ok
+// is false if there is nothing new to fetch.
+func (c *Calendar) Plan(last string, now time.Time) (start, end string, ok bool) {
+	to := c.LastClosed(now)
+	if to.IsZero() {
+		return "", "", false
+	}
+	end = to.Format(DateLayout)
+	if last == "" {
+		return "", end, true
+	}
+
+	d, err := Parse(last)
+	if err != nil {
+		return "", end, true
+	}
+	from := c.Next(d)
+	if from.After(to) {
+		return "", "", false
+	}
+	return from.Format(DateLayout), end, true
+}
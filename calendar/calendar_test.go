@@ -0,0 +1,117 @@
+package calendar_test
+
+import (
+	"time"
+
+	. "github.com/twold/go-quandl/calendar"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func dates(days []Day) []string {
+	out := []string{}
+	for _, d := range days {
+		out = append(out, d.Date)
+	}
+	return out
+}
+
+func format(days []time.Time) []string {
+	out := []string{}
+	for _, d := range days {
+		out = append(out, d.Format(DateLayout))
+	}
+	return out
+}
+
+func date(s string) time.Time {
+	d, err := Parse(s)
+	Expect(err).Should(BeNil())
+	return d
+}
+
+var _ = Describe("Calendar", func() {
+	It("should know the NYSE holidays", func() {
+		Expect(dates(NYSE.Holidays(2018))).Should(Equal([]string{
+			"2018-01-01", "2018-01-15", "2018-02-19", "2018-03-30", "2018-05-28",
+			"2018-07-04", "2018-09-03", "2018-11-22", "2018-12-05", "2018-12-25",
+		}))
+		// new year's day on a Saturday is not observed, Juneteenth and
+		// Christmas on a Sunday move to Monday
+		Expect(dates(NYSE.Holidays(2022))).Should(Equal([]string{
+			"2022-01-17", "2022-02-21", "2022-04-15", "2022-05-30", "2022-06-20",
+			"2022-07-04", "2022-09-05", "2022-11-24", "2022-12-26",
+		}))
+		Expect(dates(NYSE.Holidays(2021))).Should(ContainElement("2021-12-24"))
+		Expect(NYSE.Holiday(date("2012-10-30"))).Should(Equal("Hurricane Sandy"))
+		Expect(NYSE.Holiday(date("1976-11-02"))).Should(Equal("Election Day"))
+	})
+
+	It("should know the early closes", func() {
+		Expect(dates(NYSE.EarlyCloses(2019))).Should(Equal([]string{"2019-07-03", "2019-11-29", "2019-12-24"}))
+		Expect(dates(NYSE.EarlyCloses(2020))).Should(Equal([]string{"2020-11-27", "2020-12-24"}))
+
+		open, close, ok := NYSE.Hours(date("2019-11-29"))
+		Expect(ok).Should(BeTrue())
+		Expect(open.Format("15:04 MST")).Should(Equal("09:30 EST"))
+		Expect(close.Format("15:04 MST")).Should(Equal("13:00 EST"))
+		_, close, _ = CFE.Hours(date("2019-07-02"))
+		Expect(close.Format("15:04 MST")).Should(Equal("15:15 CDT"))
+		_, _, ok = NYSE.Hours(date("2019-07-04"))
+		Expect(ok).Should(BeFalse())
+	})
+
+	It("should list sessions", func() {
+		Expect(format(NYSE.Sessions(date("2012-10-26"), date("2012-11-01")))).Should(Equal([]string{
+			"2012-10-26", "2012-10-31", "2012-11-01",
+		}))
+		Expect(NYSE.Count(date("2018-01-01"), date("2018-12-31"))).Should(Equal(251))
+		Expect(CFE.Sessions(date("2004-03-01"), date("2004-03-29"))).Should(HaveLen(2))
+		Expect(NYSE.IsSession(date("2018-03-30"))).Should(BeFalse())
+		Expect(NYSE.IsSession(date("2018-03-29"))).Should(BeTrue())
+	})
+
+	It("should find missing sessions", func() {
+		missing, err := NYSE.Missing([]string{"2018-04-03", "2018-03-28", "2018-04-04"})
+		Expect(err).Should(BeNil())
+		Expect(missing).Should(Equal([]string{"2018-03-29", "2018-04-02"}))
+	})
+
+	It("should step trading days", func() {
+		Expect(NYSE.Next(date("2018-03-29")).Format(DateLayout)).Should(Equal("2018-04-02"))
+		Expect(NYSE.Prev(date("2018-04-02")).Format(DateLayout)).Should(Equal("2018-03-29"))
+		Expect(NYSE.Offset(date("2018-12-31"), 2).Format(DateLayout)).Should(Equal("2019-01-03"))
+		Expect(NYSE.Offset(date("2018-03-31"), 0).Format(DateLayout)).Should(Equal("2018-04-02"))
+		Expect(NYSE.Offset(date("2018-03-28"), -5).Format(DateLayout)).Should(Equal("2018-03-21"))
+		Expect(CFE.Prev(date("2004-03-26")).IsZero()).Should(BeTrue())
+	})
+
+	It("should plan incremental fetches", func() {
+		ny := NYSE.Location
+		before := time.Date(2018, 4, 2, 15, 0, 0, 0, ny)
+		after := time.Date(2018, 4, 2, 16, 30, 0, 0, ny)
+
+		_, _, ok := NYSE.Plan("2018-03-29", before)
+		Expect(ok).Should(BeFalse())
+		start, end, ok := NYSE.Plan("2018-03-29", after)
+		Expect(ok).Should(BeTrue())
+		Expect([]string{start, end}).Should(Equal([]string{"2018-04-02", "2018-04-02"}))
+		start, end, ok = NYSE.Plan("2018-03-20", time.Date(2018, 3, 31, 12, 0, 0, 0, ny))
+		Expect(ok).Should(BeTrue())
+		Expect([]string{start, end}).Should(Equal([]string{"2018-03-21", "2018-03-29"}))
+		start, end, ok = NYSE.Plan("", after)
+		Expect(ok).Should(BeTrue())
+		Expect([]string{start, end}).Should(Equal([]string{"", "2018-04-02"}))
+	})
+
+	It("should look calendars up", func() {
+		c, err := Get("NYSE")
+		Expect(err).Should(BeNil())
+		Expect(c).Should(Equal(NYSE))
+		_, err = Get("lse")
+		Expect(err).ShouldNot(BeNil())
+		Expect(ForDB("CBOE")).Should(Equal(CFE))
+		Expect(ForDB("WIKI")).Should(Equal(NYSE))
+	})
+})
@@ -0,0 +1,142 @@
+package calendar
+
+import (
+	"strconv"
+	"time"
+)
+
+// nyseSpecial are the unscheduled NYSE closures since 1970
+var nyseSpecial = []Day{
+	{"1972-12-28", "Funeral of President Truman"},
+	{"1973-01-25", "Funeral of President Johnson"},
+	{"1977-07-14", "New York City blackout"},
+	{"1985-09-27", "Hurricane Gloria"},
+	{"1994-04-27", "Funeral of President Nixon"},
+	{"2001-09-11", "September 11"},
+	{"2001-09-12", "September 11"},
+	{"2001-09-13", "September 11"},
+	{"2001-09-14", "September 11"},
+	{"2004-06-11", "Funeral of President Reagan"},
+	{"2007-01-02", "Day of mourning for President Ford"},
+	{"2012-10-29", "Hurricane Sandy"},
+	{"2012-10-30", "Hurricane Sandy"},
+	{"2018-12-05", "Day of mourning for President George H. W. Bush"},
+	{"2025-01-09", "Day of mourning for President Carter"},
+}
+
+// nyseHolidays returns the holidays and special closures of year
+func nyseHolidays(year int) []Day {
+	days := []Day{}
+	add := func(d time.Time, name string) {
+		if d.Year() == year && d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days = append(days, Day{Date: d.Format(DateLayout), Name: name})
+		}
+	}
+
+	// a new year's day on a Saturday is not moved to the Friday before
+	newYear := Date(year, time.January, 1)
+	if newYear.Weekday() == time.Sunday {
+		newYear = newYear.AddDate(0, 0, 1)
+	}
+	add(newYear, "New Year's Day")
+	if year >= 1998 {
+		add(nth(year, time.January, time.Monday, 3), "Martin Luther King Jr. Day")
+	}
+	if year >= 1971 {
+		add(nth(year, time.February, time.Monday, 3), "Washington's Birthday")
+	} else {
+		add(observed(Date(year, time.February, 22)), "Washington's Birthday")
+	}
+	add(easter(year).AddDate(0, 0, -2), "Good Friday")
+	if year >= 1971 {
+		add(last(year, time.May, time.Monday), "Memorial Day")
+	} else {
+		add(observed(Date(year, time.May, 30)), "Memorial Day")
+	}
+	if year >= 2022 {
+		add(observed(Date(year, time.June, 19)), "Juneteenth")
+	}
+	add(observed(Date(year, time.July, 4)), "Independence Day")
+	add(nth(year, time.September, time.Monday, 1), "Labor Day")
+	if year == 1972 || year == 1976 || year == 1980 {
+		add(nth(year, time.November, time.Monday, 1).AddDate(0, 0, 1), "Election Day")
+	}
+	add(nth(year, time.November, time.Thursday, 4), "Thanksgiving Day")
+	add(observed(Date(year, time.December, 25)), "Christmas Day")
+
+	for _, d := range nyseSpecial {
+		if d.Date[:4] == strconv.Itoa(year) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// nyseEarlyCloses returns the sessions of year closing at 1 p.m.
+func nyseEarlyCloses(year int) []Day {
+	days := []Day{}
+	add := func(d time.Time, name string) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days = append(days, Day{Date: d.Format(DateLayout), Name: name})
+		}
+	}
+
+	// July 3rd when the 4th is a Tuesday to Friday, a Friday 3rd is the
+	// holiday of a Saturday 4th
+	if year >= 1995 {
+		if d := Date(year, time.July, 3); d.Weekday() != time.Friday {
+			add(d, "Day before Independence Day")
+		}
+	}
+	if year >= 1993 {
+		add(nth(year, time.November, time.Thursday, 4).AddDate(0, 0, 1), "Day after Thanksgiving")
+	}
+	// December 24th when Christmas is a Tuesday to Friday
+	if year >= 1999 {
+		if d := Date(year, time.December, 24); d.Weekday() != time.Friday {
+			add(d, "Christmas Eve")
+		}
+	}
+	return days
+}
+
+// observed moves a holiday on a Saturday to the Friday before and one on a
+// Sunday to the Monday after
+func observed(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// nth returns the nth weekday of month, counting from 1
+func nth(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	d := Date(year, month, 1)
+	d = d.AddDate(0, 0, (int(weekday)-int(d.Weekday())+7)%7)
+	return d.AddDate(0, 0, 7*(n-1))
+}
+
+// last returns the last weekday of month
+func last(year int, month time.Month, weekday time.Weekday) time.Time {
+	d := Date(year, month+1, 1).AddDate(0, 0, -1)
+	return d.AddDate(0, 0, -((int(d.Weekday()) - int(weekday) + 7) % 7))
+}
+
+// easter returns Easter Sunday of year, by the anonymous Gregorian algorithm
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return Date(year, time.Month(month), day)
+}
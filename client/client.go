@@ -2,14 +2,31 @@ package client
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/redact"
 	"github.com/twold/go-quandl/request"
 )
 
+// AuthHeader is the header Quandl reads the API key from.
+const AuthHeader = "X-Api-Token"
+
 type ClientInfo struct {
 	APIVersion  string
 	APIKey      *string
+	keyInQuery  bool
+	params      url.Values
+	attempts    int
+	backoff     time.Duration
+	log         logging.Logger
+	stats       metrics.Metrics
 	dataType    string
 	dbCode      string
 	format      string
@@ -31,8 +48,31 @@ func New(service string) *Client {
 	}
 }
 
+// the key is sent in the X-Api-Token header and registered for redaction
 func (c *Client) Auth(key *string) *Client {
 	c.APIKey = key
+	if key != nil {
+		redact.Add(*key)
+	}
+	return c
+}
+
+// AuthQuery sends the key as the legacy api_key query parameter instead of
+// the header, for proxies that strip unknown headers.
+func (c *Client) AuthQuery() *Client {
+	c.keyInQuery = true
+	return c
+}
+
+// Logger receives retry warnings, by default nothing is logged
+func (c *Client) Logger(l logging.Logger) *Client {
+	c.log = l
+	return c
+}
+
+// Metrics records requests, latency, retries and rate limit waits in m
+func (c *Client) Metrics(m metrics.Metrics) *Client {
+	c.stats = m
 	return c
 }
 
@@ -54,19 +94,142 @@ func (c *Client) Format(format string) *Client {
 	return c
 }
 
+// Params adds query parameters, e.g. start_date and end_date, to every request
+func (c *Client) Params(params url.Values) *Client {
+	if c.params == nil {
+		c.params = url.Values{}
+	}
+	for k, v := range params {
+		c.params[k] = v
+	}
+	return c
+}
+
+// Retry resends requests that fail with a network error, 429 or 5xx status up
+// to attempts more times, doubling backoff after every try
+func (c *Client) Retry(attempts int, backoff time.Duration) *Client {
+	c.attempts = attempts
+	c.backoff = backoff
+	return c
+}
+
 func (c *Client) Do(method, ticker string) (*Client, error) {
-	url, err := endpoints.New(c.serviceName, c.dbCode, ticker, c.dataType, c.format)
+	e, err := endpoints.New(c.serviceName, c.dbCode, ticker, c.dataType, c.format)
 	if err != nil {
 		return nil, err
 	}
-	// add API key to query string
-	if c.APIKey != nil {
-		url.URL = fmt.Sprintf("%s?api_key=%s", url.URL, *c.APIKey)
+	return c.send(method, e.URL, url.Values{})
+}
+
+// Search queries the dataset search endpoint, restricted to the client's
+// database code when one is set
+func (c *Client) Search(method, query string, page, perPage int) (*Client, error) {
+	e, err := endpoints.Search(c.serviceName, c.format)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	params := url.Values{}
+	if query != "" {
+		params.Set("query", query)
+	}
+	if c.dbCode != "" {
+		params.Set("database_code", c.dbCode)
+	}
+	if page > 0 {
+		params.Set("page", strconv.Itoa(page))
 	}
+	if perPage > 0 {
+		params.Set("per_page", strconv.Itoa(perPage))
+	}
+	return c.send(method, e.URL, params)
+}
+
+func (c *Client) send(method, URL string, params url.Values) (*Client, error) {
+	for k, v := range c.params {
+		for _, s := range v {
+			params.Add(k, s)
+		}
+	}
+
+	// add API key to header, or query string when requested
+	header := http.Header{}
+	if c.APIKey != nil && *c.APIKey != "" {
+		if c.keyInQuery {
+			params.Set("api_key", *c.APIKey)
+		} else {
+			header.Set(AuthHeader, *c.APIKey)
+		}
+	}
+
+	if len(params) > 0 {
+		URL = fmt.Sprintf("%s?%s", URL, params.Encode())
+	}
+
+	// send from a copy so that concurrent requests do not share a response
+	cl := *c
+	m := metrics.OrDiscard(c.stats)
+	for i := 0; ; i++ {
+		start := time.Now()
+		cl.Request = request.NewWithHeader(method, URL, header, nil)
+		m.Observe(metrics.RequestSeconds, time.Since(start).Seconds(), metrics.DB, c.dbCode)
+		m.Add(metrics.Requests, 1, metrics.DB, c.dbCode, metrics.Status, cl.statusCode())
+		if i >= c.attempts || !cl.retryable() {
+			break
+		}
+		if cl.HTTPResponse != nil {
+			cl.HTTPResponse.Body.Close()
+		}
+
+		wait := c.backoff << uint(i)
+		m.Add(metrics.Retries, 1, metrics.DB, c.dbCode)
+		if cl.Error == nil && cl.HTTPResponse.StatusCode == http.StatusTooManyRequests {
+			m.Add(metrics.RateLimitWaits, 1, metrics.DB, c.dbCode)
+			m.Observe(metrics.RateLimitWaitSeconds, wait.Seconds(), metrics.DB, c.dbCode)
+		}
+		logging.OrDiscard(c.log).Warn("Retrying request.", logging.DBCode, c.dbCode, logging.URL, redact.String(URL),
+			"attempt", i+1, "wait", wait, logging.Error, cl.status())
+		time.Sleep(wait)
+	}
+
+	if cl.Error != nil {
+		return nil, redact.Error(cl.Error)
+	}
+	return &cl, nil
+}
 
-	c.Request = request.New(method, url.URL, nil)
+func (c *Client) statusCode() string {
 	if c.Error != nil {
-		return nil, c.Error
+		return "error"
+	}
+	return strconv.Itoa(c.HTTPResponse.StatusCode)
+}
+
+func (c *Client) status() string {
+	if c.Error != nil {
+		return redact.String(c.Error.Error())
+	}
+	return c.HTTPResponse.Status
+}
+
+func (c *Client) retryable() bool {
+	if c.Error != nil {
+		return true
+	}
+	code := c.HTTPResponse.StatusCode
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// Database returns the database code, e.g. "WIKI"
+func (c *Client) Database() string {
+	return c.dbCode
+}
+
+// URL returns the last requested URL with any credentials masked.
+func (c *Client) URL() string {
+	if c.Request == nil || c.HTTPRequest == nil {
+		return ""
 	}
-	return c, nil
+	return redact.String(c.HTTPRequest.URL.String())
 }
@@ -0,0 +1,92 @@
+// Package corporate extracts the corporate actions of a symbol from the
+// ExDividend and SplitRatio columns of its WIKI rows: splits with their
+// ratio and cash dividends with their amount, both dated by their ex-date.
+// Quandl sets SplitRatio to 1 and ExDividend to 0 on days without an action.
+package corporate
+
+import (
+	"sort"
+
+	"github.com/twold/go-quandl/api"
+)
+
+// Types
+const (
+	Split    = "split"
+	Dividend = "dividend"
+)
+
+// Action is a split or a cash dividend
+type Action struct {
+	Symbol string `json:"symbol" type:"string"`
+	// Date is the ex-date
+	Date string `json:"date" type:"string"`
+	Type string `json:"type" type:"string"`
+	// Ratio of a split, new shares per old share, e.g. 2 for a 2-for-1
+	// split and 0.1 for a 1-for-10 reverse split
+	Ratio float64 `json:"ratio,omitempty" type:"float64"`
+	// Amount of a dividend per share
+	Amount float64 `json:"amount,omitempty" type:"float64"`
+}
+
+// Columns are the columns of a table of actions
+var Columns = []string{"Date", "SplitRatio", "Dividend"}
+
+// Extract returns the actions in the rows of symbol ordered by date, a split
+// before a dividend of the same date
+func Extract(symbol string, objs []api.Wiki) []Action {
+	acts := []Action{}
+	for _, obj := range objs {
+		if obj.Date == nil {
+			continue
+		}
+		if r := obj.SplitRatio; r != nil && *r > 0 && *r != 1 {
+			acts = append(acts, Action{Symbol: symbol, Date: *obj.Date, Type: Split, Ratio: *r})
+		}
+		if d := obj.ExDividend; d != nil && *d != 0 {
+			acts = append(acts, Action{Symbol: symbol, Date: *obj.Date, Type: Dividend, Amount: *d})
+		}
+	}
+	sort.SliceStable(acts, func(i, j int) bool {
+		if acts[i].Date != acts[j].Date {
+			return acts[i].Date < acts[j].Date
+		}
+		return acts[i].Type == Split && acts[j].Type != Split
+	})
+	return acts
+}
+
+// Filter returns the actions of type typ
+func Filter(acts []Action, typ string) []Action {
+	out := []Action{}
+	for _, a := range acts {
+		if a.Type == typ {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Table returns the actions of symbol, ordered by date as Extract returns
+// them, as a table with one row per ex-date so that it can be written by the
+// table sinks keyed by symbol and date. A column is nil on a date without an
+// action of its type.
+func Table(symbol string, acts []Action) *api.Table {
+	t := &api.Table{Symbol: symbol, DBCode: "ACTIONS", Columns: Columns, Rows: [][]interface{}{}}
+	for _, a := range acts {
+		var row []interface{}
+		if n := len(t.Rows); n > 0 && t.Rows[n-1][0] == a.Date {
+			row = t.Rows[n-1]
+		} else {
+			row = []interface{}{a.Date, nil, nil}
+			t.Rows = append(t.Rows, row)
+		}
+		switch a.Type {
+		case Split:
+			row[1] = a.Ratio
+		case Dividend:
+			row[2] = a.Amount
+		}
+	}
+	return t
+}
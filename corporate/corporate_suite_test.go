@@ -0,0 +1,13 @@
+package corporate_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCorporate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Corporate Suite")
+}
@@ -0,0 +1,57 @@
+package corporate_test
+
+import (
+	"github.com/twold/go-quandl/api"
+	. "github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/internal/fixture"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// row is a WIKI row as Quandl returns it, with a split ratio of 1 and no
+// dividend on most days
+func row(date string, split, dividend float64) api.Wiki {
+	return api.Wiki{Date: &date, Close: fixture.Float(100), SplitRatio: fixture.Float(split), ExDividend: fixture.Float(dividend)}
+}
+
+var _ = Describe("Corporate actions", func() {
+	// newest first, like Quandl
+	rows := []api.Wiki{
+		row("2014-08-07", 1, 0.47),
+		row("2014-08-06", 1, 0),
+		row("2014-06-09", 7, 0),
+		row("2014-05-08", 1, 3.29),
+		row("2005-02-28", 2, 0.1),
+		{Date: nil, SplitRatio: fixture.Float(3)},
+	}
+
+	It("should extract splits and dividends ordered by date", func() {
+		acts := Extract("AAPL", rows)
+		Expect(acts).Should(Equal([]Action{
+			{Symbol: "AAPL", Date: "2005-02-28", Type: Split, Ratio: 2},
+			{Symbol: "AAPL", Date: "2005-02-28", Type: Dividend, Amount: 0.1},
+			{Symbol: "AAPL", Date: "2014-05-08", Type: Dividend, Amount: 3.29},
+			{Symbol: "AAPL", Date: "2014-06-09", Type: Split, Ratio: 7},
+			{Symbol: "AAPL", Date: "2014-08-07", Type: Dividend, Amount: 0.47},
+		}))
+		Expect(len(Filter(acts, Split))).Should(Equal(2))
+		Expect(len(Filter(acts, Dividend))).Should(Equal(3))
+	})
+
+	It("should ignore rows without the columns", func() {
+		date := "2018-01-02"
+		Expect(Extract("FB", []api.Wiki{{Date: &date, Close: fixture.Float(1)}})).Should(BeEmpty())
+	})
+
+	It("should return a table with a row per ex-date", func() {
+		t := Table("AAPL", Extract("AAPL", rows))
+		Expect(t.Columns).Should(Equal(Columns))
+		Expect(t.Rows).Should(Equal([][]interface{}{
+			{"2005-02-28", 2.0, 0.1},
+			{"2014-05-08", nil, 3.29},
+			{"2014-06-09", 7.0, nil},
+			{"2014-08-07", nil, 0.47},
+		}))
+	})
+})
@@ -29,6 +29,7 @@ const (
 // Database codes
 const (
 	CBOE = "CBOE"
+	EOD  = "EOD"
 	WIKI = "WIKI"
 )
 
@@ -49,6 +50,7 @@ const (
 var (
 	DatabaseCodes = []string{
 		CBOE,
+		EOD,
 		WIKI,
 	}
 )
@@ -138,3 +140,19 @@ func New(service, opt, param, dataType, format string) (Endpoint, error) {
 	}
 	return Endpoint{URL: URL}, nil
 }
+
+// Search returns the dataset search endpoint, e.g. /api/v3/datasets.json
+func Search(service, format string) (Endpoint, error) {
+	e := endpoint(service)
+	if e.opts == nil {
+		return e, errInvalidService
+	}
+
+	URL := fmt.Sprintf("%s://%s%s/%s/%s", e.DefaultProtocol, e.url, e.suffix, defaultVersion, service)
+	for _, f := range e.returnFormats {
+		if f == format {
+			URL = fmt.Sprintf("%s.%s", URL, format)
+		}
+	}
+	return Endpoint{URL: URL}, nil
+}
@@ -42,3 +42,20 @@ var _ = Describe("New", func() {
 		})
 	})
 })
+
+var _ = Describe("Search", func() {
+	Context("When I input the name of an invalid service", func() {
+		It("returns an error", func() {
+			_, err := Search("dataset", "json")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("When I input the name of a valid service", func() {
+		It("returns the search endpoint", func() {
+			actual, err := Search("datasets", "json")
+			Expect(err).Should(BeNil())
+			Expect(actual.URL).Should(Equal("https://www.quandl.com/api/v3/datasets.json"))
+		})
+	})
+})
@@ -0,0 +1,298 @@
+// Package enrich derives calendar fields from the date of each row: the ISO
+// week, the month, quarter and fiscal period, the index of the trading day
+// in its month, the days since the previous row and whether the row is the
+// last session of a month or a quarter. Trading days follow the exchange
+// calendar of the data set's database.
+package enrich
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/calendar"
+)
+
+// Fields
+const (
+	// ISOWeek is the ISO 8601 week, e.g. "2018-W01"
+	ISOWeek = "iso_week"
+	// Month is the month, 1 to 12
+	Month = "month"
+	// Quarter is the calendar quarter, 1 to 4
+	Quarter = "quarter"
+	// FiscalPeriod is the fiscal year and quarter, e.g. "FY2018Q3"
+	FiscalPeriod = "fiscal_period"
+	// TradingDay is the index of the session in its month, from 1
+	TradingDay = "trading_day"
+	// DaysSince is the number of days since the previous row, unset on the
+	// first one
+	DaysSince = "days_since"
+	// MonthEnd is set on the last session of a month
+	MonthEnd = "month_end"
+	// QuarterEnd is set on the last session of a quarter
+	QuarterEnd = "quarter_end"
+
+	// All selects every field
+	All = "all"
+)
+
+// Names lists the fields in column order
+var Names = []string{ISOWeek, Month, Quarter, FiscalPeriod, TradingDay, DaysSince, MonthEnd, QuarterEnd}
+
+// Columns names the table column and json key of each field
+var Columns = map[string]string{
+	ISOWeek:      "ISOWeek",
+	Month:        "Month",
+	Quarter:      "Quarter",
+	FiscalPeriod: "FiscalPeriod",
+	TradingDay:   "TradingDay",
+	DaysSince:    "DaysSince",
+	MonthEnd:     "MonthEnd",
+	QuarterEnd:   "QuarterEnd",
+}
+
+// Fields holds the derived fields of a row, unset fields were not asked for
+type Fields struct {
+	ISOWeek      *string `json:"ISOWeek,omitempty" type:"string"`
+	Month        *int    `json:"Month,omitempty" type:"int"`
+	Quarter      *int    `json:"Quarter,omitempty" type:"int"`
+	FiscalPeriod *string `json:"FiscalPeriod,omitempty" type:"string"`
+	TradingDay   *int    `json:"TradingDay,omitempty" type:"int"`
+	DaysSince    *int    `json:"DaysSince,omitempty" type:"int"`
+	MonthEnd     *bool   `json:"MonthEnd,omitempty" type:"bool"`
+	QuarterEnd   *bool   `json:"QuarterEnd,omitempty" type:"bool"`
+}
+
+// Value returns the field name as a table value: a string, a float64, 1 or 0
+// for booleans, or nil if it is unset
+func (f *Fields) Value(name string) interface{} {
+	if f == nil {
+		return nil
+	}
+	switch name {
+	case ISOWeek:
+		return text(f.ISOWeek)
+	case Month:
+		return number(f.Month)
+	case Quarter:
+		return number(f.Quarter)
+	case FiscalPeriod:
+		return text(f.FiscalPeriod)
+	case TradingDay:
+		return number(f.TradingDay)
+	case DaysSince:
+		return number(f.DaysSince)
+	case MonthEnd:
+		return flag(f.MonthEnd)
+	case QuarterEnd:
+		return flag(f.QuarterEnd)
+	}
+	return nil
+}
+
+// Present returns the names of the fields set on any of fs, in column order
+func Present(fs []*Fields) []string {
+	names := []string{}
+	for _, name := range Names {
+		for _, f := range fs {
+			if f.Value(name) != nil {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Options configures an Enricher
+type Options struct {
+	// Fields to add, all of them if empty
+	Fields []string
+	// FiscalYearStart is the first month of the fiscal year, January if
+	// zero. Fiscal years are named after the calendar year they end in.
+	FiscalYearStart time.Month
+	// Calendar gives the trading sessions, by default the calendar of the
+	// database, see calendar.ForDB
+	Calendar *calendar.Calendar
+}
+
+// Enricher derives the fields of rows. It is safe for concurrent use.
+type Enricher struct {
+	o  Options
+	on map[string]bool
+}
+
+// New returns an enricher of the fields of o
+func New(o Options) (*Enricher, error) {
+	if len(o.Fields) == 0 {
+		o.Fields = Names
+	}
+	if o.FiscalYearStart == 0 {
+		o.FiscalYearStart = time.January
+	}
+	if o.FiscalYearStart < time.January || o.FiscalYearStart > time.December {
+		return nil, fmt.Errorf("invalid fiscal year start %d, expected a month from 1 to 12", o.FiscalYearStart)
+	}
+
+	e := &Enricher{o: o, on: map[string]bool{}}
+	for _, name := range o.Fields {
+		if _, ok := Columns[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q, options are %s", name, strings.Join(Names, ", "))
+		}
+		e.on[name] = true
+	}
+	return e, nil
+}
+
+// Parse parses field names separated by commas, or "all"
+func Parse(s string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == All:
+			return Names, nil
+		case Columns[name] == "":
+			return nil, fmt.Errorf("unknown field %q, options are %s or %s", name, strings.Join(Names, ", "), All)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Fields returns the names of the fields added, in column order
+func (e *Enricher) Fields() []string {
+	names := []string{}
+	for _, name := range Names {
+		if e.on[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Dates returns the fields of rows dated dates, YYYY-MM-DD in any order, of
+// a data set of the database dbCode. Empty dates get nil fields.
+func (e *Enricher) Dates(dbCode string, dates []string) ([]*Fields, error) {
+	cal := e.o.Calendar
+	if cal == nil {
+		cal = calendar.ForDB(dbCode)
+	}
+
+	days := make([]time.Time, len(dates))
+	order := make([]int, 0, len(dates))
+	for i, s := range dates {
+		if s == "" {
+			continue
+		}
+		d, err := calendar.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		days[i] = d
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return days[order[a]].Before(days[order[b]]) })
+
+	// sessions of every month seen, by its first day
+	sessions := map[time.Time][]time.Time{}
+	month := func(d time.Time) []time.Time {
+		first := calendar.Date(d.Year(), d.Month(), 1)
+		s, ok := sessions[first]
+		if !ok {
+			s = cal.Sessions(first, first.AddDate(0, 1, -1))
+			sessions[first] = s
+		}
+		return s
+	}
+
+	fields := make([]*Fields, len(dates))
+	for n, i := range order {
+		d, f := days[i], &Fields{}
+		if e.on[ISOWeek] {
+			year, week := d.ISOWeek()
+			f.ISOWeek = str(fmt.Sprintf("%d-W%02d", year, week))
+		}
+		if e.on[Month] {
+			f.Month = integer(int(d.Month()))
+		}
+		if e.on[Quarter] {
+			f.Quarter = integer(quarter(d.Month()))
+		}
+		if e.on[FiscalPeriod] {
+			f.FiscalPeriod = str(e.fiscal(d))
+		}
+		if e.on[DaysSince] && n > 0 {
+			f.DaysSince = integer(int(d.Sub(days[order[n-1]]).Hours() / 24))
+		}
+
+		if e.on[TradingDay] || e.on[MonthEnd] || e.on[QuarterEnd] {
+			s := month(d)
+			index := sort.Search(len(s), func(j int) bool { return s[j].After(d) })
+			end := len(s) > 0 && s[len(s)-1].Equal(d)
+			if e.on[TradingDay] {
+				f.TradingDay = integer(index)
+			}
+			if e.on[MonthEnd] {
+				f.MonthEnd = &end
+			}
+			if e.on[QuarterEnd] {
+				qend := end && d.Month()%3 == 0
+				f.QuarterEnd = &qend
+			}
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// fiscal returns the fiscal period of d, e.g. "FY2018Q3"
+func (e *Enricher) fiscal(d time.Time) string {
+	start := e.o.FiscalYearStart
+	year := d.Year()
+	if start != time.January && d.Month() >= start {
+		year++
+	}
+	offset := (int(d.Month()) - int(start) + 12) % 12
+	return fmt.Sprintf("FY%dQ%d", year, offset/3+1)
+}
+
+func quarter(m time.Month) int {
+	return (int(m)-1)/3 + 1
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func integer(i int) *int {
+	return &i
+}
+
+func text(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func number(i *int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return float64(*i)
+}
+
+func flag(b *bool) interface{} {
+	if b == nil {
+		return nil
+	}
+	if *b {
+		return 1.0
+	}
+	return 0.0
+}
@@ -0,0 +1,98 @@
+package enrich_test
+
+import (
+	"time"
+
+	"github.com/twold/go-quandl/calendar"
+	. "github.com/twold/go-quandl/enrich"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Enrich", func() {
+	// unordered, like Quandl's newest first; March 30th 2018 is Good Friday
+	dates := []string{"2018-04-02", "2018-03-29", "", "2018-03-28", "2018-01-02"}
+
+	It("should derive every field by default", func() {
+		e, err := New(Options{})
+		Expect(err).Should(BeNil())
+		Expect(e.Fields()).Should(Equal(Names))
+
+		fs, err := e.Dates("WIKI", dates)
+		Expect(err).Should(BeNil())
+		Expect(fs[2]).Should(BeNil())
+
+		apr, mar29, mar28, jan := fs[0], fs[1], fs[3], fs[4]
+		Expect(*jan.ISOWeek).Should(Equal("2018-W01"))
+		Expect(*apr.ISOWeek).Should(Equal("2018-W14"))
+		Expect(*mar29.Month).Should(Equal(3))
+		Expect(*apr.Quarter).Should(Equal(2))
+		Expect(*jan.FiscalPeriod).Should(Equal("FY2018Q1"))
+
+		Expect(*jan.TradingDay).Should(Equal(1))
+		Expect(*mar28.TradingDay).Should(Equal(20))
+		Expect(*mar29.TradingDay).Should(Equal(21))
+		Expect(*apr.TradingDay).Should(Equal(1))
+
+		Expect(jan.DaysSince).Should(BeNil())
+		Expect(*mar28.DaysSince).Should(Equal(85))
+		Expect(*mar29.DaysSince).Should(Equal(1))
+		Expect(*apr.DaysSince).Should(Equal(4))
+
+		Expect(*mar29.MonthEnd).Should(BeTrue())
+		Expect(*mar29.QuarterEnd).Should(BeTrue())
+		Expect(*mar28.MonthEnd).Should(BeFalse())
+		Expect(*apr.QuarterEnd).Should(BeFalse())
+	})
+
+	It("should name fiscal years after the year they end in", func() {
+		e, err := New(Options{Fields: []string{FiscalPeriod}, FiscalYearStart: time.October})
+		Expect(err).Should(BeNil())
+		fs, err := e.Dates("WIKI", []string{"2017-10-02", "2018-01-02", "2018-09-28"})
+		Expect(err).Should(BeNil())
+		Expect(*fs[0].FiscalPeriod).Should(Equal("FY2018Q1"))
+		Expect(*fs[1].FiscalPeriod).Should(Equal("FY2018Q2"))
+		Expect(*fs[2].FiscalPeriod).Should(Equal("FY2018Q4"))
+		Expect(fs[0].Month).Should(BeNil())
+	})
+
+	It("should follow the calendar it is given", func() {
+		// NASDAQ opened on February 8th 1971
+		fs := func(o Options) []*Fields {
+			e, err := New(o)
+			Expect(err).Should(BeNil())
+			fs, err := e.Dates("WIKI", []string{"1971-02-05"})
+			Expect(err).Should(BeNil())
+			return fs
+		}
+		Expect(*fs(Options{Fields: []string{TradingDay}})[0].TradingDay).Should(Equal(5))
+		Expect(*fs(Options{Fields: []string{TradingDay}, Calendar: calendar.NASDAQ})[0].TradingDay).Should(Equal(0))
+	})
+
+	It("should return table values of the fields set", func() {
+		e, err := New(Options{Fields: []string{Month, MonthEnd}})
+		Expect(err).Should(BeNil())
+		fs, err := e.Dates("WIKI", dates)
+		Expect(err).Should(BeNil())
+
+		Expect(Present(fs)).Should(Equal([]string{Month, MonthEnd}))
+		Expect(fs[1].Value(Month)).Should(Equal(3.0))
+		Expect(fs[1].Value(MonthEnd)).Should(Equal(1.0))
+		Expect(fs[0].Value(MonthEnd)).Should(Equal(0.0))
+		Expect(fs[1].Value(ISOWeek)).Should(BeNil())
+		Expect(fs[2].Value(Month)).Should(BeNil())
+	})
+
+	It("should parse field lists", func() {
+		Expect(Parse("iso_week, month_end")).Should(Equal([]string{ISOWeek, MonthEnd}))
+		Expect(Parse("all")).Should(Equal(Names))
+		_, err := Parse("week")
+		Expect(err).ShouldNot(BeNil())
+
+		_, err = New(Options{Fields: []string{"week"}})
+		Expect(err).ShouldNot(BeNil())
+		_, err = New(Options{FiscalYearStart: 13})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
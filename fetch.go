@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/calendar"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/quality"
+	"github.com/twold/go-quandl/store"
+)
+
+func runFetch(args []string) error {
+	var o options
+	fs := newFlagSet("fetch", "[SYMBOL...]")
+	o.authFlags(fs)
+	o.pathFlag(fs)
+	o.symbolFlags(fs)
+	o.qualityFlags(fs)
+	o.enrichFlags(fs)
+	o.resampleFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := o.startQuality(); err != nil {
+		return err
+	}
+	if err := o.startEnrich(); err != nil {
+		return err
+	}
+	if err := o.checkResample(); err != nil {
+		return err
+	}
+	o.format = endpoints.JSON
+
+	symbols, err := o.symbols(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	svc := o.service(endpoints.DATA)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "	")
+	err = forEach(symbols, func(symbol string) error {
+		resp, err := svc.Fetch(symbol)
+		if err != nil {
+			return err
+		}
+		if o.freq == "" {
+			return enc.Encode(resp.Data)
+		}
+
+		objs, ok := resp.Data.([]api.Wiki)
+		if !ok {
+			return fmt.Errorf("-resample only supports WIKI data sets")
+		}
+		// fields of the daily rows are derived again for the bars
+		bars, err := o.resampled(resp.Source.DBCode, objs)
+		if err != nil {
+			return err
+		}
+		if o.enricher != nil {
+			if err := api.EnrichWiki(o.enricher, resp.Source.DBCode, bars); err != nil {
+				return err
+			}
+		}
+		return enc.Encode(bars)
+	})
+	if qerr := o.saveQuality(); err == nil {
+		err = qerr
+	}
+	return err
+}
+
+func runSync(args []string) error {
+	var (
+		o           options
+		revisions   bool
+		incremental bool
+	)
+	fs := newFlagSet("sync", "[SYMBOL...]")
+	o.authFlags(fs)
+	o.pathFlag(fs)
+	o.symbolFlags(fs)
+	o.layoutFlag(fs)
+	o.metricsFlag(fs)
+	o.qualityFlags(fs)
+	o.enrichFlags(fs)
+	fs.BoolVar(&revisions, "revisions", false, "record saved rows restated by Quandl in <path>/revisions and save the new values")
+	fs.BoolVar(&incremental, "incremental", false, "only fetch the trading days after the last saved one, up to the last closed session")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	t, err := o.parseLayout()
+	if err != nil {
+		return err
+	}
+	if err := o.startMetrics(); err != nil {
+		return err
+	}
+	if err := o.startQuality(); err != nil {
+		return err
+	}
+	if err := o.startEnrich(); err != nil {
+		return err
+	}
+	if o.enricher != nil && t != nil && t.Ext() == ".parquet" {
+		return usagef("sync: -enrich fields are not saved in parquet files, use a json layout")
+	}
+	o.format = endpoints.JSON
+
+	symbols, err := o.symbols(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	service := func(string) (api.Getter, error) {
+		return o.service(endpoints.DATA), nil
+	}
+	if incremental {
+		service, err = o.planner(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t == nil && !revisions {
+		err = forEach(symbols, func(symbol string) error {
+			svc, err := service(symbol)
+			if svc == nil || err != nil {
+				return err
+			}
+			_, err = svc.Get(o.path, symbol)
+			return err
+		})
+		if qerr := o.saveQuality(); err == nil {
+			err = qerr
+		}
+		return err
+	}
+
+	var sink api.Sink = &api.JSONFiles{Path: o.path, Overwrite: true, Log: logger, Metrics: o.stats}
+	if t != nil {
+		sink = &store.Partitioned{Path: o.path, Layout: t, Log: logger, Metrics: o.stats}
+	}
+	if revisions {
+		sink = &store.Tracker{Path: o.path, Layout: t, Sink: sink, Log: logger, Metrics: o.stats}
+	}
+	err = forEach(symbols, func(symbol string) error {
+		svc, err := service(symbol)
+		if svc == nil || err != nil {
+			return err
+		}
+		resp, err := svc.Fetch(symbol)
+		if err != nil {
+			return err
+		}
+		objs, ok := resp.Data.([]api.Wiki)
+		if !ok {
+			return fmt.Errorf("-layout and -revisions only support WIKI data sets")
+		}
+		return sink.Write(resp.Source, objs)
+	})
+	if qerr := o.saveQuality(); err == nil {
+		err = qerr
+	}
+	return err
+}
+
+// planner returns a func giving the service that fetches the sessions of a
+// symbol after its last saved date, by the calendar of the database, or nil
+// if the symbol is up to date
+func (o *options) planner(t *layout.Template) (func(symbol string) (api.Getter, error), error) {
+	var (
+		r   *store.Reader
+		err error
+	)
+	if t != nil {
+		r, err = store.OpenLayout(o.path, t)
+	} else {
+		r, err = store.Open(o.path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	cal := calendar.ForDB(o.dbCode)
+
+	return func(symbol string) (api.Getter, error) {
+		last := ""
+		if r != nil {
+			saved, err := r.Range(symbol)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			if saved != nil {
+				last = saved.End
+			}
+		}
+		start, end, ok := cal.Plan(last, time.Now())
+		if !ok {
+			logger.Info("Symbol is up to date.", logging.Symbol, symbol, "last_date", last)
+			return nil, nil
+		}
+		logger.Debug("Planned fetch.", logging.Symbol, symbol, "start_date", start, "end_date", end)
+		return o.service(endpoints.DATA, api.DateRange(start, end)), nil
+	}, nil
+}
+
+func runMeta(args []string) error {
+	var o options
+	fs := newFlagSet("meta", "SYMBOL...")
+	o.authFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("meta: at least one symbol is required")
+	}
+
+	svc := o.service(endpoints.METADATA)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "	")
+	return forEach(fs.Args(), func(symbol string) error {
+		meta, err := svc.Meta(symbol)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(meta)
+	})
+}
+
+func runSearch(args []string) error {
+	var (
+		o       options
+		page    int
+		perPage int
+	)
+	fs := newFlagSet("search", "QUERY...")
+	o.authFlags(fs)
+	fs.IntVar(&page, "page", 1, "result page")
+	fs.IntVar(&perPage, "per_page", 20, "results per page")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("search: a query is required")
+	}
+
+	svc := o.service(endpoints.METADATA)
+	res, err := svc.Search(strings.Join(fs.Args(), " "), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range res.Datasets {
+		fmt.Printf("%s/%s\t%s\t%s\n", str(d.DatabaseCode), str(d.DatasetCode), str(d.NewestAvailableDate), str(d.Name))
+	}
+	if res.Meta.TotalCount != nil && res.Meta.TotalPages != nil {
+		fmt.Fprintf(os.Stderr, "page %d of %d, %d data sets\n", page, *res.Meta.TotalPages, *res.Meta.TotalCount)
+	}
+	return nil
+}
+
+// forEach runs fn for each symbol, skipping invalid tickers
+func forEach(symbols []string, fn func(symbol string) error) error {
+	return forEachN(symbols, 1, fn)
+}
+
+// forEachN runs fn for each symbol on n goroutines and stops at the first error
+func forEachN(symbols []string, n int, fn func(symbol string) error) error {
+	if n < 1 {
+		n = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		first    error
+		rejected int
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return first != nil
+	}
+
+	ch := make(chan string)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range ch {
+				err := fn(symbol)
+				if err == nil {
+					continue
+				}
+				if ignorable(err) {
+					logger.Warn("Removed invalid ticker from list.", logging.Symbol, symbol, logging.Error, err)
+					continue
+				}
+				// symbols failing quality rules are skipped, the run fails
+				// once the others are done
+				if _, ok := err.(*quality.Error); ok {
+					logger.Error("Skipped symbol failing quality rules.", logging.Symbol, symbol, logging.Error, err)
+					mu.Lock()
+					rejected++
+					mu.Unlock()
+					continue
+				}
+				mu.Lock()
+				if first == nil {
+					first = fmt.Errorf("%s: %v", symbol, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, symbol := range symbols {
+		if failed() {
+			break
+		}
+		ch <- symbol
+	}
+	close(ch)
+	wg.Wait()
+	if first == nil && rejected > 0 {
+		return fmt.Errorf("%d symbols failed quality rules", rejected)
+	}
+	return first
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
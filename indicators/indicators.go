@@ -0,0 +1,318 @@
+// Package indicators computes technical indicators over price series one bar
+// at a time, oldest first, so that a series can be extended as new rows are
+// fetched without going over its history again. Each indicator has a typed
+// Add method; Spec and Table compute any of them by name and append their
+// outputs to tables as extra columns.
+//
+// Averages are seeded like TA-Lib: an EMA starts at the SMA of its first n
+// values, RSI and ATR use Wilder's smoothing after the mean of their first n
+// values, and Bollinger Bands use the population standard deviation.
+package indicators
+
+import "math"
+
+// window holds the last n values
+type window struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newWindow(n int) *window {
+	return &window{values: make([]float64, n)}
+}
+
+// push adds v, dropping the oldest value of a full window
+func (w *window) push(v float64) {
+	w.values[w.next] = v
+	w.next = (w.next + 1) % len(w.values)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// len returns the number of values held
+func (w *window) len() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+// at returns the ith value, 0 being the oldest
+func (w *window) at(i int) float64 {
+	if !w.full {
+		return w.values[i]
+	}
+	return w.values[(w.next+i)%len(w.values)]
+}
+
+// SMA is the simple moving average of the last N values
+type SMA struct {
+	w   *window
+	sum float64
+}
+
+// NewSMA returns the simple moving average of n values
+func NewSMA(n int) *SMA {
+	return &SMA{w: newWindow(n)}
+}
+
+// Add adds a value and returns the average, ok once there are n values
+func (s *SMA) Add(v float64) (float64, bool) {
+	if s.w.full {
+		s.sum -= s.w.at(0)
+	}
+	s.w.push(v)
+	s.sum += v
+	return s.sum / float64(s.w.len()), s.w.full
+}
+
+// EMA is the exponential moving average of weight 2/(n+1), seeded with the
+// SMA of the first n values
+type EMA struct {
+	n     int
+	alpha float64
+	seed  *SMA
+	value float64
+	ready bool
+}
+
+// NewEMA returns the exponential moving average of n values
+func NewEMA(n int) *EMA {
+	return &EMA{n: n, alpha: 2 / float64(n+1), seed: NewSMA(n)}
+}
+
+// Add adds a value and returns the average, ok once there are n values
+func (e *EMA) Add(v float64) (float64, bool) {
+	if !e.ready {
+		e.value, e.ready = e.seed.Add(v)
+		return e.value, e.ready
+	}
+	e.value += e.alpha * (v - e.value)
+	return e.value, true
+}
+
+// WMA is the linearly weighted moving average of the last N values, the
+// newest weighing n and the oldest 1
+type WMA struct {
+	w *window
+}
+
+// NewWMA returns the weighted moving average of n values
+func NewWMA(n int) *WMA {
+	return &WMA{w: newWindow(n)}
+}
+
+// Add adds a value and returns the average, ok once there are n values
+func (m *WMA) Add(v float64) (float64, bool) {
+	m.w.push(v)
+	sum, weights := 0.0, 0.0
+	for i := 0; i < m.w.len(); i++ {
+		sum += float64(i+1) * m.w.at(i)
+		weights += float64(i + 1)
+	}
+	return sum / weights, m.w.full
+}
+
+// wilder is Wilder's smoothing of n values, seeded with their mean
+type wilder struct {
+	n     int
+	count int
+	value float64
+}
+
+func (s *wilder) add(v float64) (float64, bool) {
+	s.count++
+	if s.count <= s.n {
+		s.value += (v - s.value) / float64(s.count)
+	} else {
+		s.value = (s.value*float64(s.n-1) + v) / float64(s.n)
+	}
+	return s.value, s.count >= s.n
+}
+
+// RSI is Wilder's relative strength index over n changes
+type RSI struct {
+	gain, loss wilder
+	prev       float64
+	started    bool
+}
+
+// NewRSI returns the relative strength index of n changes
+func NewRSI(n int) *RSI {
+	return &RSI{gain: wilder{n: n}, loss: wilder{n: n}}
+}
+
+// Add adds a close and returns the index from 0 to 100, ok once there are n
+// changes, i.e. n+1 closes
+func (r *RSI) Add(v float64) (float64, bool) {
+	if !r.started {
+		r.prev, r.started = v, true
+		return 0, false
+	}
+	change := v - r.prev
+	r.prev = v
+	gain, ok := r.gain.add(math.Max(change, 0))
+	loss, _ := r.loss.add(math.Max(-change, 0))
+	if loss == 0 {
+		return 100, ok
+	}
+	return 100 - 100/(1+gain/loss), ok
+}
+
+// MACD is the difference of a fast and a slow EMA, with an EMA of that
+// difference as signal line
+type MACD struct {
+	fast, slow, signal *EMA
+}
+
+// NewMACD returns the MACD of EMAs of fast and slow values with a signal line
+// of signal values, usually 12, 26 and 9
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+// Add adds a close and returns the MACD line, ok once the slow EMA is ready,
+// and the signal line and histogram, ready once the signal EMA is
+func (m *MACD) Add(v float64) (line float64, ok bool, signal, hist float64, signalOK bool) {
+	fast, _ := m.fast.Add(v)
+	slow, ok := m.slow.Add(v)
+	if !ok {
+		return 0, false, 0, 0, false
+	}
+	line = fast - slow
+	signal, signalOK = m.signal.Add(line)
+	return line, true, signal, line - signal, signalOK
+}
+
+// Bollinger are bands k standard deviations around the SMA of n values
+type Bollinger struct {
+	k float64
+	w *window
+}
+
+// NewBollinger returns Bollinger Bands of n values and k deviations, usually
+// 20 and 2
+func NewBollinger(n int, k float64) *Bollinger {
+	return &Bollinger{k: k, w: newWindow(n)}
+}
+
+// Add adds a close and returns the middle, upper and lower bands, ok once
+// there are n values
+func (b *Bollinger) Add(v float64) (middle, upper, lower float64, ok bool) {
+	b.w.push(v)
+	n := float64(b.w.len())
+	for i := 0; i < b.w.len(); i++ {
+		middle += b.w.at(i)
+	}
+	middle /= n
+	variance := 0.0
+	for i := 0; i < b.w.len(); i++ {
+		d := b.w.at(i) - middle
+		variance += d * d
+	}
+	sd := math.Sqrt(variance / n)
+	return middle, middle + b.k*sd, middle - b.k*sd, b.w.full
+}
+
+// ATR is Wilder's average true range of n bars
+type ATR struct {
+	avg     wilder
+	prev    float64
+	started bool
+}
+
+// NewATR returns the average true range of n bars
+func NewATR(n int) *ATR {
+	return &ATR{avg: wilder{n: n}}
+}
+
+// Add adds a bar and returns the average, ok once there are n bars. The true
+// range of the first bar is its high minus its low.
+func (a *ATR) Add(high, low, close float64) (float64, bool) {
+	tr := high - low
+	if a.started {
+		tr = math.Max(tr, math.Max(math.Abs(high-a.prev), math.Abs(low-a.prev)))
+	}
+	a.prev, a.started = close, true
+	return a.avg.add(tr)
+}
+
+// OBV is the on-balance volume, the running sum of the volumes of up closes
+// minus those of down closes
+type OBV struct {
+	value   float64
+	prev    float64
+	started bool
+}
+
+// NewOBV returns an on-balance volume starting at 0
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Add adds a bar and returns the volume balance
+func (o *OBV) Add(close, volume float64) float64 {
+	if o.started {
+		switch {
+		case close > o.prev:
+			o.value += volume
+		case close < o.prev:
+			o.value -= volume
+		}
+	}
+	o.prev, o.started = close, true
+	return o.value
+}
+
+// Rolling is the highest high and the lowest low of the last n bars
+type Rolling struct {
+	highs, lows *window
+}
+
+// NewRolling returns the rolling high and low of n bars
+func NewRolling(n int) *Rolling {
+	return &Rolling{highs: newWindow(n), lows: newWindow(n)}
+}
+
+// Add adds a bar and returns the highest high and the lowest low, ok once
+// there are n bars
+func (r *Rolling) Add(high, low float64) (hi, lo float64, ok bool) {
+	r.highs.push(high)
+	r.lows.push(low)
+	hi, lo = math.Inf(-1), math.Inf(1)
+	for i := 0; i < r.highs.len(); i++ {
+		hi = math.Max(hi, r.highs.at(i))
+		lo = math.Min(lo, r.lows.at(i))
+	}
+	return hi, lo, r.highs.full
+}
+
+// Stochastic is the stochastic oscillator: %K places the close within the
+// range of the last k bars, %D is the SMA of d values of %K
+type Stochastic struct {
+	rolling *Rolling
+	d       *SMA
+}
+
+// NewStochastic returns the stochastic oscillator of k bars smoothed over d,
+// usually 14 and 3
+func NewStochastic(k, d int) *Stochastic {
+	return &Stochastic{rolling: NewRolling(k), d: NewSMA(d)}
+}
+
+// Add adds a bar and returns %K, ok once there are k bars, and %D, ok once
+// there are d values of %K. %K is 50 when the range is empty.
+func (s *Stochastic) Add(high, low, close float64) (k float64, ok bool, d float64, dOK bool) {
+	hi, lo, ok := s.rolling.Add(high, low)
+	if !ok {
+		return 0, false, 0, false
+	}
+	k = 50
+	if hi > lo {
+		k = 100 * (close - lo) / (hi - lo)
+	}
+	d, dOK = s.d.Add(k)
+	return k, true, d, dOK
+}
@@ -0,0 +1,13 @@
+package indicators_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestIndicators(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Indicators Suite")
+}
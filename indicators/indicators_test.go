@@ -0,0 +1,272 @@
+package indicators_test
+
+import (
+	"fmt"
+
+	"github.com/twold/go-quandl/adjust"
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	. "github.com/twold/go-quandl/indicators"
+	"github.com/twold/go-quandl/internal/fixture"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// the closes of Wilder's RSI example as published by StockCharts; highs and
+// lows are half a point around them except on two bars, volumes grow by 100
+var closes = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+}
+
+func bar(i int) Bar {
+	b := Bar{High: closes[i] + 0.5, Low: closes[i] - 0.5, Close: closes[i], Volume: float64(1000 + 100*i)}
+	switch i {
+	case 3:
+		b.High = 44.5
+	case 7:
+		b.Low = 44.0
+	}
+	return b
+}
+
+// feed returns the value of add at each close and whether it was ready
+func feed(add func(float64) (float64, bool)) ([]float64, []bool) {
+	values, oks := make([]float64, len(closes)), make([]bool, len(closes))
+	for i, c := range closes {
+		values[i], oks[i] = add(c)
+	}
+	return values, oks
+}
+
+type tables struct {
+	written []*api.Table
+}
+
+func (t *tables) WriteTable(table *api.Table) error {
+	t.written = append(t.written, table)
+	return nil
+}
+
+var _ = Describe("Indicators", func() {
+	// golden values computed independently, to 6 decimals
+	const tolerance = 1e-6
+
+	It("should compute moving averages", func() {
+		sma, oks := feed(NewSMA(5).Add)
+		Expect(oks[3]).Should(BeFalse())
+		Expect(oks[4]).Should(BeTrue())
+		Expect(sma[17]).Should(BeNumerically("~", 46.2, tolerance))
+		Expect(sma[19]).Should(BeNumerically("~", 46.06, tolerance))
+
+		ema, oks := feed(NewEMA(5).Add)
+		Expect(oks[3]).Should(BeFalse())
+		Expect(ema[4]).Should(BeNumerically("~", 44.104, tolerance))
+		Expect(ema[5]).Should(BeNumerically("~", 44.346, tolerance))
+		Expect(ema[6]).Should(BeNumerically("~", 44.597333, tolerance))
+		Expect(ema[19]).Should(BeNumerically("~", 45.996054, tolerance))
+
+		wma, oks := feed(NewWMA(5).Add)
+		Expect(oks[4]).Should(BeTrue())
+		Expect(wma[18]).Should(BeNumerically("~", 46.207333, tolerance))
+		Expect(wma[19]).Should(BeNumerically("~", 46.024667, tolerance))
+	})
+
+	It("should compute Wilder's RSI", func() {
+		rsi, oks := feed(NewRSI(14).Add)
+		Expect(oks[13]).Should(BeFalse())
+		Expect(oks[14]).Should(BeTrue())
+		for i, want := range []float64{70.464135, 66.249619, 66.480942, 69.346853, 66.294713, 57.915021} {
+			Expect(rsi[14+i]).Should(BeNumerically("~", want, tolerance))
+		}
+
+		all, _ := NewRSI(2).Add(1)
+		Expect(all).Should(Equal(0.0))
+		r := NewRSI(2)
+		for _, c := range []float64{1, 2, 3} {
+			all, _ = r.Add(c)
+		}
+		Expect(all).Should(Equal(100.0))
+	})
+
+	It("should compute MACD with its signal line", func() {
+		m := NewMACD(3, 6, 4)
+		type out struct {
+			line, signal, hist float64
+			ok, signalOK       bool
+		}
+		outs := make([]out, len(closes))
+		for i, c := range closes {
+			o := &outs[i]
+			o.line, o.ok, o.signal, o.hist, o.signalOK = m.Add(c)
+		}
+		Expect(outs[4].ok).Should(BeFalse())
+		Expect(outs[5].ok).Should(BeTrue())
+		Expect(outs[5].line).Should(BeNumerically("~", 0.247917, tolerance))
+		Expect(outs[7].signalOK).Should(BeFalse())
+		Expect(outs[8].signalOK).Should(BeTrue())
+		Expect(outs[8].signal).Should(BeNumerically("~", 0.33284, tolerance))
+		Expect(outs[19].line).Should(BeNumerically("~", -0.063549, tolerance))
+		Expect(outs[19].signal).Should(BeNumerically("~", 0.041704, tolerance))
+		Expect(outs[19].hist).Should(BeNumerically("~", -0.105253, tolerance))
+	})
+
+	It("should compute Bollinger Bands", func() {
+		bb := NewBollinger(5, 2)
+		var middle, upper, lower float64
+		var ok bool
+		for _, c := range closes {
+			middle, upper, lower, ok = bb.Add(c)
+		}
+		Expect(ok).Should(BeTrue())
+		Expect(middle).Should(BeNumerically("~", 46.06, tolerance))
+		Expect(upper).Should(BeNumerically("~", 46.573030, tolerance))
+		Expect(lower).Should(BeNumerically("~", 45.546970, tolerance))
+	})
+
+	It("should compute indicators of bars", func() {
+		atr, obv, st, r := NewATR(5), NewOBV(), NewStochastic(5, 3), NewRolling(5)
+		for i := range closes {
+			b := bar(i)
+			a, aOK := atr.Add(b.High, b.Low, b.Close)
+			o := obv.Add(b.Close, b.Volume)
+			k, kOK, d, dOK := st.Add(b.High, b.Low, b.Close)
+			hi, lo, rOK := r.Add(b.High, b.Low)
+
+			Expect(aOK).Should(Equal(i >= 4))
+			Expect(kOK).Should(Equal(i >= 4))
+			Expect(dOK).Should(Equal(i >= 6))
+			Expect(rOK).Should(Equal(i >= 4))
+			switch i {
+			case 1:
+				Expect(o).Should(Equal(-1100.0))
+			case 4:
+				Expect(a).Should(BeNumerically("~", 1.122, tolerance))
+				Expect(o).Should(Equal(200.0))
+				Expect(k).Should(BeNumerically("~", 70.520231, tolerance))
+				Expect([]float64{hi, lo}).Should(Equal([]float64{44.84, 43.11}))
+			case 5:
+				Expect(a).Should(BeNumerically("~", 1.0976, tolerance))
+			case 6:
+				Expect(d).Should(BeNumerically("~", 75.972462, tolerance))
+			case 7:
+				Expect([]float64{hi, lo}).Should(Equal([]float64{45.92, 43.11}))
+			case 19:
+				Expect(a).Should(BeNumerically("~", 1.04185, tolerance))
+				Expect(o).Should(Equal(6000.0))
+				Expect(k).Should(BeNumerically("~", 28.248588, tolerance))
+				Expect(d).Should(BeNumerically("~", 47.950475, tolerance))
+				Expect([]float64{hi, lo}).Should(Equal([]float64{46.91, 45.14}))
+			}
+		}
+	})
+
+	It("should resume where it stopped", func() {
+		whole, _ := feed(NewEMA(5).Add)
+		e := NewEMA(5)
+		for _, c := range closes[:10] {
+			e.Add(c)
+		}
+		var last float64
+		for _, c := range closes[10:] {
+			last, _ = e.Add(c)
+		}
+		Expect(last).Should(Equal(whole[19]))
+	})
+
+	It("should parse specs", func() {
+		s, err := Parse("MACD")
+		Expect(err).Should(BeNil())
+		Expect(s.Params).Should(Equal([]float64{12, 26, 9}))
+		Expect(s.Columns()).Should(Equal([]string{"MACD_12_26_9", "MACDSignal_12_26_9", "MACDHist_12_26_9"}))
+
+		s, err = Parse("bb:10")
+		Expect(err).Should(BeNil())
+		Expect(s.String()).Should(Equal("bb:10:2"))
+		s, err = Parse("bb:10:2.5")
+		Expect(err).Should(BeNil())
+		Expect(s.Columns()[1]).Should(Equal("BBUpper_10_2.5"))
+
+		specs, err := ParseList("sma:50, obv,high")
+		Expect(err).Should(BeNil())
+		Expect(len(specs)).Should(Equal(3))
+		Expect(specs[1].Columns()).Should(Equal([]string{"OBV"}))
+		Expect(specs[2].Columns()).Should(Equal([]string{"RollingHigh_20"}))
+
+		for _, bad := range []string{"vwap", "sma:0", "sma:2.5", "sma:x", "rsi:14:3", "macd:26:12", "bb:20:0"} {
+			_, err := Parse(bad)
+			Expect(err).ShouldNot(BeNil(), bad)
+		}
+	})
+
+	It("should append the outputs to tables", func() {
+		// newest first like Quandl, with a row without a close; the raw
+		// prices are twice the adjusted ones
+		objs := []api.Wiki{}
+		for i := len(closes) - 1; i >= 0; i-- {
+			b, date := bar(i), fmt.Sprintf("2018-01-%02d", i+1)
+			objs = append(objs, api.Wiki{
+				Date: &date, Close: fixture.Float(2 * b.Close), High: fixture.Float(2 * b.High), Low: fixture.Float(2 * b.Low), Volume: fixture.Float(b.Volume),
+				AdjClose: fixture.Float(b.Close), AdjHigh: fixture.Float(b.High), AdjLow: fixture.Float(b.Low), AdjVolume: fixture.Float(b.Volume),
+			})
+		}
+		gap := "2018-01-31"
+		objs = append([]api.Wiki{{Date: &gap}}, objs...)
+
+		specs, err := ParseList("sma:5,obv,macd:3:6:4")
+		Expect(err).Should(BeNil())
+		t, err := Wiki("AAPL", objs, specs, Options{})
+		Expect(err).Should(BeNil())
+		n := len(api.WikiColumns)
+		Expect(t.Columns[n:]).Should(Equal([]string{"SMA_5", "OBV", "MACD_3_6_4", "MACDSignal_3_6_4", "MACDHist_3_6_4"}))
+		Expect(api.WikiColumns).Should(HaveLen(n))
+
+		Expect(t.Rows[0][n:]).Should(Equal([]interface{}{nil, nil, nil, nil, nil}))
+		newest := t.Rows[1][n:]
+		Expect(newest[0]).Should(BeNumerically("~", 46.06, tolerance))
+		Expect(newest[1]).Should(Equal(6000.0))
+		Expect(newest[4]).Should(BeNumerically("~", -0.105253, tolerance))
+		oldest := t.Rows[len(t.Rows)-1][n:]
+		Expect(oldest).Should(Equal([]interface{}{nil, 0.0, nil, nil, nil}))
+
+		raw := api.WikiTable("AAPL", objs)
+		Expect(Table(raw, specs[:1], Options{Prices: Raw})).Should(Succeed())
+		Expect(raw.Rows[1][n]).Should(BeNumerically("~", 92.12, tolerance))
+
+		Expect(Table(api.CBOETable("VX", nil), specs, Options{Prices: Adjusted})).ShouldNot(Succeed())
+		Expect(Table(api.WikiTable("AAPL", objs), specs, Options{Prices: "log"})).ShouldNot(Succeed())
+	})
+
+	It("should use the adjusted settles of CBOE tables", func() {
+		dates := []string{"2018-01-02", "2018-01-03", "2018-01-04"}
+		rows := []api.CBOE{}
+		for i, settle := range []float64{20, 22, 12} {
+			rows = append(rows, api.CBOE{TradeDate: &dates[i], Settle: fixture.Float(settle), TotalVolume: fixture.Float(100)})
+		}
+		t := api.CBOETable("VX1", rows)
+		Expect(adjust.Table(t, []corporate.Action{{Date: dates[2], Type: corporate.Split, Ratio: 2}}, adjust.Options{})).Should(Succeed())
+
+		specs, err := ParseList("sma:2,obv")
+		Expect(err).Should(BeNil())
+		Expect(Table(t, specs, Options{})).Should(Succeed())
+		sma, obv := t.Column("SMA_2"), t.Column("OBV")
+		Expect(t.Rows[1][sma]).Should(BeNumerically("~", 10.5, tolerance))
+		Expect(t.Rows[2][sma]).Should(BeNumerically("~", 11.5, tolerance))
+		// 200 adjusted contracts up, 100 up
+		Expect(t.Rows[2][obv]).Should(Equal(300.0))
+
+		Expect(Table(t, specs[:1], Options{Prices: Raw})).Should(Succeed())
+		Expect(t.Rows[2][len(t.Columns)-1]).Should(BeNumerically("~", 17, tolerance))
+	})
+
+	It("should write the outputs through a sink", func() {
+		date := "2018-01-02"
+		w := &tables{}
+		s := &Sink{Writer: w, Specs: []Spec{{Name: OBVName}}}
+		Expect(s.Write(api.Source{Symbol: "AAPL", DBCode: "WIKI"}, []api.Wiki{{Date: &date, Close: fixture.Float(1)}})).Should(Succeed())
+		Expect(w.written).Should(HaveLen(1))
+		Expect(w.written[0].Columns).Should(ContainElement("OBV"))
+		Expect(w.written[0].Rows[0]).Should(HaveLen(len(api.WikiColumns) + 1))
+	})
+})
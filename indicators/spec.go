@@ -0,0 +1,411 @@
+package indicators
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twold/go-quandl/api"
+)
+
+// Names of the indicators a Spec can hold
+const (
+	SMAName        = "sma"
+	EMAName        = "ema"
+	WMAName        = "wma"
+	RSIName        = "rsi"
+	MACDName       = "macd"
+	BollingerName  = "bb"
+	ATRName        = "atr"
+	OBVName        = "obv"
+	StochasticName = "stoch"
+	HighName       = "high"
+	LowName        = "low"
+)
+
+// Defaults are the parameters of the indicators when a spec has none
+var Defaults = map[string][]float64{
+	SMAName:        {20},
+	EMAName:        {20},
+	WMAName:        {20},
+	RSIName:        {14},
+	MACDName:       {12, 26, 9},
+	BollingerName:  {20, 2},
+	ATRName:        {14},
+	OBVName:        {},
+	StochasticName: {14, 3},
+	HighName:       {20},
+	LowName:        {20},
+}
+
+// Names lists the indicators in the order they are documented
+var Names = []string{
+	SMAName, EMAName, WMAName, RSIName, MACDName, BollingerName, ATRName, OBVName, StochasticName, HighName, LowName,
+}
+
+// Bar is the input of an indicator. Indicators of closes only read Close.
+type Bar struct {
+	High, Low, Close, Volume float64
+}
+
+// Indicator computes the outputs of an indicator bar by bar, oldest first
+type Indicator interface {
+	// Columns names the outputs
+	Columns() []string
+	// Update adds a bar and returns an output per column, nil until there
+	// are enough bars for it
+	Update(b Bar) []*float64
+}
+
+// Spec names an indicator and its parameters, e.g. macd:12:26:9
+type Spec struct {
+	Name   string
+	Params []float64
+}
+
+// Parse parses a spec, the name and its parameters separated by colons.
+// Missing parameters take their Defaults, e.g. rsi is rsi:14 and bb:10 is
+// bb:10:2.
+func Parse(s string) (Spec, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), ":")
+	spec := Spec{Name: parts[0]}
+	defaults, ok := Defaults[spec.Name]
+	if !ok {
+		return spec, fmt.Errorf("unknown indicator %q, options are %s", spec.Name, strings.Join(Names, ", "))
+	}
+	if len(parts)-1 > len(defaults) {
+		return spec, fmt.Errorf("%s takes %d parameters, got %d", spec.Name, len(defaults), len(parts)-1)
+	}
+	spec.Params = append([]float64{}, defaults...)
+	for i, p := range parts[1:] {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return spec, fmt.Errorf("%s: bad parameter %q", spec.Name, p)
+		}
+		spec.Params[i] = v
+	}
+	return spec, spec.validate()
+}
+
+// ParseList parses comma separated specs, e.g. "sma:50,sma:200,rsi"
+func ParseList(s string) ([]Spec, error) {
+	specs := []Spec{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		spec, err := Parse(part)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (s Spec) validate() error {
+	for i, p := range s.Params {
+		// the deviations of Bollinger Bands are the only fractional parameter
+		if s.Name == BollingerName && i == 1 {
+			if p <= 0 {
+				return fmt.Errorf("%s: deviations must be positive, got %v", s.Name, p)
+			}
+			continue
+		}
+		if p < 1 || p != float64(int(p)) {
+			return fmt.Errorf("%s: periods must be positive integers, got %v", s.Name, p)
+		}
+	}
+	if s.Name == MACDName && s.Params[0] >= s.Params[1] {
+		return fmt.Errorf("%s: fast period %v must be shorter than slow period %v", s.Name, s.Params[0], s.Params[1])
+	}
+	return nil
+}
+
+// String returns the spec as Parse reads it
+func (s Spec) String() string {
+	parts := []string{s.Name}
+	for _, p := range s.Params {
+		parts = append(parts, strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	return strings.Join(parts, ":")
+}
+
+// Columns names the outputs of the spec, e.g. SMA_20 or MACDSignal_12_26_9
+func (s Spec) Columns() []string {
+	var names []string
+	switch s.Name {
+	case SMAName, EMAName, WMAName, RSIName, ATRName, OBVName:
+		names = []string{strings.ToUpper(s.Name)}
+	case MACDName:
+		names = []string{"MACD", "MACDSignal", "MACDHist"}
+	case BollingerName:
+		names = []string{"BBMiddle", "BBUpper", "BBLower"}
+	case StochasticName:
+		names = []string{"StochK", "StochD"}
+	case HighName:
+		names = []string{"RollingHigh"}
+	case LowName:
+		names = []string{"RollingLow"}
+	}
+	suffix := strings.Replace(strings.TrimPrefix(s.String(), s.Name), ":", "_", -1)
+	for i := range names {
+		names[i] += suffix
+	}
+	return names
+}
+
+// New returns a fresh indicator of the spec
+func (s Spec) New() Indicator {
+	p := make([]int, len(s.Params))
+	for i, v := range s.Params {
+		p[i] = int(v)
+	}
+	columns := s.Columns()
+	switch s.Name {
+	case SMAName:
+		return single(columns, NewSMA(p[0]).Add)
+	case EMAName:
+		return single(columns, NewEMA(p[0]).Add)
+	case WMAName:
+		return single(columns, NewWMA(p[0]).Add)
+	case RSIName:
+		return single(columns, NewRSI(p[0]).Add)
+	case MACDName:
+		m := NewMACD(p[0], p[1], p[2])
+		return &indicator{columns, func(b Bar) []*float64 {
+			line, ok, signal, hist, signalOK := m.Add(b.Close)
+			return []*float64{ready(line, ok), ready(signal, signalOK), ready(hist, signalOK)}
+		}}
+	case BollingerName:
+		bb := NewBollinger(p[0], s.Params[1])
+		return &indicator{columns, func(b Bar) []*float64 {
+			middle, upper, lower, ok := bb.Add(b.Close)
+			return []*float64{ready(middle, ok), ready(upper, ok), ready(lower, ok)}
+		}}
+	case ATRName:
+		a := NewATR(p[0])
+		return &indicator{columns, func(b Bar) []*float64 {
+			return []*float64{ready(a.Add(b.High, b.Low, b.Close))}
+		}}
+	case OBVName:
+		o := NewOBV()
+		return &indicator{columns, func(b Bar) []*float64 {
+			return []*float64{ready(o.Add(b.Close, b.Volume), true)}
+		}}
+	case StochasticName:
+		st := NewStochastic(p[0], p[1])
+		return &indicator{columns, func(b Bar) []*float64 {
+			k, ok, d, dOK := st.Add(b.High, b.Low, b.Close)
+			return []*float64{ready(k, ok), ready(d, dOK)}
+		}}
+	case HighName, LowName:
+		r := NewRolling(p[0])
+		high := s.Name == HighName
+		return &indicator{columns, func(b Bar) []*float64 {
+			hi, lo, ok := r.Add(b.High, b.Low)
+			if high {
+				return []*float64{ready(hi, ok)}
+			}
+			return []*float64{ready(lo, ok)}
+		}}
+	}
+	return nil
+}
+
+// indicator adapts the typed indicators to Indicator
+type indicator struct {
+	columns []string
+	update  func(b Bar) []*float64
+}
+
+func (i *indicator) Columns() []string       { return i.columns }
+func (i *indicator) Update(b Bar) []*float64 { return i.update(b) }
+
+// single adapts an indicator of closes with one output
+func single(columns []string, add func(float64) (float64, bool)) Indicator {
+	return &indicator{columns, func(b Bar) []*float64 {
+		return []*float64{ready(add(b.Close))}
+	}}
+}
+
+func ready(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// Prices
+const (
+	Raw      = "raw"
+	Adjusted = "adjusted"
+)
+
+// Options configures Table
+type Options struct {
+	// Prices is Raw or Adjusted. If empty, the adjusted columns are used
+	// when the table has them and the raw columns otherwise.
+	Prices string
+}
+
+// columns of a table that an indicator can read, by name; the first one the
+// table has is used
+var (
+	rawColumns = map[string][]string{
+		"High":   {"High"},
+		"Low":    {"Low"},
+		"Close":  {"Close", "Settle"},
+		"Volume": {"Volume", "Total Volume", "TotalVolume"},
+	}
+	adjustedColumns = map[string][]string{
+		"High":   {"AdjHigh", "Adj. High"},
+		"Low":    {"AdjLow", "Adj. Low"},
+		"Close":  {"AdjClose", "Adj. Close", "AdjSettle"},
+		"Volume": {"AdjVolume", "Adj. Volume", "AdjTotalVolume"},
+	}
+)
+
+// Table appends the outputs of specs as columns of t, computed over its rows
+// in date order, whatever order they are in. It works on tables of any
+// database, e.g. from WikiTable, CBOETable or DataSetTable, with a Close or
+// Settle column. Rows without a close get nil outputs and are skipped; a
+// missing high or low is taken to be the close and a missing volume to be 0.
+func Table(t *api.Table, specs []Spec, o Options) error {
+	prices := o.Prices
+	if prices == "" {
+		prices = Raw
+		if find(t, adjustedColumns["Close"]) >= 0 {
+			prices = Adjusted
+		}
+	}
+	var from map[string][]string
+	switch prices {
+	case Raw:
+		from = rawColumns
+	case Adjusted:
+		from = adjustedColumns
+	default:
+		return fmt.Errorf("unknown prices %q, options are %s, %s", o.Prices, Raw, Adjusted)
+	}
+	high, low, closeCol, volume := find(t, from["High"]), find(t, from["Low"]), find(t, from["Close"]), find(t, from["Volume"])
+	if closeCol < 0 {
+		return fmt.Errorf("%s: no %s close column", t.Symbol, prices)
+	}
+
+	order := make([]int, 0, len(t.Rows))
+	for i, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return fmt.Errorf("%s: row has %d values, want %d", t.Symbol, len(row), len(t.Columns))
+		}
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		da, _ := t.Rows[order[a]][0].(string)
+		db, _ := t.Rows[order[b]][0].(string)
+		return da < db
+	})
+
+	inds := make([]Indicator, len(specs))
+	cols := make([]string, len(t.Columns))
+	copy(cols, t.Columns)
+	for i, s := range specs {
+		inds[i] = s.New()
+		cols = append(cols, inds[i].Columns()...)
+	}
+	added := len(cols) - len(t.Columns)
+	t.Columns = cols
+
+	for _, i := range order {
+		row := t.Rows[i]
+		out := make([]interface{}, 0, added)
+		c, ok := cell(row, closeCol)
+		if !ok {
+			t.Rows[i] = append(row, make([]interface{}, added)...)
+			continue
+		}
+		b := Bar{High: c, Low: c, Close: c}
+		if v, ok := cell(row, high); ok {
+			b.High = v
+		}
+		if v, ok := cell(row, low); ok {
+			b.Low = v
+		}
+		b.Volume, _ = cell(row, volume)
+		for _, ind := range inds {
+			for _, v := range ind.Update(b) {
+				if v == nil {
+					out = append(out, nil)
+				} else {
+					out = append(out, *v)
+				}
+			}
+		}
+		t.Rows[i] = append(row, out...)
+	}
+	return nil
+}
+
+// Wiki returns objs as a table with the outputs of specs appended
+func Wiki(symbol string, objs []api.Wiki, specs []Spec, o Options) (*api.Table, error) {
+	t := api.WikiTable(symbol, objs)
+	if err := Table(t, specs, o); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TableWriter writes tables, e.g. arrowsink.Files or sqlsink.Sink
+type TableWriter interface {
+	WriteTable(t *api.Table) error
+}
+
+// Sink is an api.Sink that appends the outputs of Specs to the rows it is
+// given before writing them to Writer. Each write starts the indicators
+// afresh, so it should hold the whole history of its symbol.
+type Sink struct {
+	Writer  TableWriter
+	Specs   []Spec
+	Options Options
+}
+
+func (s *Sink) Write(src api.Source, objs []api.Wiki) error {
+	t, err := Wiki(src.Symbol, objs, s.Specs, s.Options)
+	if err != nil {
+		return err
+	}
+	if src.DBCode != "" {
+		t.DBCode = src.DBCode
+	}
+	return s.Writer.WriteTable(t)
+}
+
+// find returns the first of the columns names that holds a value, e.g. Settle
+// of CBOE rows without a close, or else the first that exists
+func find(t *api.Table, names []string) int {
+	first := -1
+	for _, name := range names {
+		i := t.Column(name)
+		if i < 0 {
+			continue
+		}
+		for _, row := range t.Rows {
+			if _, ok := cell(row, i); ok {
+				return i
+			}
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+func cell(row []interface{}, i int) (float64, bool) {
+	if i < 0 || i >= len(row) {
+		return 0, false
+	}
+	v, ok := row[i].(float64)
+	return v, ok
+}
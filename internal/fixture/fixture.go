@@ -0,0 +1,28 @@
+// Package fixture builds the rows the specs of the other packages run on.
+package fixture
+
+import (
+	"time"
+
+	"github.com/twold/go-quandl/api"
+)
+
+// Float returns a pointer to v
+func Float(v float64) *float64 {
+	return &v
+}
+
+// String returns a pointer to s
+func String(s string) *string {
+	return &s
+}
+
+// Wiki returns a WIKI row of date, YYYY-MM-DD, with its day of the week and
+// close as both the raw and the adjusted close
+func Wiki(date string, close float64) api.Wiki {
+	obj := api.Wiki{Date: String(date), Close: Float(close), AdjClose: Float(close)}
+	if d, err := time.Parse("2006-01-02", date); err == nil {
+		obj.DayOfWeek = String(d.Weekday().String())
+	}
+	return obj
+}
@@ -0,0 +1,40 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+var envVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces ${VAR} and ${VAR:-default} in every scalar value
+func interpolate(node *yaml.Node) Errors {
+	errs := Errors{}
+	if node.Kind == yaml.ScalarNode {
+		value := envVar.ReplaceAllStringFunc(node.Value, func(m string) string {
+			sub := envVar.FindStringSubmatch(m)
+			if v, ok := os.LookupEnv(sub[1]); ok && v != "" {
+				return v
+			}
+			if sub[2] != "" {
+				return sub[3]
+			}
+			errs = append(errs, &Error{Line: node.Line, Msg: fmt.Sprintf("environment variable %s is not set", sub[1])})
+			return m
+		})
+		// let yaml resolve the type of plain values again, e.g. ${WORKERS} as int
+		if value != node.Value && node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+			node.Tag = ""
+		}
+		node.Value = value
+		return errs
+	}
+
+	for _, n := range node.Content {
+		errs = append(errs, interpolate(n)...)
+	}
+	return errs
+}
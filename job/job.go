@@ -0,0 +1,224 @@
+// Package job reads the declarative job files run by the quandl CLI.
+//
+// A job file is YAML with a list of jobs, e.g.
+//
+//	api_key: ${QUANDLAPIKEY}
+//	jobs:
+//	  - name: tech
+//	    dbcode: WIKI
+//	    path: ${DATA:-./data}
+//	    input_file: SP500.json
+//	    sector: Information Technology
+//	    start_date: 2018-01-01
+//	    sink: json
+//	    layout: symbol={{.Symbol}}/year={{.Year}}/part.json
+//	    revisions: true
+//	    rules:
+//	      low_above_high: drop
+//	      negative_volume: fail
+//	    quality_report: ./data/quality.json
+//	    enrich: [iso_week, fiscal_period, month_end]
+//	    fiscal_year_start: 10
+//	    concurrency: 4
+//	    retry:
+//	      attempts: 3
+//	      backoff: 2s
+//
+// ${VAR} and ${VAR:-default} are replaced with environment variables.
+package job
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/twold/go-quandl/quality"
+)
+
+// Sinks
+const (
+	JSON    = "json"
+	Parquet = "parquet"
+	Stdout  = "stdout"
+)
+
+// Sinks lists the supported sink names
+var Sinks = []string{JSON, Parquet, Stdout}
+
+type File struct {
+	Name   string `yaml:"-"`
+	APIKey string `yaml:"api_key"`
+	Jobs   []Job  `yaml:"jobs"`
+}
+
+type Job struct {
+	Name          string            `yaml:"name"`
+	DBCode        string            `yaml:"dbcode"`
+	Path          string            `yaml:"path"`
+	Tickers       []string          `yaml:"tickers"`
+	InputFile     string            `yaml:"input_file"`
+	Sector        string            `yaml:"sector"`
+	StartDate     string            `yaml:"start_date"`
+	EndDate       string            `yaml:"end_date"`
+	Sink          string            `yaml:"sink"`
+	Layout        string            `yaml:"layout"`
+	Revisions     bool              `yaml:"revisions"`
+	Rules         map[string]string `yaml:"rules"`
+	QualityReport string            `yaml:"quality_report"`
+	Enrich        []string          `yaml:"enrich"`
+	FiscalStart   int               `yaml:"fiscal_year_start"`
+	Concurrency   int               `yaml:"concurrency"`
+	Retry         Retry             `yaml:"retry"`
+
+	// line of the job and of each of its keys, for errors
+	line    int
+	lines   map[string]int
+	unknown []string
+}
+
+type Retry struct {
+	Attempts int      `yaml:"attempts"`
+	Backoff  Duration `yaml:"backoff"`
+}
+
+// Duration is a time.Duration written as e.g. "500ms" or "2s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	v, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return &Error{Line: value.Line, Msg: fmt.Sprintf("invalid duration %q", value.Value)}
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads, interpolates and validates a job file
+func Load(name string) (*File, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(name, b)
+}
+
+// Parse reads a job file from b, name is only used in errors
+func Parse(name string, b []byte) (*File, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, yamlError(name, err)
+	}
+
+	errs := interpolate(&doc)
+	if len(errs) > 0 {
+		return nil, errs.in(name)
+	}
+
+	f := &File{Name: name}
+	if err := doc.Decode(f); err != nil {
+		return nil, yamlError(name, err)
+	}
+
+	if errs := f.Validate(); len(errs) > 0 {
+		return nil, errs.in(name)
+	}
+	return f, nil
+}
+
+func (j *Job) UnmarshalYAML(value *yaml.Node) error {
+	type plain Job
+	if err := value.Decode((*plain)(j)); err != nil {
+		return err
+	}
+
+	j.line = value.Line
+	j.lines = map[string]int{}
+	j.unknown = nil
+	j.keyLines("", value)
+	return nil
+}
+
+// Line returns the line of key, e.g. "retry.backoff", or of the job itself
+func (j *Job) Line(key string) int {
+	if line, ok := j.lines[key]; ok {
+		return line
+	}
+	return j.line
+}
+
+var known = map[string]bool{
+	"name": true, "dbcode": true, "path": true, "tickers": true, "input_file": true, "sector": true,
+	"start_date": true, "end_date": true, "sink": true, "layout": true, "revisions": true, "concurrency": true, "retry": true,
+	"retry.attempts": true, "retry.backoff": true, "rules": true, "quality_report": true, "enrich": true,
+	"fiscal_year_start": true,
+}
+
+func init() {
+	for _, rule := range quality.Rules {
+		known["rules."+rule] = true
+	}
+}
+
+// keyLines records the line of every key of node, following merge keys
+func (j *Job) keyLines(prefix string, node *yaml.Node) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if k.Value == "<<" {
+			if v.Kind == yaml.SequenceNode {
+				for _, n := range v.Content {
+					j.keyLines(prefix, n)
+				}
+				continue
+			}
+			j.keyLines(prefix, v)
+			continue
+		}
+
+		key := prefix + k.Value
+		if _, ok := j.lines[key]; ok {
+			// keys given directly override merged ones
+			continue
+		}
+		j.lines[key] = k.Line
+		if !known[key] {
+			j.unknown = append(j.unknown, key)
+		}
+		if v.Kind == yaml.MappingNode {
+			j.keyLines(key+".", v)
+		}
+	}
+}
+
+// yamlError adds the file name to yaml syntax and type errors
+func yamlError(name string, err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return Errors{e}.in(name)
+	case *yaml.TypeError:
+		errs := Errors{}
+		for _, msg := range e.Errors {
+			errs = append(errs, parseLine(msg))
+		}
+		return errs.in(name)
+	}
+	return &Error{File: name, Line: 0, Msg: strings.TrimPrefix(err.Error(), "yaml: ")}
+}
+
+// parseLine splits "line 3: message" as returned by yaml
+func parseLine(msg string) *Error {
+	e := &Error{Msg: msg}
+	if n, _ := fmt.Sscanf(msg, "line %d:", &e.Line); n == 1 {
+		e.Msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+	return e
+}
@@ -0,0 +1,192 @@
+package job_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/twold/go-quandl/job"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	Context("When I input a valid job file", func() {
+		It("returns the jobs with environment variables replaced", func() {
+			os.Setenv("JOB_TEST_DATA", "/tmp/data")
+			os.Setenv("JOB_TEST_WORKERS", "4")
+			actual, err := Parse("jobs.yaml", []byte(`
+defaults: &defaults
+  dbcode: WIKI
+  path: ${JOB_TEST_DATA}
+jobs:
+  - <<: *defaults
+    name: tech
+    input_file: SP500.json
+    sector: Information Technology
+    start_date: 2018-01-01
+    concurrency: ${JOB_TEST_WORKERS}
+    retry:
+      attempts: 3
+      backoff: 2s
+  - <<: *defaults
+    name: fb
+    tickers: [FB]
+    sink: ${JOB_TEST_SINK:-parquet}
+`))
+			Expect(err).Should(BeNil())
+			Expect(actual.Jobs).Should(HaveLen(2))
+			Expect(actual.Jobs[0].Path).Should(Equal("/tmp/data"))
+			Expect(actual.Jobs[0].Concurrency).Should(Equal(4))
+			Expect(actual.Jobs[0].Sink).Should(Equal(JSON))
+			Expect(time.Duration(actual.Jobs[0].Retry.Backoff)).Should(Equal(2 * time.Second))
+			Expect(actual.Jobs[1].Sink).Should(Equal(Parquet))
+			Expect(actual.Jobs[1].Concurrency).Should(Equal(1))
+		})
+	})
+
+	Context("When I input an invalid job", func() {
+		It("returns errors pointing at the offending lines", func() {
+			_, err := Parse("jobs.yaml", []byte(`jobs:
+  - name: bad
+    dbcode: NOPE
+    path: ./data
+    tickers: [FB]
+    start_date: 2018-02-01
+    end_date: 2018-01-01
+    colour: red
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:3: job "bad": unknown database code "NOPE"`))
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:7: job "bad": end_date 2018-01-01 is before start_date 2018-02-01`))
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:8: job "bad": unknown key "colour"`))
+		})
+	})
+
+	Context("When a job has a layout", func() {
+		It("checks it against the sink", func() {
+			f, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    sink: parquet
+    layout: hive
+`))
+			Expect(err).Should(BeNil())
+			Expect(f.Jobs[0].Layout).Should(Equal("db={{.DB}}/symbol={{.Symbol}}/year={{.Year}}/part.parquet"))
+
+			_, err = Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    layout: year={{.Year}}/part.json
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("jobs.yaml:5: "))
+			Expect(err.Error()).Should(ContainSubstring("must contain {{.Symbol}}"))
+		})
+	})
+
+	Context("When a job tracks revisions", func() {
+		It("needs a sink that saves files", func() {
+			f, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    revisions: true
+`))
+			Expect(err).Should(BeNil())
+			Expect(f.Jobs[0].Revisions).Should(BeTrue())
+
+			_, err = Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    tickers: [FB]
+    sink: stdout
+    revisions: true
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("jobs.yaml:5: "))
+			Expect(err.Error()).Should(ContainSubstring("revisions needs a json or parquet sink"))
+		})
+	})
+
+	Context("When a job has quality rules", func() {
+		It("checks the rules and actions", func() {
+			f, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    rules:
+      low_above_high: drop
+    quality_report: ./data/quality.json
+`))
+			Expect(err).Should(BeNil())
+			Expect(f.Jobs[0].Rules).Should(Equal(map[string]string{"low_above_high": "drop"}))
+
+			_, err = Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    rules:
+      spikes: drop
+      negative_volume: ignore
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:6: jobs[0]: unknown key "rules.spikes"`))
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:7: jobs[0]: unknown action "ignore"`))
+		})
+	})
+
+	Context("When a job enriches rows", func() {
+		It("checks the fields and the sink", func() {
+			f, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    enrich: [iso_week, month_end]
+    fiscal_year_start: 10
+`))
+			Expect(err).Should(BeNil())
+			Expect(f.Jobs[0].Enrich).Should(Equal([]string{"iso_week", "month_end"}))
+			Expect(f.Jobs[0].FiscalStart).Should(Equal(10))
+
+			_, err = Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    sink: parquet
+    enrich: [week]
+    fiscal_year_start: 13
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:6: jobs[0]: unknown field "week"`))
+			Expect(err.Error()).Should(ContainSubstring(`jobs.yaml:7: jobs[0]: fiscal_year_start must be a month from 1 to 12`))
+			Expect(err.Error()).Should(ContainSubstring("enrich fields are not saved in parquet files"))
+		})
+	})
+
+	Context("When an environment variable is missing", func() {
+		It("returns the line of the value", func() {
+			_, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ${JOB_TEST_MISSING}
+    tickers: [FB]
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(Equal("jobs.yaml:3: environment variable JOB_TEST_MISSING is not set"))
+		})
+	})
+
+	Context("When a value has the wrong type", func() {
+		It("returns the line of the value", func() {
+			_, err := Parse("jobs.yaml", []byte(`jobs:
+  - dbcode: WIKI
+    path: ./data
+    tickers: [FB]
+    concurrency: many
+`))
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(HavePrefix("jobs.yaml:5: "))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package job
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/quality"
+)
+
+// Error points at the line of a job file that caused it
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+// Errors lists every problem found in a job file
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e Errors) in(name string) Errors {
+	for _, err := range e {
+		err.File = name
+	}
+	return e
+}
+
+const dateLayout = "2006-01-02"
+
+// Validate checks every job and returns all errors found
+func (f *File) Validate() Errors {
+	errs := Errors{}
+	if len(f.Jobs) == 0 {
+		errs = append(errs, &Error{Msg: "no jobs defined"})
+	}
+
+	names := map[string]bool{}
+	for i := range f.Jobs {
+		j := &f.Jobs[i]
+		add := func(key, format string, a ...interface{}) {
+			msg := fmt.Sprintf("jobs[%d]: %s", i, fmt.Sprintf(format, a...))
+			if j.Name != "" {
+				msg = fmt.Sprintf("job %q: %s", j.Name, fmt.Sprintf(format, a...))
+			}
+			errs = append(errs, &Error{Line: j.Line(key), Msg: msg})
+		}
+
+		for _, key := range j.unknown {
+			add(key, "unknown key %q", key)
+		}
+
+		if j.Name != "" {
+			if names[j.Name] {
+				add("name", "duplicate job name")
+			}
+			names[j.Name] = true
+		}
+
+		if j.DBCode == "" {
+			add("dbcode", "dbcode is required")
+		} else if !contains(endpoints.DatabaseCodes, j.DBCode) {
+			add("dbcode", "unknown database code %q, options are %s", j.DBCode, strings.Join(endpoints.DatabaseCodes, ", "))
+		}
+
+		switch {
+		case len(j.Tickers) > 0 && j.InputFile != "":
+			add("input_file", "tickers and input_file are mutually exclusive")
+		case len(j.Tickers) == 0 && j.InputFile == "":
+			add("tickers", "tickers or input_file is required")
+		case j.Sector != "" && j.InputFile == "":
+			add("sector", "sector requires input_file")
+		}
+
+		start, startErr := parseDate(j.StartDate)
+		if startErr != nil {
+			add("start_date", "invalid start_date %q, expected YYYY-MM-DD", j.StartDate)
+		}
+		end, endErr := parseDate(j.EndDate)
+		if endErr != nil {
+			add("end_date", "invalid end_date %q, expected YYYY-MM-DD", j.EndDate)
+		}
+		if startErr == nil && endErr == nil && !start.IsZero() && !end.IsZero() && end.Before(start) {
+			add("end_date", "end_date %s is before start_date %s", j.EndDate, j.StartDate)
+		}
+
+		if j.Sink == "" {
+			j.Sink = JSON
+		}
+		if !contains(Sinks, j.Sink) {
+			add("sink", "unknown sink %q, options are %s", j.Sink, strings.Join(Sinks, ", "))
+		}
+		if j.Layout != "" {
+			if j.Layout == "hive" {
+				j.Layout = layout.Hive
+			}
+			t, err := layout.Parse(j.Layout)
+			switch {
+			case err != nil:
+				add("layout", "%v", err)
+			case j.Sink == Stdout:
+				add("layout", "layout needs a json or parquet sink")
+			case t.Ext() != "."+j.Sink:
+				add("layout", "layout %q does not end in .%s", j.Layout, j.Sink)
+			}
+		}
+		rules := make([]string, 0, len(j.Rules))
+		for rule := range j.Rules {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			if action := j.Rules[rule]; contains(quality.Rules, rule) && !contains(quality.Actions, action) {
+				add("rules."+rule, "unknown action %q, options are %s", action, strings.Join(quality.Actions, ", "))
+			}
+		}
+
+		for _, field := range j.Enrich {
+			if field != enrich.All && enrich.Columns[field] == "" {
+				add("enrich", "unknown field %q, options are %s or %s", field, strings.Join(enrich.Names, ", "), enrich.All)
+			}
+		}
+		if j.FiscalStart < 0 || j.FiscalStart > 12 {
+			add("fiscal_year_start", "fiscal_year_start must be a month from 1 to 12")
+		}
+		if len(j.Enrich) > 0 && (j.Sink == Parquet || (j.Layout != "" && path.Ext(j.Layout) == ".parquet")) {
+			add("enrich", "enrich fields are not saved in parquet files, use a json sink")
+		}
+
+		if j.Revisions && j.Sink == Stdout {
+			add("revisions", "revisions needs a json or parquet sink")
+		}
+		if j.Path == "" && (j.Sink != Stdout || j.InputFile != "") {
+			add("path", "path is required")
+		}
+
+		if j.Concurrency < 0 {
+			add("concurrency", "concurrency must not be negative")
+		}
+		if j.Concurrency == 0 {
+			j.Concurrency = 1
+		}
+		if j.Retry.Attempts < 0 {
+			add("retry.attempts", "retry attempts must not be negative")
+		}
+		if j.Retry.Backoff < 0 {
+			add("retry.backoff", "retry backoff must not be negative")
+		}
+	}
+	return errs
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, s)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/job"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/quality"
+	"github.com/twold/go-quandl/store"
+)
+
+func runJobs(args []string) error {
+	var (
+		o    options
+		only string
+	)
+	fs := newFlagSet("run", "JOBFILE")
+	fs.StringVar(&only, "job", "", "only run the job with this name")
+	logFlag(fs)
+	o.metricsFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := o.startMetrics(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("run: exactly one job file is required")
+	}
+
+	f, err := job.Load(fs.Arg(0))
+	if err != nil {
+		if _, ok := err.(job.Errors); ok {
+			return &usageError{msg: err.Error()}
+		}
+		return err
+	}
+
+	ran := false
+	for i := range f.Jobs {
+		j := &f.Jobs[i]
+		if only != "" && j.Name != only {
+			continue
+		}
+		ran = true
+
+		logger.Info("Running job.", "job", j.Name)
+		if err := runJob(f, j, o.stats); err != nil {
+			return fmt.Errorf("job %q: %v", j.Name, err)
+		}
+	}
+	if !ran {
+		return usagef("run: no job named %q in %s", only, f.Name)
+	}
+	return nil
+}
+
+func runJob(f *job.File, j *job.Job, stats metrics.Metrics) error {
+	o := options{
+		apiKey:    f.APIKey,
+		dbCode:    j.DBCode,
+		format:    endpoints.JSON,
+		inputFile: j.InputFile,
+		path:      j.Path,
+		sector:    j.Sector,
+	}
+	if o.apiKey == "" {
+		o.apiKey = os.Getenv("QUANDLAPIKEY")
+	}
+
+	symbols, err := o.symbols(j.Tickers)
+	if err != nil {
+		return err
+	}
+
+	opts := []api.Option{
+		api.DateRange(j.StartDate, j.EndDate),
+		api.Retry(j.Retry.Attempts, time.Duration(j.Retry.Backoff)),
+		api.Logger(logger),
+		api.Metrics(stats),
+	}
+	if len(j.Rules) > 0 || j.QualityReport != "" {
+		// rules were checked when the job file was loaded
+		o.validator, err = quality.New(j.Rules)
+		if err != nil {
+			return err
+		}
+		o.qualityReport = j.QualityReport
+		opts = append(opts, api.Validate(o.validator))
+	}
+
+	if len(j.Enrich) > 0 {
+		fields, err := enrich.Parse(strings.Join(j.Enrich, ","))
+		if err == nil {
+			o.enricher, err = enrich.New(enrich.Options{Fields: fields, FiscalYearStart: time.Month(j.FiscalStart)})
+		}
+		if err != nil {
+			return err
+		}
+		opts = append(opts, api.Enrich(o.enricher))
+	}
+
+	dataType := endpoints.DATA
+	svc := api.New(&dataType, &o.dbCode, &o.format, &o.apiKey, opts...)
+
+	var (
+		sink api.Sink
+		t    *layout.Template
+	)
+	switch {
+	case j.Layout != "":
+		t, err = layout.Parse(j.Layout)
+		if err != nil {
+			return err
+		}
+		sink = &store.Partitioned{Path: j.Path, Layout: t, Log: logger, Metrics: stats}
+	case j.Sink == job.JSON:
+		sink = &api.JSONFiles{Path: j.Path, Overwrite: j.Revisions, Log: logger, Metrics: stats}
+	case j.Sink == job.Parquet:
+		sink = &api.ParquetFiles{Path: j.Path, Overwrite: j.Revisions, Log: logger, Metrics: stats}
+	}
+	if j.Revisions && sink != nil {
+		sink = &store.Tracker{Path: j.Path, Layout: t, Sink: sink, Log: logger, Metrics: stats}
+	}
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "	")
+	err = forEachN(symbols, j.Concurrency, func(symbol string) error {
+		resp, err := svc.Fetch(symbol)
+		if err != nil {
+			return err
+		}
+
+		if sink == nil {
+			mu.Lock()
+			defer mu.Unlock()
+			return enc.Encode(resp.Data)
+		}
+
+		objs, ok := resp.Data.([]api.Wiki)
+		if !ok {
+			return fmt.Errorf("sink %s only supports WIKI data sets", j.Sink)
+		}
+		return sink.Write(resp.Source, objs)
+	})
+	if qerr := o.saveQuality(); err == nil {
+		err = qerr
+	}
+	return err
+}
@@ -0,0 +1,225 @@
+// Package layout names saved files with path templates such as
+// db={{.DB}}/symbol={{.Symbol}}/year={{.Year}}/part.parquet. Templates use
+// the text/template field syntax but only plain fields, so that a path can be
+// matched back to the values it was rendered from.
+package layout
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Hive is a Hive-style layout with one parquet file per symbol and year that
+// Spark, Trino and DuckDB discover as db, symbol and year partitions
+const Hive = "db={{.DB}}/symbol={{.Symbol}}/year={{.Year}}/part.parquet"
+
+// Vars are the values a template is rendered with
+type Vars struct {
+	DB     string
+	Symbol string
+	Year   string
+	Month  string
+	Day    string
+	Date   string
+}
+
+// For returns the values of a row of symbol dated date, YYYY-MM-DD
+func For(db, symbol, date string) Vars {
+	v := Vars{DB: db, Symbol: symbol, Date: date}
+	if len(date) == len("2006-01-02") {
+		v.Year, v.Month, v.Day = date[:4], date[5:7], date[8:]
+	}
+	return v
+}
+
+// Span returns the first and last date a partition with these values can
+// hold, "" for an open end
+func (v Vars) Span() (string, string) {
+	switch {
+	case v.Date != "":
+		return v.Date, v.Date
+	case v.Year != "" && v.Month != "" && v.Day != "":
+		d := v.Year + "-" + v.Month + "-" + v.Day
+		return d, d
+	case v.Year != "" && v.Month != "":
+		return v.Year + "-" + v.Month + "-01", v.Year + "-" + v.Month + "-31"
+	case v.Year != "":
+		return v.Year + "-01-01", v.Year + "-12-31"
+	}
+	return "", ""
+}
+
+// Overlaps reports whether the partition may hold dates from from to to,
+// either may be ""
+func (v Vars) Overlaps(from, to string) bool {
+	first, last := v.Span()
+	if from != "" && last != "" && last < from {
+		return false
+	}
+	if to != "" && first != "" && first > to {
+		return false
+	}
+	return true
+}
+
+func (v Vars) get(field string) string {
+	switch field {
+	case "DB":
+		return v.DB
+	case "Symbol":
+		return v.Symbol
+	case "Year":
+		return v.Year
+	case "Month":
+		return v.Month
+	case "Day":
+		return v.Day
+	}
+	return v.Date
+}
+
+func (v *Vars) set(field, value string) {
+	switch field {
+	case "DB":
+		v.DB = value
+	case "Symbol":
+		v.Symbol = value
+	case "Year":
+		v.Year = value
+	case "Month":
+		v.Month = value
+	case "Day":
+		v.Day = value
+	case "Date":
+		v.Date = value
+	}
+}
+
+var fields = map[string]bool{"DB": true, "Symbol": true, "Year": true, "Month": true, "Day": true, "Date": true}
+
+var action = regexp.MustCompile(`{{\s*\.(\w+)\s*}}`)
+
+// Template is a parsed path template
+type Template struct {
+	text string
+	full pattern
+	// dirs[k] matches the first k+1 directories, to prune a walk
+	dirs []pattern
+}
+
+type pattern struct {
+	re     *regexp.Regexp
+	fields []string
+}
+
+// Parse parses a path template. It must name the symbol and end in .json or
+// .parquet, which decides the file format.
+func Parse(text string) (*Template, error) {
+	if text == "" || path.IsAbs(text) || strings.Contains(text, `\`) {
+		return nil, fmt.Errorf("layout %q: must be a relative path", text)
+	}
+	segs := strings.Split(text, "/")
+	for _, seg := range segs {
+		if seg == "" || seg == "." || seg == ".." {
+			return nil, fmt.Errorf("layout %q: bad path segment %q", text, seg)
+		}
+	}
+	if strings.Contains(action.ReplaceAllString(text, ""), "{{") {
+		return nil, fmt.Errorf("layout %q: only fields such as {{.Symbol}} are supported", text)
+	}
+
+	t := &Template{text: text}
+	var err error
+	if t.full, err = compile(text); err != nil {
+		return nil, fmt.Errorf("layout %q: %v", text, err)
+	}
+	for k := 1; k < len(segs); k++ {
+		p, _ := compile(strings.Join(segs[:k], "/"))
+		t.dirs = append(t.dirs, p)
+	}
+
+	if !t.Has("Symbol") {
+		return nil, fmt.Errorf("layout %q: must contain {{.Symbol}}", text)
+	}
+	if ext := t.Ext(); ext != ".json" && ext != ".parquet" {
+		return nil, fmt.Errorf("layout %q: must end in .json or .parquet", text)
+	}
+	return t, nil
+}
+
+func compile(text string) (pattern, error) {
+	var p pattern
+	expr, last := "^", 0
+	for _, m := range action.FindAllStringSubmatchIndex(text, -1) {
+		field := text[m[2]:m[3]]
+		if !fields[field] {
+			return p, fmt.Errorf("unknown field .%s", field)
+		}
+		expr += regexp.QuoteMeta(text[last:m[0]]) + `([^/]+)`
+		p.fields = append(p.fields, field)
+		last = m[1]
+	}
+	p.re = regexp.MustCompile(expr + regexp.QuoteMeta(text[last:]) + "$")
+	return p, nil
+}
+
+func (p pattern) match(s string) (Vars, bool) {
+	var v Vars
+	m := p.re.FindStringSubmatch(s)
+	if m == nil {
+		return v, false
+	}
+	for i, field := range p.fields {
+		// a field used twice must have the same value
+		if prev := v.get(field); prev != "" && prev != m[i+1] {
+			return v, false
+		}
+		v.set(field, m[i+1])
+	}
+	return v, true
+}
+
+// String returns the template text
+func (t *Template) String() string {
+	return t.text
+}
+
+// Ext returns the file extension, ".json" or ".parquet"
+func (t *Template) Ext() string {
+	return path.Ext(t.text)
+}
+
+// Has reports whether the template uses field, e.g. "Year"
+func (t *Template) Has(field string) bool {
+	for _, f := range t.full.fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute returns the slash separated path of v
+func (t *Template) Execute(v Vars) string {
+	return action.ReplaceAllStringFunc(t.text, func(s string) string {
+		return v.get(action.FindStringSubmatch(s)[1])
+	})
+}
+
+// Match returns the values a slash separated file path was rendered from, or
+// false if the template did not render it
+func (t *Template) Match(p string) (Vars, bool) {
+	return t.full.match(p)
+}
+
+// MatchDir is Match for a directory, returning the values of the leading
+// directories of the template it matches
+func (t *Template) MatchDir(p string) (Vars, bool) {
+	k := strings.Count(p, "/")
+	if k >= len(t.dirs) {
+		return Vars{}, false
+	}
+	return t.dirs[k].match(p)
+}
@@ -0,0 +1,74 @@
+package layout_test
+
+import (
+	. "github.com/twold/go-quandl/layout"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layout", func() {
+	Context("When I parse the hive layout", func() {
+		t, err := Parse(Hive)
+
+		It("renders and matches paths", func() {
+			Expect(err).Should(BeNil())
+			Expect(t.Ext()).Should(Equal(".parquet"))
+
+			name := t.Execute(For("WIKI", "FB", "2018-01-02"))
+			Expect(name).Should(Equal("db=WIKI/symbol=FB/year=2018/part.parquet"))
+
+			v, ok := t.Match(name)
+			Expect(ok).Should(BeTrue())
+			Expect(v).Should(Equal(Vars{DB: "WIKI", Symbol: "FB", Year: "2018"}))
+
+			_, ok = t.Match("db=WIKI/symbol=FB/2018/part.parquet")
+			Expect(ok).Should(BeFalse())
+		})
+
+		It("matches leading directories", func() {
+			v, ok := t.MatchDir("db=WIKI/symbol=FB")
+			Expect(ok).Should(BeTrue())
+			Expect(v.Symbol).Should(Equal("FB"))
+
+			_, ok = t.MatchDir("input")
+			Expect(ok).Should(BeFalse())
+			_, ok = t.MatchDir("db=WIKI/symbol=FB/year=2018")
+			Expect(ok).Should(BeTrue())
+			_, ok = t.MatchDir("db=WIKI/symbol=FB/year=2018/more")
+			Expect(ok).Should(BeFalse())
+		})
+	})
+
+	Context("When I prune partitions", func() {
+		It("compares their span with the dates", func() {
+			year := Vars{Year: "2018"}
+			Expect(year.Overlaps("2018-06-01", "")).Should(BeTrue())
+			Expect(year.Overlaps("2019-01-01", "")).Should(BeFalse())
+			Expect(year.Overlaps("", "2017-12-31")).Should(BeFalse())
+
+			month := Vars{Year: "2018", Month: "02"}
+			Expect(month.Overlaps("2018-02-28", "2018-03-31")).Should(BeTrue())
+			Expect(month.Overlaps("2018-03-01", "")).Should(BeFalse())
+
+			Expect(Vars{Symbol: "FB"}.Overlaps("2018-01-01", "2018-01-31")).Should(BeTrue())
+		})
+	})
+
+	Context("When I parse a bad layout", func() {
+		It("returns an error", func() {
+			for _, text := range []string{
+				"",
+				"/abs/{{.Symbol}}.json",
+				"../{{.Symbol}}.json",
+				"{{.Year}}.json",
+				"{{.Symbol}}/{{.Hour}}.json",
+				"{{.Symbol}}/{{if .Year}}x{{end}}.json",
+				"{{.Symbol}}/{{.Date}}.csv",
+			} {
+				_, err := Parse(text)
+				Expect(err).ShouldNot(BeNil(), text)
+			}
+		})
+	})
+})
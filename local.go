@@ -0,0 +1,642 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/arrowsink"
+	"github.com/twold/go-quandl/blob"
+	"github.com/twold/go-quandl/calendar"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/sqlite"
+	"github.com/twold/go-quandl/store"
+	"github.com/twold/go-quandl/xlsx"
+)
+
+func runListSectors(args []string) error {
+	var o options
+	fs := newFlagSet("list-sectors", "")
+	o.pathFlag(fs)
+	fs.StringVar(&o.inputFile, "inputFile", "SP500.json", "input file under <path>/input listing symbols")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	list, err := api.ReadInputFile(o.path, o.inputFile)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, item := range list {
+		counts[item.Sector]++
+	}
+	sectors := make([]string, 0, len(counts))
+	for sector := range counts {
+		sectors = append(sectors, sector)
+	}
+	sort.Strings(sectors)
+
+	for _, sector := range sectors {
+		fmt.Printf("%s\t%d\n", sector, counts[sector])
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	var (
+		o        options
+		to       string
+		out      string
+		bySector bool
+		adjusted bool
+		asOf     string
+		actions  bool
+	)
+	fs := newFlagSet("export", "[SYMBOL...]")
+	o.pathFlag(fs)
+	o.symbolFlags(fs)
+	o.layoutFlag(fs)
+	fs.StringVar(&to, "to", "csv", "export format, 'csv', 'json', 'sqlite', 'arrow' (IPC stream), 'feather' or 'xlsx'")
+	fs.StringVar(&out, "o", "", "output file, defaults to stdout")
+	fs.BoolVar(&bySector, "by_sector", false, "xlsx: one sheet per sector of the input file instead of per symbol")
+	fs.BoolVar(&adjusted, "adjusted", false, "xlsx: only the date and the adjusted columns")
+	fs.StringVar(&asOf, "as_of", "", "export the rows as known at the end of this day, YYYY-MM-DD, undoing later revisions")
+	fs.BoolVar(&actions, "actions", false, "export the splits and dividends found in the saved rows instead of the rows")
+	o.enrichFlags(fs)
+	o.resampleFlag(fs)
+	o.indicatorFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := o.startEnrich(); err != nil {
+		return err
+	}
+	if err := o.checkResample(); err != nil {
+		return err
+	}
+	if err := o.startIndicators(); err != nil {
+		return err
+	}
+	switch to {
+	case "csv", "json", "arrow":
+	case "sqlite", "feather", "xlsx":
+		if out == "" {
+			return usagef("export: -to=%s requires -o", to)
+		}
+	default:
+		return usagef("export: unknown format %q", to)
+	}
+	if (bySector || adjusted) && to != "xlsx" {
+		return usagef("export: -by_sector and -adjusted need -to=xlsx")
+	}
+	if actions && (adjusted || o.fields != "" || o.freq != "" || len(o.specs) > 0) {
+		return usagef("export: -actions takes the daily rows, without -adjusted, -enrich, -indicators or -resample")
+	}
+	if len(o.specs) > 0 && (to == "json" || to == "sqlite") {
+		return usagef("export: -indicators needs -to=csv, arrow, feather or xlsx")
+	}
+	var on time.Time
+	if asOf != "" {
+		day, err := time.Parse(store.DateLayout, asOf)
+		if err != nil {
+			return usagef("export: invalid -as_of %q, expected YYYY-MM-DD", asOf)
+		}
+		on = day.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	symbols, err := o.symbols(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	r, err := o.reader()
+	if err != nil {
+		return err
+	}
+	load := func(symbol string) ([]api.Wiki, error) {
+		var (
+			objs []api.Wiki
+			err  error
+		)
+		if on.IsZero() {
+			objs, err = r.Load(symbol, "", "")
+		} else {
+			objs, err = r.LoadAsOf(symbol, "", "", on)
+		}
+		if err != nil || (o.enricher == nil && o.freq == "") {
+			return objs, err
+		}
+		src, err := r.Source(symbol)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		objs, err = o.resampled(src.DBCode, objs)
+		if err != nil || o.enricher == nil {
+			return objs, err
+		}
+		return objs, api.EnrichWiki(o.enricher, src.DBCode, objs)
+	}
+	if actions {
+		return exportActions(&o, load, symbols, to, out, bySector)
+	}
+	switch to {
+	case "sqlite":
+		return exportSQLite(&o, r, load, symbols, out)
+	case "xlsx":
+		return exportXLSX(&o, load, symbols, out, bySector, adjusted)
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if out != "" {
+		f, err = os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	rows := map[string][]api.Wiki{}
+	for _, symbol := range symbols {
+		objs, err := load(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		rows[symbol] = objs
+	}
+
+	switch to {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "	")
+		return enc.Encode(rows)
+	}
+	if to == "csv" && len(o.specs) == 0 {
+		return writeCSV(w, symbols, rows)
+	}
+
+	tables := make([]*api.Table, 0, len(symbols))
+	for _, symbol := range symbols {
+		t, err := o.table(symbol, rows[symbol])
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		tables = append(tables, t)
+	}
+	switch to {
+	case "arrow":
+		return arrowsink.WriteStream(w, tables)
+	case "feather":
+		return arrowsink.WriteFeather(f, tables)
+	}
+	return writeTablesCSV(w, tables)
+}
+
+// exportSQLite upserts the saved symbols into the database file name, with
+// the names and sectors of the input file when there is one
+func exportSQLite(o *options, r *store.Reader, load func(string) ([]api.Wiki, error), symbols []string, name string) error {
+	db, err := sqlite.Open(name)
+	if err != nil {
+		return err
+	}
+	db.Log = logger
+
+	err = writeSQLite(o, db, r, load, symbols)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeSQLite(o *options, db *sqlite.DB, r *store.Reader, load func(string) ([]api.Wiki, error), symbols []string) error {
+	list, err := api.ReadInputFile(o.path, o.inputFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := db.Symbols(list); err != nil {
+		return err
+	}
+
+	for _, symbol := range symbols {
+		src, err := r.Source(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		objs, err := load(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		if err := db.Write(src, objs); err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+	}
+	return nil
+}
+
+// exportXLSX writes the saved symbols to the workbook name, one sheet per
+// symbol or per sector of the input file
+func exportXLSX(o *options, load func(string) ([]api.Wiki, error), symbols []string, name string, bySector, adjusted bool) error {
+	opts := xlsx.Options{Adjusted: adjusted}
+	if bySector {
+		list, err := api.ReadInputFile(o.path, o.inputFile)
+		if err != nil {
+			return err
+		}
+		opts.Sectors = xlsx.Sectors(list)
+	}
+
+	tables := make([]*api.Table, 0, len(symbols))
+	for _, symbol := range symbols {
+		objs, err := load(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		t, err := o.table(symbol, objs)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		tables = append(tables, t)
+	}
+	if err := xlsx.WriteFile(name, tables, opts); err != nil {
+		return err
+	}
+	logger.Info("Wrote workbook.", logging.File, name, "symbols", len(tables))
+	return nil
+}
+
+// exportActions writes the corporate actions of the saved symbols to out in
+// the format to
+func exportActions(o *options, load func(string) ([]api.Wiki, error), symbols []string, to, out string, bySector bool) error {
+	acts := map[string][]corporate.Action{}
+	tables := make([]*api.Table, 0, len(symbols))
+	for _, symbol := range symbols {
+		objs, err := load(symbol)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		acts[symbol] = corporate.Extract(symbol, objs)
+		tables = append(tables, corporate.Table(symbol, acts[symbol]))
+	}
+
+	switch to {
+	case "sqlite":
+		db, err := sqlite.Open(out)
+		if err != nil {
+			return err
+		}
+		for _, symbol := range symbols {
+			if err = db.Actions(symbol, acts[symbol]); err != nil {
+				err = fmt.Errorf("%s: %v", symbol, err)
+				break
+			}
+		}
+		if cerr := db.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	case "xlsx":
+		var opts xlsx.Options
+		if bySector {
+			list, err := api.ReadInputFile(o.path, o.inputFile)
+			if err != nil {
+				return err
+			}
+			opts.Sectors = xlsx.Sectors(list)
+		}
+		return xlsx.WriteFile(out, tables, opts)
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if out != "" {
+		var err error
+		f, err = os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch to {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "	")
+		return enc.Encode(acts)
+	case "arrow":
+		return arrowsink.WriteStream(w, tables)
+	case "feather":
+		return arrowsink.WriteFeather(f, tables)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Symbol", "Date", "Type", "Ratio", "Amount"}); err != nil {
+		return err
+	}
+	for _, symbol := range symbols {
+		for _, a := range acts[symbol] {
+			rec := []string{symbol, a.Date, a.Type, "", ""}
+			if a.Type == corporate.Split {
+				rec[3] = num(&a.Ratio)
+			} else {
+				rec[4] = num(&a.Amount)
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var csvHeader = []string{
+	"Symbol", "Date", "DayOfWeek", "Open", "High", "Low", "Close", "Volume", "ExDividend", "SplitRatio",
+	"AdjOpen", "AdjHigh", "AdjLow", "AdjClose", "AdjVolume",
+}
+
+func writeCSV(w io.Writer, symbols []string, rows map[string][]api.Wiki) error {
+	fields := []*enrich.Fields{}
+	for _, symbol := range symbols {
+		for _, r := range rows[symbol] {
+			fields = append(fields, r.Fields)
+		}
+	}
+	extra := enrich.Present(fields)
+
+	cw := csv.NewWriter(w)
+	header := append([]string{}, csvHeader...)
+	for _, name := range extra {
+		header = append(header, enrich.Columns[name])
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, symbol := range symbols {
+		for _, r := range rows[symbol] {
+			rec := []string{symbol, str(r.Date), str(r.DayOfWeek)}
+			for _, v := range []*float64{r.Open, r.High, r.Low, r.Close, r.Volume, r.ExDividend, r.SplitRatio,
+				r.AdjOpen, r.AdjHigh, r.AdjLow, r.AdjClose, r.AdjVolume} {
+				rec = append(rec, num(v))
+			}
+			for _, name := range extra {
+				rec = append(rec, cell(r.Fields.Value(name)))
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTablesCSV writes tables under a Symbol column and the union of their
+// columns, in the order they first appear
+func writeTablesCSV(w io.Writer, tables []*api.Table) error {
+	header := []string{"Symbol"}
+	index := map[string]int{}
+	for _, t := range tables {
+		for _, name := range t.Columns {
+			if _, ok := index[name]; !ok {
+				index[name] = len(header)
+				header = append(header, name)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		for _, row := range t.Rows {
+			rec := make([]string, len(header))
+			rec[0] = t.Symbol
+			for i, v := range row {
+				if i < len(t.Columns) {
+					rec[index[t.Columns[i]]] = cell(v)
+				}
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cell formats a table value for csv
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return num(&v)
+	}
+	return fmt.Sprint(v)
+}
+
+func runVerify(args []string) error {
+	var (
+		o       options
+		meta    bool
+		missing bool
+		repair  bool
+		asJSON  bool
+		cal     string
+	)
+	fs := newFlagSet("verify", "[SYMBOL...]")
+	o.authFlags(fs)
+	o.pathFlag(fs)
+	fs.BoolVar(&meta, "meta", false, "check the date range against the data set metadata, requires an api key")
+	fs.BoolVar(&missing, "missing", false, "report trading days without a file")
+	fs.BoolVar(&repair, "repair", false, "remove broken files and fetch broken or missing ranges again")
+	fs.BoolVar(&asJSON, "json", false, "print the reports as json")
+	fs.StringVar(&cal, "calendar", "nyse", "trading calendar of -missing, 'nyse', 'nasdaq', 'cfe' or 'weekdays'")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	symbols := fs.Args()
+	if len(symbols) == 0 {
+		var err error
+		symbols, err = store.Symbols(o.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts := store.VerifyOptions{MissingDays: missing}
+	if cal != "weekdays" {
+		c, err := calendar.Get(cal)
+		if err != nil {
+			return usagef("verify: %v", err)
+		}
+		opts.Sessions = c.Sessions
+	}
+	if meta {
+		opts.Meta = o.service(endpoints.METADATA).Meta
+	}
+	fetch := func(symbol, start, end string) (*api.DataSet, error) {
+		return o.service(endpoints.DATA, api.DateRange(start, end)).Fetch(symbol)
+	}
+	sink := &api.JSONFiles{Path: o.path, Log: logger}
+
+	reports := make([]*store.Report, 0, len(symbols))
+	bad := 0
+	for _, symbol := range symbols {
+		r, err := store.Verify(o.path, symbol, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		if repair && !r.OK() {
+			gaps, err := store.Repair(o.path, r, fetch, sink)
+			if err != nil {
+				return fmt.Errorf("%s: %v", symbol, err)
+			}
+			logger.Info("Repaired symbol.", logging.Symbol, symbol, "problems", len(r.Problems), "ranges", len(gaps))
+			if r, err = store.Verify(o.path, symbol, opts); err != nil {
+				return fmt.Errorf("%s: %v", symbol, err)
+			}
+		}
+		if !r.OK() {
+			bad++
+		}
+		reports = append(reports, r)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "	")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range reports {
+			for _, p := range r.Problems {
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", r.Symbol, p.Kind, p.Date, p.File, p.Detail)
+			}
+		}
+	}
+
+	if bad > 0 {
+		return fmt.Errorf("verify: %d of %d symbols have problems", bad, len(reports))
+	}
+	return nil
+}
+
+func runCompact(args []string) error {
+	var (
+		o    options
+		opts store.CompactOptions
+	)
+	fs := newFlagSet("compact", "[SYMBOL...]")
+	o.pathFlag(fs)
+	fs.StringVar(&opts.By, "by", store.BySymbol, "write one parquet file per symbol or per year: symbol, year")
+	fs.BoolVar(&opts.Keep, "keep", false, "keep the daily files after compacting")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if opts.By != store.BySymbol && opts.By != store.ByYear {
+		return usagef("-by must be %s or %s", store.BySymbol, store.ByYear)
+	}
+
+	symbols := fs.Args()
+	if len(symbols) == 0 {
+		var err error
+		symbols, err = store.Symbols(o.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, symbol := range symbols {
+		names, err := store.Compact(o.path, symbol, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", symbol, err)
+		}
+		logger.Info("Compacted symbol.", logging.Symbol, symbol, "files", len(names))
+	}
+	return nil
+}
+
+func runPush(args []string) error {
+	var (
+		o        options
+		endpoint string
+		region   string
+		partSize int
+	)
+	fs := newFlagSet("push", "s3://BUCKET/PREFIX|DIR [SYMBOL...]")
+	o.pathFlag(fs)
+	o.layoutFlag(fs)
+	fs.StringVar(&region, "region", envOr("AWS_REGION", "us-east-1"), "S3 region, defaults to $AWS_REGION")
+	fs.StringVar(&endpoint, "endpoint", os.Getenv("S3_ENDPOINT"), "S3 endpoint, e.g. 'http://localhost:9000' for MinIO, defaults to $S3_ENDPOINT or AWS")
+	fs.IntVar(&partSize, "part_size", blob.MinPartSize, "upload files larger than this many bytes in parts")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("push: a destination is required")
+	}
+	t, err := o.parseLayout()
+	if err != nil {
+		return err
+	}
+
+	dest, prefix := fs.Arg(0), ""
+	var s blob.Store = blob.Dir(dest)
+	if strings.HasPrefix(dest, "s3://") {
+		bucket := strings.TrimPrefix(dest, "s3://")
+		if i := strings.Index(bucket, "/"); i >= 0 {
+			bucket, prefix = bucket[:i], strings.Trim(bucket[i:], "/")
+		}
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		s = &blob.S3{
+			Endpoint:     endpoint,
+			Region:       region,
+			Bucket:       bucket,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+
+	n, err := blob.PushLayout(s, prefix, o.path, t, partSize, fs.Args()[1:]...)
+	if err != nil {
+		return err
+	}
+	logger.Info("Pushed files.", "files", n, "destination", dest)
+	return nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func num(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,53 @@
+// Package logging defines the leveled, structured logger used by the library.
+//
+// Logger is satisfied by *slog.Logger, so any log/slog handler can be plugged
+// in. Messages take alternating key/value pairs, e.g.
+//
+//	l.Info("Fetched data set.", "symbol", "FB", "rows", 1500)
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// Field keys shared by every package
+const (
+	Symbol   = "symbol"
+	DBCode   = "db"
+	URL      = "url"
+	Rows     = "rows"
+	Duration = "duration"
+	File     = "file"
+	Error    = "error"
+)
+
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Discard drops every message, it is the default for library use
+var Discard Logger = discard{}
+
+// New returns a text logger writing messages at or above level to w
+func New(w io.Writer, level slog.Leveler) Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// OrDiscard returns l, or Discard when l is nil
+func OrDiscard(l Logger) Logger {
+	if l == nil {
+		return Discard
+	}
+	return l
+}
+
+type discard struct{}
+
+func (discard) Debug(msg string, args ...interface{}) {}
+func (discard) Info(msg string, args ...interface{})  {}
+func (discard) Warn(msg string, args ...interface{})  {}
+func (discard) Error(msg string, args ...interface{}) {}
@@ -1,100 +1,411 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/calendar"
+	"github.com/twold/go-quandl/endpoints"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/indicators"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+	"github.com/twold/go-quandl/quality"
+	"github.com/twold/go-quandl/resample"
+	"github.com/twold/go-quandl/store"
 )
 
+// sample input where $QUANDLAPIKEY is your api key and $GOPATH/src/github.com/twold/go-quandl/data
+// is where you have input file and is desired output location
+
+// go run . sync -api_key=$QUANDLAPIKEY -path=$GOPATH/src/github.com/twold/go-quandl/data
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	// assigned here as help refers back to the table
+	commands = []command{
+		{"fetch", "print data sets to stdout without saving them", runFetch},
+		{"sync", "fetch data sets and save them under <path>/output", runSync},
+		{"meta", "print data set metadata", runMeta},
+		{"search", "search data sets in a database", runSearch},
+		{"list-sectors", "list the sectors of the input file", runListSectors},
+		{"export", "export saved data sets as csv or json", runExport},
+		{"verify", "check saved data sets and optionally repair them", runVerify},
+		{"compact", "merge saved daily files into parquet files", runCompact},
+		{"push", "upload saved data sets to S3 compatible storage", runPush},
+		{"risk", "print returns and risk metrics of saved or fetched data sets", runRisk},
+		{"panel", "align a column of many symbols by date into one table", runPanel},
+		{"run", "run the jobs of a job file", runJobs},
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("quandl: ")
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			return run([]string{args[1], "-h"})
+		}
+		usage(os.Stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args[1:])
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case isUsage(err):
+			log.Println(err)
+			return exitUsage
+		default:
+			log.Println(err)
+			return exitError
+		}
+	}
+
+	log.Printf("unknown command %q\n", name)
+	usage(os.Stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: quandl <command> [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nrun 'quandl <command> -h' for the flags of a command\n")
+}
+
+// usageError is reported with exit code 2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+func isUsage(err error) bool {
+	var u *usageError
+	return errors.As(err, &u)
+}
+
 var (
-	api_key   string
-	datatype  string
-	dbcode    string
+	// the CLI logs at info unless -log_level says otherwise
+	logLevel = new(slog.LevelVar)
+	logger   = logging.New(os.Stderr, logLevel)
+)
+
+// options shared by the commands, registered per command as needed
+type options struct {
+	apiKey    string
+	dbCode    string
 	format    string
 	inputFile string
-	sector    string
 	path      string
+	sector    string
 	ticker    string
+	layout    string
 
-	err     error
-	tickers []string
-)
+	metricsAddr string
+	stats       metrics.Metrics
 
-func init() {
-	// Add api key
-	flag.StringVar(&api_key, "api_key", "$QUANDLAPIKEY", "-api_key=xyzABCD1234567890 add api key for data pull")
-	// 'data' is all we need right now
-	flag.StringVar(&datatype, "datatype", "data", "-datatype=metadata your two options are 'data' and 'metadata'.  Default is 'data'")
-	// WIKI is fully baked, need help with CB
-	flag.StringVar(&dbcode, "dbcode", "WIKI", "-dbcode=WIKI add dbcode you would like to query here")
-	// json option is fully baked
-	flag.StringVar(&format, "format", "json", "-format=json add the response format you would like here.  Options are 'json', xml' and 'csv'")
-	// Modified SP500 input file from
-	// https://pkgstore.datahub.io/core/s-and-p-500-companies/constituents_json/data/64dd3e9582b936b0352fdd826ecd3c95/constituents_json.json
-	flag.StringVar(&inputFile, "inputFile", "SP500.json", "-inputFile=")
-	// Select sector
-	flag.StringVar(&sector, "sector", "all", "-sector=all returns all symbols in list, or you can specify a sector to retrieve a subset of symbols 'Industrials', 'Health Care', 'Information Technology'")
-	// This is path to data files including input and output folders
-	flag.StringVar(&path, "path", "", "-path=")
-	// Optional input param.  This overwrites input file with multiple symbols if given.
-	flag.StringVar(&ticker, "ticker", "", "-ticker=FB input ticker symbol to retrieve data set")
+	rules         string
+	qualityReport string
+	validator     *quality.Validator
+
+	fields      string
+	fiscalStart int
+	enricher    *enrich.Enricher
+
+	freq string
+
+	indicatorList   string
+	indicatorPrices string
+	specs           []indicators.Spec
 }
 
-// sample input where $QUANDLAPIKEY is your api key and $GOPATH/src/github.com/twold/go-quandl/data
-// is where you have input file and is desired output location
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: quandl %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-// go run main.go -api_key=$QUANDLAPIKEY -path=$GOPATH/src/github.com/twold/go-quandl/data
+// parse reports flag errors as usage errors
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &usageError{msg: err.Error()}
+}
 
-func main() {
-	flag.Parse()
+func logFlag(fs *flag.FlagSet) {
+	fs.Func("log_level", "log level, 'debug', 'info', 'warn' or 'error' (default \"info\")", func(s string) error {
+		return logLevel.UnmarshalText([]byte(s))
+	})
+}
 
-	// create service using input format and data type
-	svc := api.New(&datatype, &dbcode, &format, &api_key)
+func (o *options) authFlags(fs *flag.FlagSet) {
+	logFlag(fs)
+	fs.StringVar(&o.apiKey, "api_key", os.Getenv("QUANDLAPIKEY"), "Quandl API key, defaults to $QUANDLAPIKEY")
+	fs.StringVar(&o.dbCode, "dbcode", "WIKI", "database code, e.g. 'WIKI' or 'CBOE'")
+}
 
-	// if individual ticker input is not given, read input file
-	if ticker == "" {
-		tickers, err = api.ReadInputList(path, inputFile, sector)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	} else {
-		tickers = append(tickers, ticker)
-	}
-
-	// range over inputs and retrieve data from API
-	for _, ticker := range tickers {
-		// pull data from API
-		resp, err := svc.Get(path, ticker)
-		if err != nil {
-			// ignore invlaid ticker properly formatted eerror message from quandl
-			if strings.Contains(err.Error(), "Quandl code. Please check your Quandl codes and try again") {
-				log.Printf("Remove ticker from list %+v.\n Error ignored: %+v\n", ticker, err)
-				continue
-			}
-			// ignore URL parsing errors due to invalid symbol
-			if strings.Contains(err.Error(), "We could not recognize the URL you requested") {
-				log.Printf("Remove ticker from list %+v.\n Error ignored: %+v\n", ticker, err)
-				continue
-			}
-			// Print error to std out and exit
-			log.Fatalln(err)
-		}
+func (o *options) metricsFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.metricsAddr, "metrics-addr", "", "serve /metrics and /debug/vars on this address, e.g. ':9100'")
+}
 
-		// response will be null if you chose "metadata" as your datatype
-		if resp != nil {
-
-			// ToDo: need switch on xml/json
-			// format json response
-			obj, err := json.MarshalIndent(resp.Data, "", "	")
-			if err != nil {
-				log.Fatal(err)
-			}
-			// output to stdout
-			fmt.Sprintf("%s\n", string(obj))
-		}
+// startMetrics serves metrics when -metrics-addr is given
+func (o *options) startMetrics() error {
+	if o.metricsAddr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", o.metricsAddr)
+	if err != nil {
+		return err
+	}
+
+	prom := metrics.NewPrometheus()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prom)
+	mux.Handle("/debug/vars", expvar.Handler())
+	go http.Serve(ln, mux)
+
+	o.stats = metrics.Multi(prom, metrics.NewExpvar("quandl"))
+	logger.Info("Serving metrics.", "addr", ln.Addr().String())
+	return nil
+}
+
+func (o *options) qualityFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.rules, "rules", "", "quality rule actions, e.g. 'low_above_high=drop,negative_volume=fail', see README")
+	fs.StringVar(&o.qualityReport, "quality_report", "", "save the quality report of the run as json to this file")
+}
+
+// startQuality checks fetched rows when rules or a report are asked for
+func (o *options) startQuality() error {
+	if o.rules == "" && o.qualityReport == "" {
+		return nil
+	}
+	rules, err := quality.ParseRules(o.rules)
+	if err == nil {
+		o.validator, err = quality.New(rules)
+	}
+	if err != nil {
+		return usagef("-rules: %v", err)
+	}
+	return nil
+}
+
+// saveQuality writes the quality report if one was asked for
+func (o *options) saveQuality() error {
+	if o.validator == nil || o.qualityReport == "" {
+		return nil
+	}
+	r := o.validator.Report()
+	if err := r.WriteFile(o.qualityReport); err != nil {
+		return err
+	}
+	logger.Info("Wrote quality report.", logging.File, o.qualityReport, logging.Rows, r.Rows, "dropped", r.Dropped, "failed", len(r.Failed))
+	return nil
+}
+
+func (o *options) enrichFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.fields, "enrich", "", "add derived date fields to each row, e.g. 'iso_week,month_end' or 'all', see README")
+	fs.IntVar(&o.fiscalStart, "fiscal_year_start", 1, "first month of the fiscal year of the fiscal_period field, 1 to 12")
+}
+
+// startEnrich derives the -enrich fields of fetched rows
+func (o *options) startEnrich() error {
+	if o.fields == "" {
+		return nil
+	}
+	fields, err := enrich.Parse(o.fields)
+	if err == nil {
+		o.enricher, err = enrich.New(enrich.Options{Fields: fields, FiscalYearStart: time.Month(o.fiscalStart)})
+	}
+	if err != nil {
+		return usagef("-enrich: %v", err)
+	}
+	return nil
+}
+
+func (o *options) resampleFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.freq, "resample", "", "resample daily rows to 'weekly', 'monthly', 'quarterly' or 'annual' bars")
+}
+
+// checkResample reports an unknown -resample frequency
+func (o *options) checkResample() error {
+	if o.freq == "" {
+		return nil
+	}
+	if _, _, err := resample.Period(o.freq, time.Time{}); err != nil {
+		return usagef("-resample: %v", err)
+	}
+	return nil
+}
+
+// resampled returns objs, rows of the database dbCode, as bars of the
+// -resample frequency dated by the database's calendar
+func (o *options) resampled(dbCode string, objs []api.Wiki) ([]api.Wiki, error) {
+	if o.freq == "" {
+		return objs, nil
+	}
+	return resample.Wiki(objs, o.freq, resample.Options{Calendar: calendar.ForDB(dbCode)})
+}
+
+func (o *options) indicatorFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.indicatorList, "indicators", "", "append technical indicators as columns, e.g. 'sma:50,rsi:14,macd', see README")
+	fs.StringVar(&o.indicatorPrices, "indicator_prices", "", "columns the indicators read, 'raw' or 'adjusted', defaults to adjusted when there are any")
+}
+
+// startIndicators parses the -indicators specs
+func (o *options) startIndicators() error {
+	switch o.indicatorPrices {
+	case "", indicators.Raw, indicators.Adjusted:
+	default:
+		return usagef("-indicator_prices: unknown prices %q, options are %s, %s", o.indicatorPrices, indicators.Raw, indicators.Adjusted)
+	}
+	specs, err := indicators.ParseList(o.indicatorList)
+	if err != nil {
+		return usagef("-indicators: %v", err)
+	}
+	o.specs = specs
+	return nil
+}
+
+// table returns objs as a table with the -indicators columns appended
+func (o *options) table(symbol string, objs []api.Wiki) (*api.Table, error) {
+	if len(o.specs) == 0 {
+		return api.WikiTable(symbol, objs), nil
+	}
+	return indicators.Wiki(symbol, objs, o.specs, indicators.Options{Prices: o.indicatorPrices})
+}
+
+func (o *options) pathFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "path", "", "data folder holding the input and output folders")
+}
+
+func (o *options) layoutFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.layout, "layout", "", "path template of partitioned files under <path>, or 'hive' for "+layout.Hive)
+}
+
+// parseLayout returns the -layout template, or nil for the default
+// output/<SYMBOL>/<date> files
+func (o *options) parseLayout() (*layout.Template, error) {
+	switch o.layout {
+	case "":
+		return nil, nil
+	case "hive":
+		return layout.Parse(layout.Hive)
+	}
+	t, err := layout.Parse(o.layout)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	return t, nil
+}
+
+// reader opens the data under -path in the -layout
+func (o *options) reader() (*store.Reader, error) {
+	t, err := o.parseLayout()
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return store.Open(o.path)
+	}
+	return store.OpenLayout(o.path, t)
+}
+
+func (o *options) symbolFlags(fs *flag.FlagSet) {
+	// Modified SP500 input file from
+	// https://pkgstore.datahub.io/core/s-and-p-500-companies/constituents_json/data/64dd3e9582b936b0352fdd826ecd3c95/constituents_json.json
+	fs.StringVar(&o.inputFile, "inputFile", "SP500.json", "input file under <path>/input listing symbols")
+	fs.StringVar(&o.sector, "sector", "all", "only use symbols of this sector, e.g. 'Industrials', 'Health Care'")
+	fs.StringVar(&o.ticker, "ticker", "", "single ticker symbol, overrides the input file")
+}
+
+// symbols returns the positional arguments, the -ticker flag or the input file symbols
+func (o *options) symbols(args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	if o.ticker != "" {
+		return []string{o.ticker}, nil
+	}
+	return api.ReadInputList(o.path, o.inputFile, o.sector)
+}
+
+func (o *options) service(dataType string, opts ...api.Option) api.Getter {
+	if o.format == "" {
+		o.format = endpoints.JSON
+	}
+	opts = append([]api.Option{api.Logger(logger), api.Metrics(o.stats)}, opts...)
+	if o.validator != nil {
+		opts = append(opts, api.Validate(o.validator))
+	}
+	if o.enricher != nil {
+		opts = append(opts, api.Enrich(o.enricher))
+	}
+	return api.New(&dataType, &o.dbCode, &o.format, &o.apiKey, opts...)
+}
+
+// ignorable reports Quandl errors caused by an invalid ticker
+func ignorable(err error) bool {
+	// ignore invlaid ticker properly formatted eerror message from quandl
+	if strings.Contains(err.Error(), "Quandl code. Please check your Quandl codes and try again") {
+		return true
 	}
+	// ignore URL parsing errors due to invalid symbol
+	return strings.Contains(err.Error(), "We could not recognize the URL you requested")
 }
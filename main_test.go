@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Main", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "main")
+		Expect(err).Should(BeNil())
+		log.SetOutput(ioutil.Discard)
+	})
+
+	AfterEach(func() {
+		log.SetOutput(os.Stderr)
+		os.RemoveAll(dir)
+	})
+
+	It("should dispatch commands and map their errors to exit codes", func() {
+		for _, c := range []struct {
+			args []string
+			code int
+		}{
+			{[]string{}, exitUsage},
+			{[]string{"nope"}, exitUsage},
+			{[]string{"help"}, exitOK},
+			{[]string{"help", "export"}, exitOK},
+			{[]string{"export", "-h"}, exitOK},
+			{[]string{"export", "-bogus"}, exitUsage},
+			{[]string{"export", "-to=xml"}, exitUsage},
+			{[]string{"export", "-to=sqlite"}, exitUsage},
+			{[]string{"compact", "-by=month"}, exitUsage},
+			{[]string{"meta"}, exitUsage},
+			{[]string{"search"}, exitUsage},
+			// runtime errors: there is no input file or saved data under dir
+			{[]string{"list-sectors", "-path=" + dir}, exitError},
+			{[]string{"export", "-path=" + dir}, exitError},
+		} {
+			Expect(run(c.args)).Should(Equal(c.code), "%v", c.args)
+		}
+	})
+
+	It("should tell usage errors from runtime errors", func() {
+		Expect(isUsage(usagef("bad flag %s", "-x"))).Should(BeTrue())
+		Expect(usagef("bad flag %s", "-x").Error()).Should(Equal("bad flag -x"))
+		Expect(isUsage(os.ErrNotExist)).Should(BeFalse())
+	})
+
+	It("should list the commands and their flags", func() {
+		var buf bytes.Buffer
+		usage(&buf)
+		for _, cmd := range commands {
+			Expect(buf.String()).Should(ContainSubstring(cmd.name))
+		}
+
+		buf.Reset()
+		fs := newFlagSet("export", "[SYMBOL...]")
+		fs.SetOutput(&buf)
+		fs.String("to", "csv", "export format")
+		fs.Usage()
+		Expect(buf.String()).Should(HavePrefix("usage: quandl export [flags] [SYMBOL...]"))
+		Expect(buf.String()).Should(ContainSubstring("-to"))
+	})
+})
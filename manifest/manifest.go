@@ -0,0 +1,182 @@
+// Package manifest keeps a per-symbol record of the files written to
+// output/<SYMBOL>/: rows, date range, size and SHA-256 of every file, plus the
+// source URL and fetch time.
+//
+// The manifest is replaced atomically once the files it lists are written, so
+// a file missing from it, or whose size or checksum differs, was left behind
+// by an interrupted run and must be written again.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/twold/go-quandl/atomicfile"
+)
+
+// Name of the manifest file in every symbol folder
+const Name = "manifest.json"
+
+type Manifest struct {
+	Symbol    string           `json:"symbol" type:"string"`
+	DBCode    string           `json:"db_code,omitempty" type:"string"`
+	Rows      int              `json:"rows" type:"int"`
+	FirstDate string           `json:"first_date,omitempty" type:"string"`
+	LastDate  string           `json:"last_date,omitempty" type:"string"`
+	SourceURL string           `json:"source_url,omitempty" type:"string"`
+	FetchedAt time.Time        `json:"fetched_at" type:"time"`
+	Files     map[string]Entry `json:"files" type:"map"`
+}
+
+// Entry describes one file, keyed by its name relative to the symbol folder
+type Entry struct {
+	Rows      int       `json:"rows" type:"int"`
+	FirstDate string    `json:"first_date" type:"string"`
+	LastDate  string    `json:"last_date" type:"string"`
+	Size      int64     `json:"size" type:"int64"`
+	SHA256    string    `json:"sha256" type:"string"`
+	FetchedAt time.Time `json:"fetched_at" type:"time"`
+}
+
+// Load reads the manifest in dir, returning an empty manifest if there is none
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{
+		Symbol: filepath.Base(dir),
+		Files:  map[string]Entry{},
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, Name))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(m.Symbol, b)
+}
+
+// Parse decodes the manifest of symbol, e.g. one read from a blob store
+func Parse(symbol string, b []byte) (*Manifest, error) {
+	m := &Manifest{Symbol: symbol}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	if m.Files == nil {
+		m.Files = map[string]Entry{}
+	}
+	return m, nil
+}
+
+// Save atomically replaces the manifest in dir
+func (m *Manifest) Save(dir string) error {
+	b, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(dir, Name), b, 0666)
+}
+
+// Marshal recomputes the totals and encodes the manifest
+func (m *Manifest) Marshal() ([]byte, error) {
+	m.summarize()
+	return json.MarshalIndent(m, "", "	")
+}
+
+// Add records a written file
+func (m *Manifest) Add(name string, e Entry) {
+	m.Files[name] = e
+}
+
+// Complete reports whether name in dir is recorded with its current size. It
+// does not read the file, see Verify.
+func (m *Manifest) Complete(dir, name string) bool {
+	e, ok := m.Files[name]
+	if !ok {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && fi.Size() == e.Size
+}
+
+// Verify reports whether name in dir is recorded with its current size and
+// checksum. It reads the whole file.
+func (m *Manifest) Verify(dir, name string) bool {
+	e, ok := m.Files[name]
+	if !ok {
+		return false
+	}
+	size, sum, err := SumFile(filepath.Join(dir, name))
+	return err == nil && size == e.Size && sum == e.SHA256
+}
+
+// Covered returns a func reporting whether a date lies within a recorded
+// file of several rows, e.g. a compacted one, that is present in dir
+func (m *Manifest) Covered(dir string) func(date string) bool {
+	spans := []Entry{}
+	for name, e := range m.Files {
+		if e.Rows > 1 && m.Complete(dir, name) {
+			spans = append(spans, e)
+		}
+	}
+	return func(date string) bool {
+		for _, e := range spans {
+			if e.FirstDate <= date && date <= e.LastDate {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Names returns the recorded file names in order
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summarize recomputes the row count and date range from the files
+func (m *Manifest) summarize() {
+	m.Rows, m.FirstDate, m.LastDate = 0, "", ""
+	for _, e := range m.Files {
+		m.Rows += e.Rows
+		if e.FirstDate != "" && (m.FirstDate == "" || e.FirstDate < m.FirstDate) {
+			m.FirstDate = e.FirstDate
+		}
+		if e.LastDate > m.LastDate {
+			m.LastDate = e.LastDate
+		}
+	}
+}
+
+// Sum returns the hex encoded SHA-256 of b
+func Sum(b []byte) string {
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
+
+// SumFile returns the size and hex encoded SHA-256 of the file name
+func SumFile(name string) (int64, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,173 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/manifest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	var (
+		dir string
+		man *Manifest
+	)
+
+	// write saves b as name in dir and records it
+	write := func(name string, b []byte, e Entry) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), b, 0666)).Should(Succeed())
+		e.Size, e.SHA256 = int64(len(b)), Sum(b)
+		man.Add(name, e)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "FB")
+		Expect(err).Should(BeNil())
+		man, err = Load(dir)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("starts empty without a manifest", func() {
+		Expect(man.Symbol).Should(Equal(filepath.Base(dir)))
+		Expect(man.Files).Should(BeEmpty())
+		Expect(man.Names()).Should(BeEmpty())
+	})
+
+	It("saves and loads the files with their totals", func() {
+		fetched := time.Date(2018, 1, 9, 12, 0, 0, 0, time.UTC)
+		man.DBCode, man.FetchedAt = "WIKI", fetched
+		write("2018-01-03.json", []byte(`{"Close": 2}`), Entry{Rows: 1, FirstDate: "2018-01-03", LastDate: "2018-01-03"})
+		write("2018-01-02.json", []byte(`{"Close": 1}`), Entry{Rows: 1, FirstDate: "2018-01-02", LastDate: "2018-01-02"})
+		write("2017.json", []byte(`[]`), Entry{Rows: 250, FirstDate: "2017-01-03", LastDate: "2017-12-29"})
+		Expect(man.Save(dir)).Should(Succeed())
+
+		actual, err := Load(dir)
+		Expect(err).Should(BeNil())
+		Expect(actual.Names()).Should(Equal([]string{"2017.json", "2018-01-02.json", "2018-01-03.json"}))
+		Expect(actual.Rows).Should(Equal(252))
+		Expect(actual.FirstDate).Should(Equal("2017-01-03"))
+		Expect(actual.LastDate).Should(Equal("2018-01-03"))
+		Expect(actual.DBCode).Should(Equal("WIKI"))
+		Expect(actual.FetchedAt.Equal(fetched)).Should(BeTrue())
+		Expect(actual.Files["2018-01-02.json"]).Should(Equal(man.Files["2018-01-02.json"]))
+	})
+
+	It("parses a manifest read elsewhere", func() {
+		actual, err := Parse("FB", []byte(`{"rows": 1, "files": {"2018-01-02.json": {"rows": 1, "size": 12, "sha256": "ab"}}}`))
+		Expect(err).Should(BeNil())
+		Expect(actual.Symbol).Should(Equal("FB"))
+		Expect(actual.Files["2018-01-02.json"].Size).Should(Equal(int64(12)))
+
+		actual, err = Parse("FB", []byte(`{}`))
+		Expect(err).Should(BeNil())
+		Expect(actual.Files).ShouldNot(BeNil())
+
+		_, err = Parse("FB", []byte(`{"rows": `))
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	Context("When checking files", func() {
+		const name = "2018-01-02.json"
+
+		BeforeEach(func() {
+			write(name, []byte(`{"Close": 1}`), Entry{Rows: 1})
+		})
+
+		It("accepts a recorded file", func() {
+			Expect(man.Complete(dir, name)).Should(BeTrue())
+			Expect(man.Verify(dir, name)).Should(BeTrue())
+		})
+
+		It("rejects a file that is not recorded or missing", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "2018-01-03.json"), nil, 0666)).Should(Succeed())
+			Expect(man.Complete(dir, "2018-01-03.json")).Should(BeFalse())
+			Expect(man.Verify(dir, "2018-01-03.json")).Should(BeFalse())
+			Expect(os.Remove(filepath.Join(dir, name))).Should(Succeed())
+			Expect(man.Complete(dir, name)).Should(BeFalse())
+			Expect(man.Verify(dir, name)).Should(BeFalse())
+		})
+
+		It("rejects a file whose size differs", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(`{"Clo`), 0666)).Should(Succeed())
+			Expect(man.Complete(dir, name)).Should(BeFalse())
+			Expect(man.Verify(dir, name)).Should(BeFalse())
+		})
+
+		It("only verifies the checksum on request", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(`{"Close": 9}`), 0666)).Should(Succeed())
+			Expect(man.Complete(dir, name)).Should(BeTrue())
+			Expect(man.Verify(dir, name)).Should(BeFalse())
+		})
+
+		It("reports the dates of complete files of several rows as covered", func() {
+			write("2017.json", []byte(`[1, 2]`), Entry{Rows: 2, FirstDate: "2017-01-03", LastDate: "2017-12-29"})
+			write("2016.json", []byte(`[1, 2]`), Entry{Rows: 2, FirstDate: "2016-01-04", LastDate: "2016-12-30"})
+			Expect(ioutil.WriteFile(filepath.Join(dir, "2016.json"), []byte(`[1, `), 0666)).Should(Succeed())
+
+			covered := man.Covered(dir)
+			Expect(covered("2017-06-01")).Should(BeTrue())
+			Expect(covered("2017-12-29")).Should(BeTrue())
+			Expect(covered("2018-01-02")).Should(BeFalse())
+			Expect(covered("2016-06-01")).Should(BeFalse())
+		})
+	})
+
+	Context("When the JSON sink finds files left by an interrupted run", func() {
+		var (
+			path string
+			src  = api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: time.Now()}
+		)
+
+		BeforeEach(func() {
+			path = dir
+			dir = filepath.Join(path, "output", "FB")
+			sink := &api.JSONFiles{Path: path}
+			Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1)})).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(path)
+		})
+
+		It("rewrites a truncated file that is not recorded", func() {
+			name := filepath.Join(dir, "2018-01-03.json")
+			Expect(ioutil.WriteFile(name, []byte(`{"Da`), 0666)).Should(Succeed())
+
+			sink := &api.JSONFiles{Path: path}
+			Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-03", 2)})).Should(Succeed())
+
+			actual, err := Load(dir)
+			Expect(err).Should(BeNil())
+			Expect(actual.Names()).Should(Equal([]string{"2018-01-02.json", "2018-01-03.json"}))
+			Expect(actual.Complete(dir, "2018-01-03.json")).Should(BeTrue())
+			b, err := ioutil.ReadFile(name)
+			Expect(err).Should(BeNil())
+			Expect(string(b)).Should(ContainSubstring(`"Close": 2`))
+		})
+
+		It("rewrites a recorded file that was truncated", func() {
+			name := filepath.Join(dir, "2018-01-02.json")
+			b, err := ioutil.ReadFile(name)
+			Expect(err).Should(BeNil())
+			Expect(ioutil.WriteFile(name, b[:len(b)/2], 0666)).Should(Succeed())
+
+			sink := &api.JSONFiles{Path: path}
+			Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1)})).Should(Succeed())
+			actual, err := ioutil.ReadFile(name)
+			Expect(err).Should(BeNil())
+			Expect(actual).Should(Equal(b))
+		})
+	})
+})
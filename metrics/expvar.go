@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Expvar publishes metrics as an expvar map, served by expvar's /debug/vars
+// handler. Counters are keyed by their labels, histograms record count and sum.
+type Expvar struct {
+	root *expvar.Map
+	mu   sync.Mutex
+}
+
+// NewExpvar publishes the metrics under name, reusing the map if name is
+// already published
+func NewExpvar(name string) *Expvar {
+	if v, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &Expvar{root: v}
+	}
+	return &Expvar{root: expvar.NewMap(name)}
+}
+
+func (e *Expvar) Add(name string, delta float64, labels ...string) {
+	e.series(name).AddFloat(key(labels), delta)
+}
+
+func (e *Expvar) Observe(name string, value float64, labels ...string) {
+	k := key(labels)
+	if k != "" {
+		k += ","
+	}
+	m := e.series(name)
+	m.AddFloat(k+"count", 1)
+	m.AddFloat(k+"sum", value)
+}
+
+func (e *Expvar) series(name string) *expvar.Map {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if m, ok := e.root.Get(name).(*expvar.Map); ok {
+		return m
+	}
+	m := new(expvar.Map).Init()
+	e.root.Set(name, m)
+	return m
+}
+
+// key formats key/value pairs as key=value,...
+func key(labels []string) string {
+	s := ""
+	for i := 0; i+1 < len(labels); i += 2 {
+		if s != "" {
+			s += ","
+		}
+		s += labels[i] + "=" + labels[i+1]
+	}
+	return s
+}
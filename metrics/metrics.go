@@ -0,0 +1,88 @@
+// Package metrics records counters and histograms for requests, rows and
+// storage. Expvar publishes them under /debug/vars and Prometheus serves them
+// in the Prometheus text format.
+//
+// Labels are alternating key/value pairs, e.g.
+//
+//	m.Add(metrics.Requests, 1, metrics.DB, "WIKI", metrics.Status, "200")
+package metrics
+
+// Metric names
+const (
+	Requests             = "quandl_requests_total"
+	RequestSeconds       = "quandl_request_duration_seconds"
+	Retries              = "quandl_retries_total"
+	RateLimitWaits       = "quandl_rate_limit_waits_total"
+	RateLimitWaitSeconds = "quandl_rate_limit_wait_seconds"
+	RowsDecoded          = "quandl_rows_decoded_total"
+	BytesDownloaded      = "quandl_downloaded_bytes_total"
+	FilesWritten         = "quandl_files_written_total"
+	RowsRevised          = "quandl_rows_revised_total"
+	RowsDropped          = "quandl_rows_dropped_total"
+)
+
+// Label keys
+const (
+	DB     = "db"
+	Status = "status"
+	Sink   = "sink"
+)
+
+// Help describes the metrics recorded by the library
+var Help = map[string]string{
+	Requests:             "Quandl API requests by database and HTTP status.",
+	RequestSeconds:       "Latency of Quandl API requests in seconds.",
+	Retries:              "Requests resent after a failure.",
+	RateLimitWaits:       "Requests delayed after a 429 Too Many Requests response.",
+	RateLimitWaitSeconds: "Time spent waiting after 429 responses in seconds.",
+	RowsDecoded:          "Data set rows decoded from API responses.",
+	BytesDownloaded:      "Bytes read from API responses.",
+	FilesWritten:         "Files written by sinks.",
+	RowsRevised:          "Saved rows restated by a later fetch.",
+	RowsDropped:          "Decoded rows dropped by quality rules.",
+}
+
+// DefaultBuckets are the histogram upper bounds in seconds
+var DefaultBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+type Metrics interface {
+	// Add increments the counter name by delta
+	Add(name string, delta float64, labels ...string)
+	// Observe records value in the histogram name
+	Observe(name string, value float64, labels ...string)
+}
+
+// Discard drops every value, it is the default for library use
+var Discard Metrics = discard{}
+
+// OrDiscard returns m, or Discard when m is nil
+func OrDiscard(m Metrics) Metrics {
+	if m == nil {
+		return Discard
+	}
+	return m
+}
+
+// Multi records every value in each of ms
+func Multi(ms ...Metrics) Metrics {
+	return multi(ms)
+}
+
+type discard struct{}
+
+func (discard) Add(name string, delta float64, labels ...string)     {}
+func (discard) Observe(name string, value float64, labels ...string) {}
+
+type multi []Metrics
+
+func (m multi) Add(name string, delta float64, labels ...string) {
+	for _, mm := range m {
+		mm.Add(name, delta, labels...)
+	}
+}
+
+func (m multi) Observe(name string, value float64, labels ...string) {
+	for _, mm := range m {
+		mm.Observe(name, value, labels...)
+	}
+}
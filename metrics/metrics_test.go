@@ -0,0 +1,44 @@
+package metrics_test
+
+import (
+	"expvar"
+	"strings"
+
+	. "github.com/twold/go-quandl/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prometheus", func() {
+	Context("When I record counters and histograms", func() {
+		It("writes them in the text exposition format", func() {
+			p := NewPrometheus()
+			p.Buckets = []float64{0.1, 1}
+			p.Add(Requests, 1, DB, "WIKI", Status, "200")
+			p.Add(Requests, 2, DB, "WIKI", Status, "200")
+			p.Observe(RequestSeconds, 0.5, DB, "WIKI")
+
+			var b strings.Builder
+			_, err := p.WriteTo(&b)
+			Expect(err).Should(BeNil())
+			Expect(b.String()).Should(ContainSubstring("# TYPE quandl_requests_total counter\n"))
+			Expect(b.String()).Should(ContainSubstring(`quandl_requests_total{db="WIKI",status="200"} 3` + "\n"))
+			Expect(b.String()).Should(ContainSubstring(`quandl_request_duration_seconds_bucket{db="WIKI",le="0.1"} 0` + "\n"))
+			Expect(b.String()).Should(ContainSubstring(`quandl_request_duration_seconds_bucket{db="WIKI",le="1"} 1` + "\n"))
+			Expect(b.String()).Should(ContainSubstring(`quandl_request_duration_seconds_bucket{db="WIKI",le="+Inf"} 1` + "\n"))
+			Expect(b.String()).Should(ContainSubstring(`quandl_request_duration_seconds_count{db="WIKI"} 1` + "\n"))
+		})
+	})
+})
+
+var _ = Describe("Expvar", func() {
+	Context("When I record a counter", func() {
+		It("publishes it under the given name", func() {
+			e := NewExpvar("quandl_test")
+			e.Add(RowsDecoded, 10, DB, "WIKI")
+			e.Add(RowsDecoded, 5, DB, "WIKI")
+			Expect(expvar.Get("quandl_test").String()).Should(ContainSubstring(`"db=WIKI": 15`))
+		})
+	})
+})
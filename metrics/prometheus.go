@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prometheus keeps metrics in memory and serves them in the Prometheus text
+// exposition format
+type Prometheus struct {
+	Buckets []float64
+
+	mu       sync.Mutex
+	counters map[string]map[string]float64
+	hists    map[string]map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		Buckets:  DefaultBuckets,
+		counters: map[string]map[string]float64{},
+		hists:    map[string]map[string]*histogram{},
+	}
+}
+
+func (p *Prometheus) Add(name string, delta float64, labels ...string) {
+	key := labelString(labels)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counters[name] == nil {
+		p.counters[name] = map[string]float64{}
+	}
+	p.counters[name][key] += delta
+}
+
+func (p *Prometheus) Observe(name string, value float64, labels ...string) {
+	key := labelString(labels)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.hists[name] == nil {
+		p.hists[name] = map[string]*histogram{}
+	}
+	h := p.hists[name][key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.Buckets))}
+		p.hists[name][key] = h
+	}
+	for i, b := range p.Buckets {
+		if value <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// WriteTo writes every metric in the text exposition format
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(p.counters) {
+		header(&b, name, "counter")
+		series := p.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(&b, "%s%s %s\n", name, braces(key), formatFloat(series[key]))
+		}
+	}
+
+	for _, name := range sortedKeys(p.hists) {
+		header(&b, name, "histogram")
+		series := p.hists[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			for i, bound := range p.Buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(key, `le="`+formatFloat(bound)+`"`)), h.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(key, `le="+Inf"`)), h.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braces(key), formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(key), h.count)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics, e.g. on /metrics
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	p.WriteTo(w)
+}
+
+func header(b *strings.Builder, name, typ string) {
+	if help, ok := Help[name]; ok {
+		fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, typ)
+}
+
+// labelString formats key/value pairs as key="value",...
+func labelString(labels []string) string {
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func braces(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "{" + key + "}"
+}
+
+func join(key, label string) string {
+	if key == "" {
+		return label
+	}
+	return key + "," + label
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
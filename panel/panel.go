@@ -0,0 +1,328 @@
+// Package panel aligns one column of many symbols by date into a wide table,
+// a row per date and a column per symbol, e.g. the adjusted closes of every
+// symbol of a sector for cross-sectional work.
+//
+// An outer join keeps every date any symbol has, an inner join only the dates
+// every symbol has a value on. Gaps left by an outer join stay empty, are
+// filled with the last value before them, or are interpolated linearly in
+// calendar days between the values around them; gaps before the first or
+// after the last value of a symbol are never filled.
+package panel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/calendar"
+
+	"github.com/xitongsys/parquet-go/ParquetFile"
+	"github.com/xitongsys/parquet-go/ParquetWriter"
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+// Joins
+const (
+	Outer = "outer"
+	Inner = "inner"
+)
+
+// Fills
+const (
+	None        = "none"
+	Forward     = "ffill"
+	Interpolate = "interpolate"
+)
+
+// Options configures a panel
+type Options struct {
+	// Column names the column of each symbol, as in api.WikiColumns or as
+	// Quandl names it, e.g. AdjClose or "Adj. Close". AdjClose if empty.
+	Column string
+	// Join is Outer or Inner, Outer if empty
+	Join string
+	// Fill is None, Forward or Interpolate, None if empty
+	Fill string
+}
+
+// Panel holds a column of many symbols aligned by date
+type Panel struct {
+	Column  string
+	Symbols []string
+	// Dates are ordered YYYY-MM-DD dates
+	Dates []string
+	// Values holds a row per date with a value per symbol, nil if the
+	// symbol has none
+	Values [][]*float64
+}
+
+// New returns the panel of the tables of the symbols, e.g. from WikiTable or
+// DataSetTable, in the order of tables. A table without a value of the column
+// on any date is an empty column of an outer join and leaves an inner join
+// without dates. Symbols must name distinct columns, ignoring case, once dots
+// become underscores; Date and Symbol, the symbol column of arrow files, are
+// taken.
+func New(tables []*api.Table, o Options) (*Panel, error) {
+	if o.Column == "" {
+		o.Column = "AdjClose"
+	}
+	switch o.Join {
+	case "":
+		o.Join = Outer
+	case Outer, Inner:
+	default:
+		return nil, fmt.Errorf("unknown join %q, options are %s, %s", o.Join, Outer, Inner)
+	}
+	switch o.Fill {
+	case "", None, Forward, Interpolate:
+	default:
+		return nil, fmt.Errorf("unknown fill %q, options are %s, %s, %s", o.Fill, None, Forward, Interpolate)
+	}
+
+	p := &Panel{Column: o.Column, Symbols: make([]string, 0, len(tables))}
+	byDate := map[string][]*float64{}
+	taken := map[string]string{"date": "Date", "symbol": "Symbol"}
+	for n, t := range tables {
+		col := column(t, o.Column)
+		if col < 0 {
+			return nil, fmt.Errorf("%s: no column %q", t.Symbol, o.Column)
+		}
+		name := strings.ToLower(columnName(t.Symbol))
+		if other, ok := taken[name]; ok {
+			return nil, fmt.Errorf("%s: column name collides with %s", t.Symbol, other)
+		}
+		taken[name] = t.Symbol
+		p.Symbols = append(p.Symbols, t.Symbol)
+		for _, row := range t.Rows {
+			if len(row) != len(t.Columns) {
+				return nil, fmt.Errorf("%s: row has %d values, want %d", t.Symbol, len(row), len(t.Columns))
+			}
+			date, _ := row[0].(string)
+			v, ok := row[col].(float64)
+			if date == "" || !ok {
+				continue
+			}
+			if byDate[date] == nil {
+				byDate[date] = make([]*float64, len(tables))
+			}
+			byDate[date][n] = &v
+		}
+	}
+
+	for date, values := range byDate {
+		if o.Join == Inner && !full(values) {
+			continue
+		}
+		p.Dates = append(p.Dates, date)
+	}
+	sort.Strings(p.Dates)
+	p.Values = make([][]*float64, len(p.Dates))
+	for i, date := range p.Dates {
+		p.Values[i] = byDate[date]
+	}
+
+	switch o.Fill {
+	case Forward:
+		p.forward()
+	case Interpolate:
+		if err := p.interpolate(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// FromWiki returns the panel of the rows of symbols
+func FromWiki(symbols []string, rows map[string][]api.Wiki, o Options) (*Panel, error) {
+	tables := make([]*api.Table, 0, len(symbols))
+	for _, symbol := range symbols {
+		tables = append(tables, api.WikiTable(symbol, rows[symbol]))
+	}
+	return New(tables, o)
+}
+
+// Load returns the panel of symbols, e.g. from api.ReadInputList, reading
+// the rows of each with load, e.g. Reader.Load of the store package
+func Load(symbols []string, load func(symbol string) ([]api.Wiki, error), o Options) (*Panel, error) {
+	rows := make(map[string][]api.Wiki, len(symbols))
+	for _, symbol := range symbols {
+		objs, err := load(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", symbol, err)
+		}
+		rows[symbol] = objs
+	}
+	return FromWiki(symbols, rows, o)
+}
+
+// forward fills each gap with the last value before it
+func (p *Panel) forward() {
+	for s := range p.Symbols {
+		var last *float64
+		for _, values := range p.Values {
+			if values[s] == nil {
+				values[s] = last
+			}
+			last = values[s]
+		}
+	}
+}
+
+// interpolate fills each gap between two values linearly in calendar days
+func (p *Panel) interpolate() error {
+	days := make([]float64, len(p.Dates))
+	for i, date := range p.Dates {
+		d, err := calendar.Parse(date)
+		if err != nil {
+			return err
+		}
+		days[i] = float64(d.Unix() / 86400)
+	}
+	for s := range p.Symbols {
+		prev := -1
+		for i, values := range p.Values {
+			if values[s] == nil {
+				continue
+			}
+			if prev >= 0 && i-prev > 1 {
+				from, to := *p.Values[prev][s], *values[s]
+				for j := prev + 1; j < i; j++ {
+					v := from + (to-from)*(days[j]-days[prev])/(days[i]-days[prev])
+					p.Values[j][s] = &v
+				}
+			}
+			prev = i
+		}
+	}
+	return nil
+}
+
+// Table returns the panel as a table of a Date column and a column per
+// symbol, its Symbol being the panel's column
+func (p *Panel) Table() *api.Table {
+	t := &api.Table{
+		Symbol: p.Column, DBCode: "PANEL",
+		Columns: append([]string{"Date"}, p.Symbols...),
+		Rows:    make([][]interface{}, len(p.Dates)),
+	}
+	for i, date := range p.Dates {
+		row := []interface{}{date}
+		for _, v := range p.Values[i] {
+			if v == nil {
+				row = append(row, nil)
+			} else {
+				row = append(row, *v)
+			}
+		}
+		t.Rows[i] = row
+	}
+	return t
+}
+
+// WriteCSV writes the panel with a header of Date and the symbols, missing
+// values being empty
+func (p *Panel) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"Date"}, p.Symbols...)); err != nil {
+		return err
+	}
+	for i, date := range p.Dates {
+		rec := []string{date}
+		for _, v := range p.Values[i] {
+			s := ""
+			if v != nil {
+				s = strconv.FormatFloat(*v, 'f', -1, 64)
+			}
+			rec = append(rec, s)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteParquet writes the panel to the parquet file name, with a UTF8 Date
+// column and an optional DOUBLE column per symbol. Dots in symbols, path
+// separators in parquet, become underscores.
+func (p *Panel) WriteParquet(name string) error {
+	md := []string{"name=Date, type=UTF8, repetitiontype=REQUIRED"}
+	for _, symbol := range p.Symbols {
+		md = append(md, fmt.Sprintf("name=%s, type=DOUBLE, repetitiontype=OPTIONAL", columnName(symbol)))
+	}
+
+	fw, err := ParquetFile.NewLocalFileWriter(name)
+	if err != nil {
+		return err
+	}
+	pw, err := ParquetWriter.NewCSVWriter(md, fw, 4)
+	if err != nil {
+		fw.Close()
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for i, date := range p.Dates {
+		rec := []interface{}{date}
+		for _, v := range p.Values[i] {
+			if v == nil {
+				rec = append(rec, nil)
+			} else {
+				rec = append(rec, *v)
+			}
+		}
+		if err = pw.Write(rec); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = pw.WriteStop()
+	}
+	if cerr := fw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// columnName returns the parquet column of symbol
+func columnName(symbol string) string {
+	return strings.Replace(symbol, ".", "_", -1)
+}
+
+// column returns the index of the column of t named name, ignoring case,
+// spaces, dots, dashes and underscores, or -1
+func column(t *api.Table, name string) int {
+	want := squash(name)
+	for i, c := range t.Columns {
+		if squash(c) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+func squash(name string) string {
+	out := []rune{}
+	for _, r := range strings.ToLower(name) {
+		switch r {
+		case ' ', '.', '-', '_':
+		default:
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
+
+func full(values []*float64) bool {
+	for _, v := range values {
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,13 @@
+package panel_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPanel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Panel Suite")
+}
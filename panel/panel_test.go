@@ -0,0 +1,213 @@
+package panel_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/xitongsys/parquet-go/ParquetFile"
+	"github.com/xitongsys/parquet-go/ParquetReader"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/arrowsink"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/panel"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// row is a row of the parquet file of a panel of AAPL and BF.B
+type row struct {
+	Date string   `parquet:"name=Date, type=UTF8, repetitiontype=REQUIRED"`
+	AAPL *float64 `parquet:"name=AAPL, type=DOUBLE, repetitiontype=OPTIONAL"`
+	BF_B *float64 `parquet:"name=BF_B, type=DOUBLE, repetitiontype=OPTIONAL"`
+}
+
+// rows returns WIKI rows of the adjusted closes by date, newest first
+func rows(closes map[string]float64) []api.Wiki {
+	objs := []api.Wiki{}
+	for date, c := range closes {
+		date := date
+		objs = append(objs, api.Wiki{Date: &date, Close: fixture.Float(2 * c), AdjClose: fixture.Float(c)})
+	}
+	return objs
+}
+
+var _ = Describe("Panel", func() {
+	// 2018-01-05 is a Friday, 2018-01-08 the Monday after
+	data := map[string][]api.Wiki{
+		"AAPL": rows(map[string]float64{"2018-01-04": 10, "2018-01-05": 11, "2018-01-08": 14, "2018-01-09": 15}),
+		"FB":   rows(map[string]float64{"2018-01-04": 20, "2018-01-09": 24}),
+		"MSFT": rows(map[string]float64{"2018-01-05": 30, "2018-01-08": 31}),
+	}
+	symbols := []string{"AAPL", "FB", "MSFT"}
+
+	values := func(p *Panel, symbol string) []interface{} {
+		out := []interface{}{}
+		for s, name := range p.Symbols {
+			if name != symbol {
+				continue
+			}
+			for _, row := range p.Values {
+				if row[s] == nil {
+					out = append(out, nil)
+				} else {
+					out = append(out, *row[s])
+				}
+			}
+		}
+		return out
+	}
+
+	It("should join the symbols on every date", func() {
+		p, err := FromWiki(symbols, data, Options{})
+		Expect(err).Should(BeNil())
+		Expect(p.Column).Should(Equal("AdjClose"))
+		Expect(p.Symbols).Should(Equal(symbols))
+		Expect(p.Dates).Should(Equal([]string{"2018-01-04", "2018-01-05", "2018-01-08", "2018-01-09"}))
+		Expect(values(p, "FB")).Should(Equal([]interface{}{20.0, nil, nil, 24.0}))
+		Expect(values(p, "MSFT")).Should(Equal([]interface{}{nil, 30.0, 31.0, nil}))
+	})
+
+	It("should keep the dates every symbol has with an inner join", func() {
+		p, err := FromWiki(symbols[:2], data, Options{Join: Inner, Column: "Close"})
+		Expect(err).Should(BeNil())
+		Expect(p.Dates).Should(Equal([]string{"2018-01-04", "2018-01-09"}))
+		Expect(values(p, "AAPL")).Should(Equal([]interface{}{20.0, 30.0}))
+
+		p, err = FromWiki(symbols, data, Options{Join: Inner})
+		Expect(err).Should(BeNil())
+		Expect(p.Dates).Should(BeEmpty())
+	})
+
+	It("should fill the gaps between values", func() {
+		p, err := FromWiki(symbols, data, Options{Fill: Forward})
+		Expect(err).Should(BeNil())
+		Expect(values(p, "FB")).Should(Equal([]interface{}{20.0, 20.0, 20.0, 24.0}))
+		Expect(values(p, "MSFT")).Should(Equal([]interface{}{nil, 30.0, 31.0, 31.0}))
+
+		// Friday is 1 of 5 days after Thursday, Monday 4
+		p, err = FromWiki(symbols, data, Options{Fill: Interpolate})
+		Expect(err).Should(BeNil())
+		fb := values(p, "FB")
+		Expect(fb[1]).Should(BeNumerically("~", 20.8, 1e-9))
+		Expect(fb[2]).Should(BeNumerically("~", 23.2, 1e-9))
+		Expect(values(p, "MSFT")).Should(Equal([]interface{}{nil, 30.0, 31.0, nil}))
+	})
+
+	It("should find columns by their Quandl names", func() {
+		t := &api.Table{Symbol: "AAPL", Columns: []string{"Date", "Adj. Close"}, Rows: [][]interface{}{{"2018-01-04", 1.0}}}
+		p, err := New([]*api.Table{t}, Options{Column: "adj_close"})
+		Expect(err).Should(BeNil())
+		Expect(*p.Values[0][0]).Should(Equal(1.0))
+
+		_, err = New([]*api.Table{t}, Options{Column: "Settle"})
+		Expect(err).ShouldNot(BeNil())
+		_, err = New([]*api.Table{t}, Options{Join: "left"})
+		Expect(err).ShouldNot(BeNil())
+		_, err = New([]*api.Table{t}, Options{Fill: "backward"})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should load the symbols", func() {
+		p, err := Load(symbols, func(symbol string) ([]api.Wiki, error) { return data[symbol], nil }, Options{})
+		Expect(err).Should(BeNil())
+		Expect(p.Dates).Should(HaveLen(4))
+
+		_, err = Load(symbols, func(symbol string) ([]api.Wiki, error) { return nil, os.ErrNotExist }, Options{})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should write tables, csv and parquet", func() {
+		p, err := FromWiki(symbols, data, Options{})
+		Expect(err).Should(BeNil())
+
+		t := p.Table()
+		Expect(t.Symbol).Should(Equal("AdjClose"))
+		Expect(t.Columns).Should(Equal([]string{"Date", "AAPL", "FB", "MSFT"}))
+		Expect(t.Rows[1]).Should(Equal([]interface{}{"2018-01-05", 11.0, nil, 30.0}))
+
+		var buf bytes.Buffer
+		Expect(p.WriteCSV(&buf)).Should(Succeed())
+		Expect(buf.String()).Should(HavePrefix("Date,AAPL,FB,MSFT\n2018-01-04,10,20,\n"))
+
+	})
+
+	It("should write parquet files with a column per symbol", func() {
+		p, err := FromWiki([]string{"AAPL", "BF.B"}, map[string][]api.Wiki{"AAPL": data["AAPL"], "BF.B": data["FB"]}, Options{})
+		Expect(err).Should(BeNil())
+
+		dir, err := ioutil.TempDir("", "panel")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+		name := filepath.Join(dir, "panel.parquet")
+		Expect(p.WriteParquet(name)).Should(Succeed())
+
+		fr, err := ParquetFile.NewLocalFileReader(name)
+		Expect(err).Should(BeNil())
+		defer fr.Close()
+		pr, err := ParquetReader.NewParquetReader(fr, new(row), 4)
+		Expect(err).Should(BeNil())
+		defer pr.ReadStop()
+		got := make([]row, pr.GetNumRows())
+		Expect(pr.Read(&got)).Should(Succeed())
+
+		Expect(got).Should(HaveLen(4))
+		for i, date := range p.Dates {
+			Expect(got[i].Date).Should(Equal(date))
+		}
+		Expect(*got[1].AAPL).Should(Equal(11.0))
+		Expect(*got[0].BF_B).Should(Equal(20.0))
+		Expect(got[1].BF_B).Should(BeNil())
+		Expect(got[2].BF_B).Should(BeNil())
+		Expect(*got[3].BF_B).Should(Equal(24.0))
+	})
+
+	It("should write arrow streams of the table", func() {
+		p, err := FromWiki(symbols, data, Options{})
+		Expect(err).Should(BeNil())
+
+		var buf bytes.Buffer
+		Expect(arrowsink.WriteStream(&buf, []*api.Table{p.Table()})).Should(Succeed())
+		r, err := ipc.NewReader(&buf)
+		Expect(err).Should(BeNil())
+		defer r.Release()
+		Expect(r.Next()).Should(BeTrue())
+		rec := r.Record()
+
+		names := []string{}
+		for _, field := range rec.Schema().Fields() {
+			names = append(names, field.Name)
+		}
+		Expect(names).Should(Equal([]string{"Symbol", "Date", "AAPL", "FB", "MSFT"}))
+		Expect(rec.NumRows()).Should(Equal(int64(4)))
+		Expect(rec.Column(0).(*array.String).Value(0)).Should(Equal("AdjClose"))
+		day := time.Date(2018, 1, 4, 0, 0, 0, 0, time.UTC).Unix() / 86400
+		Expect(rec.Column(1).(*array.Date32).Value(0)).Should(Equal(arrow.Date32(day)))
+
+		fb := rec.Column(3).(*array.Float64)
+		Expect(fb.Value(0)).Should(Equal(20.0))
+		Expect(fb.IsNull(1)).Should(BeTrue())
+		Expect(fb.IsNull(2)).Should(BeTrue())
+		Expect(fb.Value(3)).Should(Equal(24.0))
+		Expect(r.Next()).Should(BeFalse())
+	})
+
+	It("should reject symbols naming the same column", func() {
+		table := func(symbol string) *api.Table {
+			return api.WikiTable(symbol, data["AAPL"])
+		}
+		for _, pair := range [][]string{{"AAPL", "AAPL"}, {"BF.B", "BF_B"}, {"AAPL", "aapl"}, {"AAPL", "Date"}, {"AAPL", "Symbol"}} {
+			_, err := New([]*api.Table{table(pair[0]), table(pair[1])}, Options{})
+			Expect(err).ShouldNot(BeNil(), pair[1])
+		}
+		_, err := New([]*api.Table{table("BF.A"), table("BF.B")}, Options{})
+		Expect(err).Should(BeNil())
+	})
+})
@@ -0,0 +1,270 @@
+// Package quality checks fetched bars against data quality rules, e.g. a Low
+// above the High or a negative volume. Every rule has an action: warn keeps
+// the bar and reports it, drop removes the bar and fail rejects the whole
+// symbol. A Validator collects the issues of a run into a Report that can be
+// saved as json.
+package quality
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twold/go-quandl/atomicfile"
+)
+
+// Actions
+const (
+	Off        = "off"
+	Warn       = "warn"
+	DropRow    = "drop"
+	FailSymbol = "fail"
+)
+
+// Actions lists the supported actions, least severe first
+var Actions = []string{Off, Warn, DropRow, FailSymbol}
+
+// Rules
+const (
+	// LowAboveHigh bars have a Low above their High
+	LowAboveHigh = "low_above_high"
+	// OpenOutsideRange bars open below the Low or above the High
+	OpenOutsideRange = "open_outside_range"
+	// CloseOutsideRange bars close below the Low or above the High
+	CloseOutsideRange = "close_outside_range"
+	// NegativeVolume bars have a volume below zero
+	NegativeVolume = "negative_volume"
+	// ZeroPrice bars have an open, high, low or close of zero or less
+	ZeroPrice = "zero_price"
+	// DuplicateDate bars repeat the date of an earlier bar, which is kept
+	DuplicateDate = "duplicate_date"
+)
+
+// Defaults are the actions of rules not configured otherwise
+var Defaults = map[string]string{
+	LowAboveHigh:      Warn,
+	OpenOutsideRange:  Warn,
+	CloseOutsideRange: Warn,
+	NegativeVolume:    Warn,
+	ZeroPrice:         Warn,
+	DuplicateDate:     DropRow,
+}
+
+// Rules lists the supported rules
+var Rules = []string{LowAboveHigh, OpenOutsideRange, CloseOutsideRange, NegativeVolume, ZeroPrice, DuplicateDate}
+
+// Bar holds the values checked by the rules, nil values are not checked
+type Bar struct {
+	Date   string
+	Open   *float64
+	High   *float64
+	Low    *float64
+	Close  *float64
+	Volume *float64
+}
+
+// Issue is a bar breaking a rule
+type Issue struct {
+	Rule   string `json:"rule" type:"string"`
+	Date   string `json:"date" type:"string"`
+	Action string `json:"action" type:"string"`
+	Detail string `json:"detail" type:"string"`
+}
+
+// SymbolReport lists the issues found in the bars of one symbol
+type SymbolReport struct {
+	Symbol  string  `json:"symbol" type:"string"`
+	DBCode  string  `json:"db_code,omitempty" type:"string"`
+	Rows    int     `json:"rows" type:"int"`
+	Dropped int     `json:"dropped" type:"int"`
+	Failed  bool    `json:"failed" type:"bool"`
+	Issues  []Issue `json:"issues" type:"list"`
+
+	drop map[int]bool
+}
+
+// Keep reports whether bar i passed the rules that drop bars
+func (r *SymbolReport) Keep(i int) bool {
+	return !r.drop[i]
+}
+
+// Report is the result of a run
+type Report struct {
+	Started time.Time         `json:"started" type:"time"`
+	Rules   map[string]string `json:"rules" type:"map"`
+	Rows    int               `json:"rows" type:"int"`
+	Dropped int               `json:"dropped" type:"int"`
+	Failed  []string          `json:"failed" type:"list"`
+	Symbols []SymbolReport    `json:"symbols" type:"list"`
+}
+
+// WriteFile atomically saves the report as json
+func (r *Report) WriteFile(name string) error {
+	b, err := json.MarshalIndent(r, "", "	")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(name, b, 0666)
+}
+
+// Error rejects a symbol that broke a rule with the fail action
+type Error struct {
+	Symbol string
+	Issues []Issue
+}
+
+func (e *Error) Error() string {
+	rules := []string{}
+	for _, i := range e.Issues {
+		if i.Action == FailSymbol && !contains(rules, i.Rule) {
+			rules = append(rules, i.Rule)
+		}
+	}
+	return fmt.Sprintf("%s failed quality rules %s", e.Symbol, strings.Join(rules, ", "))
+}
+
+// Validator checks bars and collects the reports of a run. It is safe for
+// concurrent use.
+type Validator struct {
+	rules map[string]string
+
+	mu     sync.Mutex
+	report Report
+}
+
+// New returns a validator applying rules, a map of rule to action, on top of
+// Defaults
+func New(rules map[string]string) (*Validator, error) {
+	v := &Validator{rules: map[string]string{}}
+	for rule, action := range Defaults {
+		v.rules[rule] = action
+	}
+	for rule, action := range rules {
+		if !contains(Rules, rule) {
+			return nil, fmt.Errorf("unknown rule %q, options are %s", rule, strings.Join(Rules, ", "))
+		}
+		if !contains(Actions, action) {
+			return nil, fmt.Errorf("unknown action %q for rule %s, options are %s", action, rule, strings.Join(Actions, ", "))
+		}
+		v.rules[rule] = action
+	}
+	v.report = Report{Started: time.Now().UTC(), Rules: v.rules, Symbols: []SymbolReport{}, Failed: []string{}}
+	return v, nil
+}
+
+// ParseRules parses rule=action pairs separated by commas, e.g.
+// "low_above_high=drop,negative_volume=fail"
+func ParseRules(s string) (map[string]string, error) {
+	rules := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid rule %q, expected rule=action", pair)
+		}
+		rules[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return rules, nil
+}
+
+// Check applies the rules to the bars of symbol and adds the result to the
+// report. It returns an *Error if a rule with the fail action was broken.
+func (v *Validator) Check(symbol, dbCode string, bars []Bar) (*SymbolReport, error) {
+	r := &SymbolReport{Symbol: symbol, DBCode: dbCode, Rows: len(bars), Issues: []Issue{}, drop: map[int]bool{}}
+	add := func(i int, rule, format string, args ...interface{}) {
+		action := v.rules[rule]
+		if action == Off {
+			return
+		}
+		r.Issues = append(r.Issues, Issue{Rule: rule, Date: bars[i].Date, Action: action, Detail: fmt.Sprintf(format, args...)})
+		switch action {
+		case DropRow:
+			r.drop[i] = true
+		case FailSymbol:
+			r.Failed = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, b := range bars {
+		if seen[b.Date] {
+			add(i, DuplicateDate, "date %s repeated", b.Date)
+		}
+		seen[b.Date] = true
+
+		if b.Low != nil && b.High != nil && *b.Low > *b.High {
+			add(i, LowAboveHigh, "low %g above high %g", *b.Low, *b.High)
+		}
+		if outside(b.Open, b.Low, b.High) {
+			add(i, OpenOutsideRange, "open %g outside [%g, %g]", *b.Open, *b.Low, *b.High)
+		}
+		if outside(b.Close, b.Low, b.High) {
+			add(i, CloseOutsideRange, "close %g outside [%g, %g]", *b.Close, *b.Low, *b.High)
+		}
+		if b.Volume != nil && *b.Volume < 0 {
+			add(i, NegativeVolume, "volume %g", *b.Volume)
+		}
+		for _, p := range []struct {
+			name  string
+			value *float64
+		}{{"open", b.Open}, {"high", b.High}, {"low", b.Low}, {"close", b.Close}} {
+			if p.value != nil && *p.value <= 0 {
+				add(i, ZeroPrice, "%s %g", p.name, *p.value)
+				break
+			}
+		}
+	}
+	r.Dropped = len(r.drop)
+
+	v.mu.Lock()
+	v.report.Rows += r.Rows
+	v.report.Dropped += r.Dropped
+	if r.Failed {
+		v.report.Failed = append(v.report.Failed, symbol)
+	}
+	v.report.Symbols = append(v.report.Symbols, *r)
+	v.mu.Unlock()
+
+	if r.Failed {
+		return r, &Error{Symbol: symbol, Issues: r.Issues}
+	}
+	return r, nil
+}
+
+// Report returns the reports of the symbols checked so far, ordered by
+// symbol
+func (v *Validator) Report() *Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	r := v.report
+	r.Symbols = append([]SymbolReport(nil), v.report.Symbols...)
+	r.Failed = append([]string{}, v.report.Failed...)
+	sort.Slice(r.Symbols, func(i, j int) bool { return r.Symbols[i].Symbol < r.Symbols[j].Symbol })
+	sort.Strings(r.Failed)
+	return &r
+}
+
+// outside reports whether v lies outside [low, high], a range that is itself
+// valid
+func outside(v, low, high *float64) bool {
+	if v == nil || low == nil || high == nil || *low > *high {
+		return false
+	}
+	return *v < *low || *v > *high
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package quality_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quality Suite")
+}
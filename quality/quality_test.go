@@ -0,0 +1,115 @@
+package quality_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/quality"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func bar(date string, open, high, low, close, volume float64) Bar {
+	return Bar{Date: date, Open: fixture.Float(open), High: fixture.Float(high), Low: fixture.Float(low), Close: fixture.Float(close), Volume: fixture.Float(volume)}
+}
+
+var _ = Describe("Quality", func() {
+	bars := []Bar{
+		bar("2018-01-02", 10, 11, 9, 10.5, 100),
+		bar("2018-01-03", 10, 9, 11, 10, 100),
+		bar("2018-01-04", 10, 11, 9, 12, 100),
+		bar("2018-01-05", 10, 11, 9, 10, -5),
+		bar("2018-01-08", 0, 11, 9, 10, 100),
+		bar("2018-01-08", 10, 11, 9, 10, 100),
+	}
+
+	It("should warn by default and drop duplicate dates", func() {
+		v, err := New(nil)
+		Expect(err).Should(BeNil())
+		r, err := v.Check("FB", "WIKI", bars)
+		Expect(err).Should(BeNil())
+
+		rules := []string{}
+		for _, i := range r.Issues {
+			rules = append(rules, i.Rule)
+		}
+		Expect(rules).Should(Equal([]string{LowAboveHigh, CloseOutsideRange, NegativeVolume, OpenOutsideRange, ZeroPrice, DuplicateDate}))
+		Expect(r.Dropped).Should(Equal(1))
+		Expect(r.Keep(4)).Should(BeTrue())
+		Expect(r.Keep(5)).Should(BeFalse())
+	})
+
+	It("should drop rows and fail symbols as configured", func() {
+		v, err := New(map[string]string{LowAboveHigh: DropRow, ZeroPrice: Off})
+		Expect(err).Should(BeNil())
+		r, err := v.Check("FB", "WIKI", bars)
+		Expect(err).Should(BeNil())
+		Expect(r.Keep(1)).Should(BeFalse())
+		Expect(r.Dropped).Should(Equal(2))
+		for _, i := range r.Issues {
+			Expect(i.Rule).ShouldNot(Equal(ZeroPrice))
+		}
+
+		v, err = New(map[string]string{NegativeVolume: FailSymbol})
+		Expect(err).Should(BeNil())
+		_, err = v.Check("AAPL", "WIKI", bars)
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.(*Error).Symbol).Should(Equal("AAPL"))
+		Expect(err.Error()).Should(Equal("AAPL failed quality rules negative_volume"))
+		_, err = v.Check("FB", "WIKI", bars[:1])
+		Expect(err).Should(BeNil())
+
+		report := v.Report()
+		Expect(report.Rows).Should(Equal(7))
+		Expect(report.Failed).Should(Equal([]string{"AAPL"}))
+		Expect(report.Symbols[0].Symbol).Should(Equal("AAPL"))
+		Expect(report.Symbols[1].Issues).Should(BeEmpty())
+	})
+
+	It("should not check missing values", func() {
+		v, err := New(nil)
+		Expect(err).Should(BeNil())
+		r, err := v.Check("VX1", "CBOE", []Bar{{Date: "2018-01-02", Close: fixture.Float(12)}})
+		Expect(err).Should(BeNil())
+		Expect(r.Issues).Should(BeEmpty())
+	})
+
+	It("should parse rules", func() {
+		rules, err := ParseRules("low_above_high=drop, negative_volume=fail,")
+		Expect(err).Should(BeNil())
+		Expect(rules).Should(Equal(map[string]string{LowAboveHigh: DropRow, NegativeVolume: FailSymbol}))
+
+		_, err = ParseRules("low_above_high")
+		Expect(err).ShouldNot(BeNil())
+		_, err = New(map[string]string{"spikes": Warn})
+		Expect(err).ShouldNot(BeNil())
+		_, err = New(map[string]string{LowAboveHigh: "ignore"})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should save the report as json", func() {
+		dir, err := ioutil.TempDir("", "quality")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+
+		v, err := New(map[string]string{LowAboveHigh: DropRow})
+		Expect(err).Should(BeNil())
+		_, err = v.Check("FB", "WIKI", bars[:2])
+		Expect(err).Should(BeNil())
+
+		name := filepath.Join(dir, "quality.json")
+		Expect(v.Report().WriteFile(name)).Should(BeNil())
+		b, err := ioutil.ReadFile(name)
+		Expect(err).Should(BeNil())
+
+		var r Report
+		Expect(json.Unmarshal(b, &r)).Should(BeNil())
+		Expect(r.Dropped).Should(Equal(1))
+		Expect(r.Rules[LowAboveHigh]).Should(Equal(DropRow))
+		Expect(r.Symbols[0].Issues[0]).Should(Equal(Issue{Rule: LowAboveHigh, Date: "2018-01-03", Action: DropRow, Detail: "low 11 above high 9"}))
+	})
+})
@@ -0,0 +1,89 @@
+// Package redact masks secrets, such as the Quandl API key, in strings and
+// errors before they reach logs, error messages or cached URLs.
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value.
+const Mask = "********"
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]struct{}{}
+
+	// api_key may still appear in URLs built by callers or echoed by Quandl
+	queryKey = regexp.MustCompile(`(?i)(api_key=)[^&\s"']+`)
+)
+
+// Add registers a secret to be masked by String and Error.
+func Add(secret string) {
+	if secret == "" {
+		return
+	}
+	mu.Lock()
+	secrets[secret] = struct{}{}
+	// register the escaped form as well, it is what ends up in URLs
+	if esc := url.QueryEscape(secret); esc != secret {
+		secrets[esc] = struct{}{}
+	}
+	mu.Unlock()
+}
+
+// String returns s with all registered secrets and api_key query values masked.
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	mu.RLock()
+	list := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		list = append(list, secret)
+	}
+	mu.RUnlock()
+
+	// replace longest secrets first so overlapping values are fully masked
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	for _, secret := range list {
+		s = strings.Replace(s, secret, Mask, -1)
+	}
+	return queryKey.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Error wraps err so that its message is masked. The original error is still
+// reachable through Unwrap for errors.Is and errors.As.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*redacted); ok {
+		return err
+	}
+
+	// net/http reports the full request URL, rebuild it without the key
+	if uerr, ok := err.(*url.Error); ok {
+		return &url.Error{
+			Op:  uerr.Op,
+			URL: String(uerr.URL),
+			Err: Error(uerr.Err),
+		}
+	}
+	return &redacted{err: err}
+}
+
+type redacted struct {
+	err error
+}
+
+func (e *redacted) Error() string {
+	return String(e.err.Error())
+}
+
+func (e *redacted) Unwrap() error {
+	return e.err
+}
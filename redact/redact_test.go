@@ -0,0 +1,45 @@
+package redact_test
+
+import (
+	"errors"
+	"net/url"
+
+	. "github.com/twold/go-quandl/redact"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("String", func() {
+	Context("When I input a URL with an api_key query value", func() {
+		It("masks the query value", func() {
+			actual := String("https://www.quandl.com/api/v3/datasets/WIKI/FB/data.json?api_key=abc123&limit=1")
+			Expect(actual).Should(Equal("https://www.quandl.com/api/v3/datasets/WIKI/FB/data.json?api_key=********&limit=1"))
+		})
+	})
+
+	Context("When I input a string containing a registered secret", func() {
+		It("masks the secret", func() {
+			Add("s3cr3tKey")
+			Expect(String("bad key s3cr3tKey given")).Should(Equal("bad key ******** given"))
+		})
+	})
+})
+
+var _ = Describe("Error", func() {
+	Context("When I input a url.Error", func() {
+		It("masks the URL and keeps the error chain", func() {
+			Add("s3cr3tKey")
+			inner := errors.New("connection refused")
+			err := Error(&url.Error{Op: "Get", URL: "https://host/x?api_key=s3cr3tKey", Err: inner})
+			Expect(err.Error()).ShouldNot(ContainSubstring("s3cr3tKey"))
+			Expect(errors.Is(err, inner)).Should(BeTrue())
+		})
+	})
+
+	Context("When I input nil", func() {
+		It("returns nil", func() {
+			Expect(Error(nil)).Should(BeNil())
+		})
+	})
+})
@@ -4,6 +4,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/twold/go-quandl/redact"
 )
 
 // A Request is the service request to be made.
@@ -17,6 +19,12 @@ type Request struct {
 }
 
 func New(method, Url string, body io.Reader) *Request {
+	return NewWithHeader(method, Url, nil, body)
+}
+
+// NewWithHeader sends the request with the given headers added. Any error is
+// redacted so that credentials in the URL or headers never leak.
+func NewWithHeader(method, Url string, header http.Header, body io.Reader) *Request {
 	// initialize new Request
 	request := &Request{}
 	if method == "" {
@@ -26,13 +34,20 @@ func New(method, Url string, body io.Reader) *Request {
 	// build http request and save to output
 	request.HTTPRequest, request.Error = http.NewRequest(method, Url, body)
 	if request.Error != nil {
+		request.Error = redact.Error(request.Error)
 		return request
 	}
 
 	request.HTTPRequest.Header.Add("cache-control", "no-cache")
+	for k, v := range header {
+		for _, s := range v {
+			request.HTTPRequest.Header.Add(k, s)
+		}
+	}
 
 	request.HTTPResponse, request.Error = http.DefaultClient.Do(request.HTTPRequest)
 	if request.Error != nil {
+		request.Error = redact.Error(request.Error)
 		return request
 	}
 	// save response body as readcloser
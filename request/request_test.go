@@ -1,12 +1,40 @@
 package request_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+
 	. "github.com/twold/go-quandl/request"
 
+	"github.com/twold/go-quandl/redact"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Request", func() {
+	Context("When I input a header", func() {
+		It("sends the header with the request", func() {
+			var token string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token = r.Header.Get("X-Api-Token")
+			}))
+			defer srv.Close()
+
+			header := http.Header{}
+			header.Set("X-Api-Token", "abc123")
+			actual := NewWithHeader("GET", srv.URL, header, nil)
+			Expect(actual.Error).Should(BeNil())
+			Expect(token).Should(Equal("abc123"))
+		})
+	})
 
+	Context("When the request fails", func() {
+		It("does not leak the API key in the error", func() {
+			redact.Add("abc123")
+			actual := New("GET", "http://127.0.0.1:0/data.json?api_key=abc123", nil)
+			Expect(actual.Error).ShouldNot(BeNil())
+			Expect(actual.Error.Error()).ShouldNot(ContainSubstring("abc123"))
+		})
+	})
 })
@@ -0,0 +1,199 @@
+// Package resample turns daily WIKI rows into weekly, monthly, quarterly or
+// annual bars without asking Quandl for a collapsed data set: the open is the
+// first open of the period, the high its highest high, the low its lowest
+// low, the close its last close and the volume the sum of its volumes, for
+// the raw and the adjusted columns alike. Weeks are ISO weeks, Monday to
+// Sunday.
+//
+// Bars are dated by the last session of their period in the exchange's
+// trading calendar, e.g. the Thursday before Good Friday, whether or not the
+// rows reach that day.
+package resample
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/calendar"
+)
+
+// Frequencies, named as Quandl's collapse parameter
+const (
+	Daily     = "daily"
+	Weekly    = "weekly"
+	Monthly   = "monthly"
+	Quarterly = "quarterly"
+	Annual    = "annual"
+)
+
+// Frequencies lists the supported frequencies
+var Frequencies = []string{Daily, Weekly, Monthly, Quarterly, Annual}
+
+// Options configures a resampling
+type Options struct {
+	// Calendar dates the bars, calendar.NYSE if nil
+	Calendar *calendar.Calendar
+	// Complete drops the last bar if its period is not over, i.e. the rows
+	// end before the period's last session
+	Complete bool
+}
+
+// Period returns the first and last day of the period of frequency freq
+// holding d
+func Period(freq string, d time.Time) (start, end time.Time, err error) {
+	year, month := d.Year(), d.Month()
+	switch freq {
+	case Daily:
+		start = calendar.Date(year, month, d.Day())
+		return start, start, nil
+	case Weekly:
+		// Monday is the first day of ISO weeks
+		start = calendar.Date(year, month, d.Day()-(int(d.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 6), nil
+	case Monthly:
+		start = calendar.Date(year, month, 1)
+		return start, start.AddDate(0, 1, -1), nil
+	case Quarterly:
+		start = calendar.Date(year, month-(month-1)%3, 1)
+		return start, start.AddDate(0, 3, -1), nil
+	case Annual:
+		start = calendar.Date(year, time.January, 1)
+		return start, start.AddDate(1, 0, -1), nil
+	}
+	return start, end, fmt.Errorf("unknown frequency %q, options are %s", freq, strings.Join(Frequencies, ", "))
+}
+
+// bar accumulates the rows of a period
+type bar struct {
+	start, end time.Time
+	last       string
+	obj        api.Wiki
+}
+
+// Wiki returns the bars of frequency freq of objs, daily rows in any order,
+// ordered by date. Daily returns the rows themselves, ordered.
+func Wiki(objs []api.Wiki, freq string, o Options) ([]api.Wiki, error) {
+	cal := o.Calendar
+	if cal == nil {
+		cal = calendar.NYSE
+	}
+	if _, _, err := Period(freq, time.Time{}); err != nil {
+		return nil, err
+	}
+
+	rows := make([]api.Wiki, 0, len(objs))
+	for _, obj := range objs {
+		if obj.Date != nil {
+			rows = append(rows, obj)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return *rows[i].Date < *rows[j].Date })
+	if freq == Daily {
+		return rows, nil
+	}
+
+	bars := []*bar{}
+	for _, obj := range rows {
+		d, err := calendar.Parse(*obj.Date)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(bars); n > 0 && !d.After(bars[n-1].end) {
+			bars[n-1].add(obj)
+			continue
+		}
+		start, end, _ := Period(freq, d)
+		b := &bar{start: start, end: end}
+		b.add(obj)
+		bars = append(bars, b)
+	}
+
+	out := make([]api.Wiki, 0, len(bars))
+	for i, b := range bars {
+		date := b.last
+		if s := cal.Sessions(b.start, b.end); len(s) > 0 {
+			date = s[len(s)-1].Format(calendar.DateLayout)
+		}
+		if o.Complete && i == len(bars)-1 && b.last < date {
+			continue
+		}
+		day, _ := calendar.Parse(date)
+		weekday := day.Weekday().String()
+		b.obj.Date, b.obj.DayOfWeek = &date, &weekday
+		out = append(out, b.obj)
+	}
+	return out, nil
+}
+
+// add merges a row, dated after the rows added before, into the bar
+func (b *bar) add(obj api.Wiki) {
+	b.last = *obj.Date
+	o := &b.obj
+	o.Open, o.AdjOpen = earliest(o.Open, obj.Open), earliest(o.AdjOpen, obj.AdjOpen)
+	o.High, o.AdjHigh = most(o.High, obj.High, 1), most(o.AdjHigh, obj.AdjHigh, 1)
+	o.Low, o.AdjLow = most(o.Low, obj.Low, -1), most(o.AdjLow, obj.AdjLow, -1)
+	o.Close, o.AdjClose = latest(o.Close, obj.Close), latest(o.AdjClose, obj.AdjClose)
+	o.Volume, o.AdjVolume = sum(o.Volume, obj.Volume), sum(o.AdjVolume, obj.AdjVolume)
+	o.ExDividend = sum(o.ExDividend, obj.ExDividend)
+	o.SplitRatio = product(o.SplitRatio, obj.SplitRatio)
+}
+
+// earliest keeps the first value set
+func earliest(acc, v *float64) *float64 {
+	if acc != nil {
+		return acc
+	}
+	return copyOf(v)
+}
+
+// latest keeps the last value set
+func latest(acc, v *float64) *float64 {
+	if v != nil {
+		return copyOf(v)
+	}
+	return acc
+}
+
+// most keeps the highest value if sign is 1 and the lowest if it is -1
+func most(acc, v *float64, sign float64) *float64 {
+	if v == nil {
+		return acc
+	}
+	if acc == nil || *v*sign > *acc*sign {
+		return copyOf(v)
+	}
+	return acc
+}
+
+func sum(acc, v *float64) *float64 {
+	if v == nil {
+		return acc
+	}
+	if acc == nil {
+		return copyOf(v)
+	}
+	x := *acc + *v
+	return &x
+}
+
+func product(acc, v *float64) *float64 {
+	if v == nil {
+		return acc
+	}
+	if acc == nil {
+		return copyOf(v)
+	}
+	x := *acc * *v
+	return &x
+}
+
+func copyOf(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	x := *v
+	return &x
+}
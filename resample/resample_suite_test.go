@@ -0,0 +1,13 @@
+package resample_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Resample Suite")
+}
@@ -0,0 +1,101 @@
+package resample_test
+
+import (
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/calendar"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/resample"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func wiki(date string, open, high, low, close, volume float64) api.Wiki {
+	return api.Wiki{
+		Date: &date, Open: fixture.Float(open), High: fixture.Float(high), Low: fixture.Float(low), Close: fixture.Float(close), Volume: fixture.Float(volume),
+		AdjOpen: fixture.Float(open / 2), AdjHigh: fixture.Float(high / 2), AdjLow: fixture.Float(low / 2), AdjClose: fixture.Float(close / 2), AdjVolume: fixture.Float(volume * 2),
+		ExDividend: fixture.Float(0), SplitRatio: fixture.Float(1),
+	}
+}
+
+var _ = Describe("Resample", func() {
+	// newest first, March 30th 2018 is Good Friday
+	rows := []api.Wiki{
+		wiki("2018-04-03", 14, 16, 13, 15, 50),
+		wiki("2018-04-02", 12, 14, 11, 13, 40),
+		wiki("2018-03-29", 11, 13, 10, 12, 30),
+		wiki("2018-03-28", 10, 12, 8, 11, 20),
+		wiki("2018-03-27", 9, 11, 9, 10, 10),
+	}
+
+	It("should build weekly bars dated by the last session of the week", func() {
+		bars, err := Wiki(rows, Weekly, Options{})
+		Expect(err).Should(BeNil())
+		Expect(len(bars)).Should(Equal(2))
+
+		b := bars[0]
+		Expect(*b.Date).Should(Equal("2018-03-29"))
+		Expect(*b.DayOfWeek).Should(Equal("Thursday"))
+		Expect(*b.Open).Should(Equal(9.0))
+		Expect(*b.High).Should(Equal(13.0))
+		Expect(*b.Low).Should(Equal(8.0))
+		Expect(*b.Close).Should(Equal(12.0))
+		Expect(*b.Volume).Should(Equal(60.0))
+		Expect(*b.AdjOpen).Should(Equal(4.5))
+		Expect(*b.AdjLow).Should(Equal(4.0))
+		Expect(*b.AdjClose).Should(Equal(6.0))
+		Expect(*b.AdjVolume).Should(Equal(120.0))
+		Expect(*b.SplitRatio).Should(Equal(1.0))
+
+		Expect(*bars[1].Date).Should(Equal("2018-04-06"))
+		Expect(*bars[1].Open).Should(Equal(12.0))
+		Expect(*bars[1].Close).Should(Equal(15.0))
+	})
+
+	It("should drop a period that is not over if asked to", func() {
+		bars, err := Wiki(rows, Monthly, Options{Complete: true})
+		Expect(err).Should(BeNil())
+		Expect(len(bars)).Should(Equal(1))
+		Expect(*bars[0].Date).Should(Equal("2018-03-29"))
+		Expect(*bars[0].Volume).Should(Equal(60.0))
+
+		bars, err = Wiki(rows, Quarterly, Options{})
+		Expect(err).Should(BeNil())
+		Expect(len(bars)).Should(Equal(2))
+		Expect(*bars[1].Date).Should(Equal("2018-06-29"))
+	})
+
+	It("should build annual bars", func() {
+		bars, err := Wiki(append(rows, wiki("2017-12-29", 1, 100, 1, 2, 5)), Annual, Options{Calendar: calendar.CFE})
+		Expect(err).Should(BeNil())
+		Expect(len(bars)).Should(Equal(2))
+		Expect(*bars[0].Date).Should(Equal("2017-12-29"))
+		Expect(*bars[0].High).Should(Equal(100.0))
+		Expect(*bars[1].Date).Should(Equal("2018-12-31"))
+		Expect(*bars[1].Volume).Should(Equal(150.0))
+	})
+
+	It("should return daily rows ordered", func() {
+		bars, err := Wiki(rows, Daily, Options{})
+		Expect(err).Should(BeNil())
+		Expect(*bars[0].Date).Should(Equal("2018-03-27"))
+		Expect(*bars[4].Date).Should(Equal("2018-04-03"))
+	})
+
+	It("should return the period of a day", func() {
+		start, end, err := Period(Weekly, time.Date(2018, 1, 7, 0, 0, 0, 0, time.UTC))
+		Expect(err).Should(BeNil())
+		Expect(start.Format(calendar.DateLayout)).Should(Equal("2018-01-01"))
+		Expect(end.Format(calendar.DateLayout)).Should(Equal("2018-01-07"))
+
+		start, end, err = Period(Quarterly, time.Date(2018, 8, 15, 0, 0, 0, 0, time.UTC))
+		Expect(err).Should(BeNil())
+		Expect(start.Format(calendar.DateLayout)).Should(Equal("2018-07-01"))
+		Expect(end.Format(calendar.DateLayout)).Should(Equal("2018-09-30"))
+
+		_, err = Wiki(rows, "hourly", Options{})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,415 @@
+// Package risk computes returns and risk metrics of price series: simple,
+// log and cumulative returns, rolling and annualized volatility, drawdowns,
+// the Sharpe and Sortino ratios and the beta and correlation against a
+// benchmark. Series come from WIKI rows, saved or freshly fetched.
+//
+// Volatilities are sample standard deviations scaled by the square root of
+// the periods per year; ratios use the annual risk free rate divided by the
+// periods per year as the return of a period.
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/twold/go-quandl/api"
+)
+
+// TradingDays is the default number of periods per year, for daily rows
+const TradingDays = 252
+
+// Series is a price series ordered by date
+type Series struct {
+	Symbol string
+	Dates  []string
+	Prices []float64
+}
+
+// FromWiki returns the closes of objs, in any order, as a series ordered by
+// date, AdjClose if adjusted is set and Close otherwise. Rows without a date
+// or a positive close are skipped.
+func FromWiki(symbol string, objs []api.Wiki, adjusted bool) Series {
+	rows := make([]api.Wiki, 0, len(objs))
+	for _, obj := range objs {
+		c := obj.Close
+		if adjusted {
+			c = obj.AdjClose
+		}
+		if obj.Date != nil && c != nil && *c > 0 {
+			rows = append(rows, obj)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return *rows[i].Date < *rows[j].Date })
+
+	s := Series{Symbol: symbol, Dates: make([]string, len(rows)), Prices: make([]float64, len(rows))}
+	for i, obj := range rows {
+		s.Dates[i], s.Prices[i] = *obj.Date, *obj.Close
+		if adjusted {
+			s.Prices[i] = *obj.AdjClose
+		}
+	}
+	return s
+}
+
+// Align returns the rows of a and b on the dates they share
+func Align(a, b Series) (Series, Series) {
+	in := make(map[string]bool, len(b.Dates))
+	for _, d := range b.Dates {
+		in[d] = true
+	}
+	shared := make(map[string]bool, len(a.Dates))
+	for _, d := range a.Dates {
+		if in[d] {
+			shared[d] = true
+		}
+	}
+	return a.only(shared), b.only(shared)
+}
+
+func (s Series) only(dates map[string]bool) Series {
+	out := Series{Symbol: s.Symbol, Dates: []string{}, Prices: []float64{}}
+	for i, d := range s.Dates {
+		if dates[d] {
+			out.Dates = append(out.Dates, d)
+			out.Prices = append(out.Prices, s.Prices[i])
+		}
+	}
+	return out
+}
+
+// Returns returns the simple returns of prices, p[i]/p[i-1] - 1, one fewer
+// than prices
+func Returns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return []float64{}
+	}
+	out := make([]float64, len(prices)-1)
+	for i := range out {
+		out[i] = prices[i+1]/prices[i] - 1
+	}
+	return out
+}
+
+// LogReturns returns the log returns of prices, ln(p[i]/p[i-1]), one fewer
+// than prices
+func LogReturns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return []float64{}
+	}
+	out := make([]float64, len(prices)-1)
+	for i := range out {
+		out[i] = math.Log(prices[i+1] / prices[i])
+	}
+	return out
+}
+
+// Cumulative returns the compounded return up to each of returns
+func Cumulative(returns []float64) []float64 {
+	out := make([]float64, len(returns))
+	growth := 1.0
+	for i, r := range returns {
+		growth *= 1 + r
+		out[i] = growth - 1
+	}
+	return out
+}
+
+// Volatility returns the annualized volatility of returns, 0 for fewer than
+// two returns
+func Volatility(returns []float64, periods float64) float64 {
+	return stdDev(returns) * math.Sqrt(periods)
+}
+
+// RollingVolatility returns the annualized volatility of the n returns up
+// to each of returns, nil for the first n-1
+func RollingVolatility(returns []float64, n int, periods float64) []*float64 {
+	out := make([]*float64, len(returns))
+	for i := n - 1; i < len(returns) && n > 1; i++ {
+		v := Volatility(returns[i-n+1:i+1], periods)
+		out[i] = &v
+	}
+	return out
+}
+
+// Drawdown is a fall of the prices below their last peak
+type Drawdown struct {
+	// Peak is the date of the last high before the fall
+	Peak string `json:"peak" type:"string"`
+	// Trough is the date of the lowest price of the fall
+	Trough string `json:"trough" type:"string"`
+	// Recovery is the date the price is back at the peak, empty if it is not
+	Recovery string `json:"recovery,omitempty" type:"string"`
+	// Depth is the fall from the peak to the trough, e.g. 0.25 for 25%
+	Depth float64 `json:"depth" type:"float64"`
+	// Bars is the number of bars from the peak to the recovery, or to the
+	// last bar if the prices have not recovered
+	Bars int `json:"bars" type:"int"`
+}
+
+// Drawdowns returns the drawdowns of prices, ordered by date
+func Drawdowns(dates []string, prices []float64) []Drawdown {
+	out := []Drawdown{}
+	peak := 0
+	var cur *Drawdown
+	for i, p := range prices {
+		if p >= prices[peak] {
+			if cur != nil {
+				cur.Recovery, cur.Bars = dates[i], i-peak
+				out = append(out, *cur)
+				cur = nil
+			}
+			peak = i
+			continue
+		}
+		depth := 1 - p/prices[peak]
+		if cur == nil {
+			cur = &Drawdown{Peak: dates[peak]}
+		}
+		if depth > cur.Depth {
+			cur.Trough, cur.Depth = dates[i], depth
+		}
+	}
+	if cur != nil {
+		cur.Bars = len(prices) - 1 - peak
+		out = append(out, *cur)
+	}
+	return out
+}
+
+// MaxDrawdown returns the deepest of dds, the first one if several are as
+// deep
+func MaxDrawdown(dds []Drawdown) Drawdown {
+	var max Drawdown
+	for _, d := range dds {
+		if d.Depth > max.Depth {
+			max = d
+		}
+	}
+	return max
+}
+
+// LongestDrawdown returns the drawdown of dds that lasts the most bars
+func LongestDrawdown(dds []Drawdown) Drawdown {
+	var longest Drawdown
+	for _, d := range dds {
+		if d.Bars > longest.Bars {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// Sharpe returns the annualized Sharpe ratio of returns for the annual risk
+// free rate riskFree, 0 if the returns do not vary
+func Sharpe(returns []float64, riskFree, periods float64) float64 {
+	sd := stdDev(returns)
+	if sd == 0 {
+		return 0
+	}
+	return (mean(returns) - riskFree/periods) / sd * math.Sqrt(periods)
+}
+
+// Sortino returns the annualized Sortino ratio of returns for the annual
+// risk free rate riskFree, the excess return over the deviation of the
+// returns below the risk free rate. It is 0 if no return is below it.
+func Sortino(returns []float64, riskFree, periods float64) float64 {
+	target := riskFree / periods
+	sum := 0.0
+	for _, r := range returns {
+		if r < target {
+			sum += (r - target) * (r - target)
+		}
+	}
+	if sum == 0 {
+		return 0
+	}
+	downside := math.Sqrt(sum / float64(len(returns)))
+	return (mean(returns) - target) / downside * math.Sqrt(periods)
+}
+
+// Beta returns the beta of returns against the returns of a benchmark over
+// the same periods, 0 if the benchmark does not vary
+func Beta(returns, benchmark []float64) (float64, error) {
+	if len(returns) != len(benchmark) {
+		return 0, fmt.Errorf("%d returns against %d benchmark returns", len(returns), len(benchmark))
+	}
+	v := covariance(benchmark, benchmark)
+	if v == 0 {
+		return 0, nil
+	}
+	return covariance(returns, benchmark) / v, nil
+}
+
+// Correlation returns the correlation of returns and the returns of a
+// benchmark over the same periods, 0 if either does not vary
+func Correlation(returns, benchmark []float64) (float64, error) {
+	if len(returns) != len(benchmark) {
+		return 0, fmt.Errorf("%d returns against %d benchmark returns", len(returns), len(benchmark))
+	}
+	sd := stdDev(returns) * stdDev(benchmark)
+	if sd == 0 {
+		return 0, nil
+	}
+	return covariance(returns, benchmark) / sd, nil
+}
+
+// Options configures Compute
+type Options struct {
+	// PeriodsPerYear annualizes the metrics, TradingDays if 0
+	PeriodsPerYear float64
+	// RiskFree is the annual risk free rate, e.g. 0.02
+	RiskFree float64
+	// Benchmark, if set, gives the beta and correlation, over the dates it
+	// shares with the series
+	Benchmark *Series
+}
+
+// Metrics are the returns and risk of a series
+type Metrics struct {
+	Symbol string `json:"symbol" type:"string"`
+	Start  string `json:"start" type:"string"`
+	End    string `json:"end" type:"string"`
+	Bars   int    `json:"bars" type:"int"`
+	// TotalReturn is the cumulative return from Start to End
+	TotalReturn float64 `json:"total_return" type:"float64"`
+	// AnnualReturn is the compounded return per year
+	AnnualReturn float64 `json:"annual_return" type:"float64"`
+	// Volatility is the annualized volatility of the returns
+	Volatility      float64  `json:"volatility" type:"float64"`
+	Sharpe          float64  `json:"sharpe" type:"float64"`
+	Sortino         float64  `json:"sortino" type:"float64"`
+	MaxDrawdown     Drawdown `json:"max_drawdown" type:"struct"`
+	LongestDrawdown Drawdown `json:"longest_drawdown" type:"struct"`
+	// Benchmark names the benchmark of Beta and Correlation
+	Benchmark   string   `json:"benchmark,omitempty" type:"string"`
+	Beta        *float64 `json:"beta,omitempty" type:"float64"`
+	Correlation *float64 `json:"correlation,omitempty" type:"float64"`
+}
+
+// Compute returns the metrics of s, which needs two prices at least
+func Compute(s Series, o Options) (Metrics, error) {
+	m := Metrics{Symbol: s.Symbol, Bars: len(s.Prices)}
+	if len(s.Prices) < 2 || len(s.Dates) != len(s.Prices) {
+		return m, fmt.Errorf("%s: %d prices for %d dates, need two at least", s.Symbol, len(s.Prices), len(s.Dates))
+	}
+	periods := o.PeriodsPerYear
+	if periods == 0 {
+		periods = TradingDays
+	}
+
+	returns := Returns(s.Prices)
+	m.Start, m.End = s.Dates[0], s.Dates[len(s.Dates)-1]
+	m.TotalReturn = s.Prices[len(s.Prices)-1]/s.Prices[0] - 1
+	m.AnnualReturn = math.Pow(1+m.TotalReturn, periods/float64(len(returns))) - 1
+	m.Volatility = Volatility(returns, periods)
+	m.Sharpe = Sharpe(returns, o.RiskFree, periods)
+	m.Sortino = Sortino(returns, o.RiskFree, periods)
+	dds := Drawdowns(s.Dates, s.Prices)
+	m.MaxDrawdown, m.LongestDrawdown = MaxDrawdown(dds), LongestDrawdown(dds)
+
+	if o.Benchmark == nil {
+		return m, nil
+	}
+	a, b := Align(s, *o.Benchmark)
+	if len(a.Prices) < 3 {
+		return m, fmt.Errorf("%s: %d dates in common with benchmark %s, need three at least", s.Symbol, len(a.Prices), b.Symbol)
+	}
+	ra, rb := Returns(a.Prices), Returns(b.Prices)
+	beta, _ := Beta(ra, rb)
+	corr, _ := Correlation(ra, rb)
+	m.Benchmark, m.Beta, m.Correlation = b.Symbol, &beta, &corr
+	return m, nil
+}
+
+// Columns are the columns of a summary table, the first being the end date
+var Columns = []string{
+	"End", "Start", "Bars", "TotalReturn", "AnnualReturn", "Volatility", "Sharpe", "Sortino",
+	"MaxDrawdown", "MaxDrawdownPeak", "MaxDrawdownTrough", "MaxDrawdownRecovery", "LongestDrawdownBars",
+	"Benchmark", "Beta", "Correlation",
+}
+
+// Summary returns a table per metrics, of one row dated by its End, so that
+// the table sinks write the metrics of a list of symbols as one table with
+// a row per symbol
+func Summary(ms []Metrics) []*api.Table {
+	tables := make([]*api.Table, 0, len(ms))
+	for _, m := range ms {
+		tables = append(tables, &api.Table{Symbol: m.Symbol, DBCode: "RISK", Columns: Columns, Rows: [][]interface{}{{
+			m.End, m.Start, float64(m.Bars), m.TotalReturn, m.AnnualReturn, m.Volatility, m.Sharpe, m.Sortino,
+			m.MaxDrawdown.Depth, text(m.MaxDrawdown.Peak), text(m.MaxDrawdown.Trough), text(m.MaxDrawdown.Recovery),
+			float64(m.LongestDrawdown.Bars), text(m.Benchmark), value(m.Beta), value(m.Correlation),
+		}}})
+	}
+	return tables
+}
+
+// SeriesTable returns the returns of s by date: the simple, log and
+// cumulative return, the annualized volatility of the last window returns
+// and the drawdown from the last peak. The first date has no returns.
+func SeriesTable(s Series, window int, periods float64) *api.Table {
+	if periods == 0 {
+		periods = TradingDays
+	}
+	vol := fmt.Sprintf("Volatility_%d", window)
+	t := &api.Table{
+		Symbol: s.Symbol, DBCode: "RISK",
+		Columns: []string{"Date", "Return", "LogReturn", "CumulativeReturn", vol, "Drawdown"},
+		Rows:    make([][]interface{}, 0, len(s.Prices)),
+	}
+	returns := Returns(s.Prices)
+	logs, cum := LogReturns(s.Prices), Cumulative(returns)
+	rolling := RollingVolatility(returns, window, periods)
+	peak := 0.0
+	for i, p := range s.Prices {
+		peak = math.Max(peak, p)
+		row := []interface{}{s.Dates[i], nil, nil, nil, nil, 1 - p/peak}
+		if i > 0 {
+			row[1], row[2], row[3], row[4] = returns[i-1], logs[i-1], cum[i-1], value(rolling[i-1])
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// covariance is the sample covariance of xs and ys, of the same length
+func covariance(xs, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	mx, my := mean(xs), mean(ys)
+	sum := 0.0
+	for i := range xs {
+		sum += (xs[i] - mx) * (ys[i] - my)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+func stdDev(xs []float64) float64 {
+	return math.Sqrt(covariance(xs, xs))
+}
+
+func text(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func value(f *float64) interface{} {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
@@ -0,0 +1,13 @@
+package risk_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRisk(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Risk Suite")
+}
@@ -0,0 +1,110 @@
+package risk_test
+
+import (
+	"fmt"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/risk"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// rows returns prices as WIKI rows dated from January 1st, newest first,
+// the adjusted closes being half the closes
+func rows(prices []float64) []api.Wiki {
+	objs := []api.Wiki{}
+	for i := len(prices) - 1; i >= 0; i-- {
+		date := fmt.Sprintf("2018-01-%02d", i+1)
+		objs = append(objs, api.Wiki{Date: &date, Close: fixture.Float(prices[i]), AdjClose: fixture.Float(prices[i] / 2)})
+	}
+	return objs
+}
+
+var _ = Describe("Risk", func() {
+	// golden values computed independently, to 6 decimals
+	const tolerance = 1e-6
+	prices := []float64{100, 102, 101, 105, 103, 98, 99, 104, 106, 104, 108, 107}
+	bench := []float64{50, 50.5, 50.2, 51, 50.8, 49.9, 50.1, 51.2, 51.5, 51.3, 52, 52.1}
+
+	It("should read series from WIKI rows", func() {
+		s := FromWiki("AAPL", rows(prices), false)
+		Expect(s.Prices).Should(Equal(prices))
+		Expect(s.Dates[0]).Should(Equal("2018-01-01"))
+		Expect(FromWiki("AAPL", rows(prices), true).Prices[0]).Should(Equal(50.0))
+
+		date := "2018-01-20"
+		Expect(FromWiki("AAPL", append(rows(prices), api.Wiki{Date: &date}), false).Prices).Should(HaveLen(len(prices)))
+	})
+
+	It("should compute returns", func() {
+		Expect(Returns(prices)[0]).Should(BeNumerically("~", 0.02, tolerance))
+		Expect(LogReturns(prices)[0]).Should(BeNumerically("~", 0.019803, tolerance))
+		cum := Cumulative(Returns(prices))
+		Expect(cum).Should(HaveLen(len(prices) - 1))
+		Expect(cum[len(cum)-1]).Should(BeNumerically("~", 0.07, tolerance))
+		Expect(Returns(prices[:1])).Should(BeEmpty())
+
+		Expect(Volatility(Returns(prices), TradingDays)).Should(BeNumerically("~", 0.482857, tolerance))
+		rolling := RollingVolatility(Returns(prices), 3, TradingDays)
+		Expect(rolling[1]).Should(BeNil())
+		Expect(*rolling[10]).Should(BeNumerically("~", 0.487405, tolerance))
+	})
+
+	It("should find drawdowns", func() {
+		s := FromWiki("AAPL", rows(prices), false)
+		dds := Drawdowns(s.Dates, s.Prices)
+		Expect(dds).Should(HaveLen(4))
+		Expect(dds[1].Depth).Should(BeNumerically("~", 0.066667, tolerance))
+		dds[1].Depth = 0
+		Expect(dds[1]).Should(Equal(Drawdown{Peak: "2018-01-04", Trough: "2018-01-06", Recovery: "2018-01-09", Bars: 5}))
+		Expect(dds[3].Recovery).Should(BeEmpty())
+		Expect(dds[3].Bars).Should(Equal(1))
+		Expect(MaxDrawdown(Drawdowns(s.Dates, s.Prices)).Trough).Should(Equal("2018-01-06"))
+		Expect(LongestDrawdown(dds)).Should(Equal(dds[1]))
+	})
+
+	It("should compute the metrics of a series", func() {
+		b := FromWiki("SPY", rows(bench), false)
+		m, err := Compute(FromWiki("AAPL", rows(prices), false), Options{RiskFree: 0.02, Benchmark: &b})
+		Expect(err).Should(BeNil())
+		Expect(m.Start).Should(Equal("2018-01-01"))
+		Expect(m.End).Should(Equal("2018-01-12"))
+		Expect(m.Bars).Should(Equal(12))
+		Expect(m.TotalReturn).Should(BeNumerically("~", 0.07, tolerance))
+		Expect(m.AnnualReturn).Should(BeNumerically("~", 3.711461, tolerance))
+		Expect(m.Sharpe).Should(BeNumerically("~", 3.397529, tolerance))
+		Expect(m.Sortino).Should(BeNumerically("~", 5.990218, tolerance))
+		Expect(m.MaxDrawdown.Depth).Should(BeNumerically("~", 0.066667, tolerance))
+		Expect(m.Benchmark).Should(Equal("SPY"))
+		Expect(*m.Beta).Should(BeNumerically("~", 2.615279, tolerance))
+		Expect(*m.Correlation).Should(BeNumerically("~", 0.979049, tolerance))
+
+		// the benchmark is aligned by date
+		short := FromWiki("SPY", rows(bench)[:6], false)
+		m, err = Compute(FromWiki("AAPL", rows(prices), false), Options{Benchmark: &short})
+		Expect(err).Should(BeNil())
+		Expect(*m.Correlation).Should(BeNumerically("<=", 1))
+
+		_, err = Compute(FromWiki("AAPL", rows(prices[:1]), false), Options{})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should tabulate the metrics and the returns", func() {
+		m, err := Compute(FromWiki("AAPL", rows(prices), false), Options{})
+		Expect(err).Should(BeNil())
+		tables := Summary([]Metrics{m, m})
+		Expect(tables).Should(HaveLen(2))
+		row := tables[0].Rows[0]
+		Expect(row).Should(HaveLen(len(Columns)))
+		Expect(row[0]).Should(Equal("2018-01-12"))
+		Expect(row[len(row)-1]).Should(BeNil())
+
+		t := SeriesTable(FromWiki("AAPL", rows(prices), false), 3, 0)
+		Expect(t.Columns).Should(Equal([]string{"Date", "Return", "LogReturn", "CumulativeReturn", "Volatility_3", "Drawdown"}))
+		Expect(t.Rows[0]).Should(Equal([]interface{}{"2018-01-01", nil, nil, nil, nil, 0.0}))
+		Expect(t.Rows[5][5]).Should(BeNumerically("~", 0.066667, tolerance))
+		Expect(t.Rows[11][4]).Should(BeNumerically("~", 0.487405, tolerance))
+	})
+})
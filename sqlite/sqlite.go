@@ -0,0 +1,353 @@
+// Package sqlite saves data sets to a SQLite database with the pure Go
+// modernc.org/sqlite driver. The schema is
+//
+//	symbols(symbol, name, sector)
+//	datasets(db, symbol, name, description, ..., source_url, fetched_at)
+//	bars(symbol, date, day_of_week, open, high, ..., adj_volume, iso_week, ..., quarter_end)
+//	actions(symbol, date, type, ratio, amount)
+//
+// keyed by symbol, by db and symbol, by symbol and date and by symbol, date
+// and type. Every write is an upsert, so fetching a data set again updates its
+// rows in place. The enrich columns of bars are null unless the rows were
+// enriched, and are added to databases created before them when opened.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/logging"
+
+	// registers the "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// Driver is the database/sql driver name
+const Driver = "sqlite"
+
+// Schema creates the tables if they do not exist
+const Schema = `
+CREATE TABLE IF NOT EXISTS symbols (
+	symbol TEXT NOT NULL PRIMARY KEY,
+	name   TEXT,
+	sector TEXT
+);
+CREATE TABLE IF NOT EXISTS datasets (
+	db                    TEXT NOT NULL,
+	symbol                TEXT NOT NULL REFERENCES symbols (symbol),
+	name                  TEXT,
+	description           TEXT,
+	column_names          TEXT,
+	frequency             TEXT,
+	oldest_available_date TEXT,
+	newest_available_date TEXT,
+	refreshed_at          TEXT,
+	source_url            TEXT,
+	fetched_at            TEXT,
+	PRIMARY KEY (db, symbol)
+);
+CREATE TABLE IF NOT EXISTS bars (
+	symbol        TEXT NOT NULL REFERENCES symbols (symbol),
+	date          TEXT NOT NULL,
+	day_of_week   TEXT,
+	open          REAL,
+	high          REAL,
+	low           REAL,
+	close         REAL,
+	volume        REAL,
+	ex_dividend   REAL,
+	split_ratio   REAL,
+	adj_open      REAL,
+	adj_high      REAL,
+	adj_low       REAL,
+	adj_close     REAL,
+	adj_volume    REAL,
+	iso_week      TEXT,
+	month         INTEGER,
+	quarter       INTEGER,
+	fiscal_period TEXT,
+	trading_day   INTEGER,
+	days_since    INTEGER,
+	month_end     INTEGER,
+	quarter_end   INTEGER,
+	PRIMARY KEY (symbol, date)
+);
+CREATE INDEX IF NOT EXISTS bars_date ON bars (date);
+CREATE TABLE IF NOT EXISTS actions (
+	symbol TEXT NOT NULL REFERENCES symbols (symbol),
+	date   TEXT NOT NULL,
+	type   TEXT NOT NULL,
+	ratio  REAL,
+	amount REAL,
+	PRIMARY KEY (symbol, date, type)
+);
+`
+
+// enrichTypes gives the column type of each enrich field, the columns of bars
+// are named after the fields
+var enrichTypes = map[string]string{
+	enrich.ISOWeek:      "TEXT",
+	enrich.Month:        "INTEGER",
+	enrich.Quarter:      "INTEGER",
+	enrich.FiscalPeriod: "TEXT",
+	enrich.TradingDay:   "INTEGER",
+	enrich.DaysSince:    "INTEGER",
+	enrich.MonthEnd:     "INTEGER",
+	enrich.QuarterEnd:   "INTEGER",
+}
+
+// DB is a SQLite database holding data sets. It is an api.Sink.
+type DB struct {
+	db *sql.DB
+
+	Log logging.Logger
+}
+
+// Open opens or creates the database file name and its tables
+func Open(name string) (*DB, error) {
+	db, err := sql.Open(Driver, name)
+	if err != nil {
+		return nil, err
+	}
+	// one writer at a time, sqlite locks the whole file
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(Schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addEnrichColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+// addEnrichColumns adds the enrich columns missing from a bars table created
+// before them
+func addEnrichColumns(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('bars')`)
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range enrich.Names {
+		if have[name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE bars ADD COLUMN %s %s", name, enrichTypes[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DB returns the underlying database for queries
+func (d *DB) DB() *sql.DB {
+	return d.db
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Symbols saves the name and sector of the symbols of an input list
+func (d *DB) Symbols(list []api.List) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO symbols (symbol, name, sector) VALUES (?, ?, ?)
+		ON CONFLICT (symbol) DO UPDATE SET name = excluded.name, sector = excluded.sector`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range list {
+		if _, err := stmt.Exec(item.Symbol, item.Name, item.Sector); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Metadata saves the metadata of a data set
+func (d *DB) Metadata(m *api.Metadata) error {
+	if m.DatabaseCode == nil || m.DatasetCode == nil {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addSymbol(tx, *m.DatasetCode); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(m.ColumnNames))
+	for _, name := range m.ColumnNames {
+		if name != nil {
+			names = append(names, *name)
+		}
+	}
+	_, err = tx.Exec(`INSERT INTO datasets (db, symbol, name, description, column_names, frequency,
+			oldest_available_date, newest_available_date, refreshed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (db, symbol) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			column_names = excluded.column_names,
+			frequency = excluded.frequency,
+			oldest_available_date = excluded.oldest_available_date,
+			newest_available_date = excluded.newest_available_date,
+			refreshed_at = excluded.refreshed_at`,
+		*m.DatabaseCode, *m.DatasetCode, m.Name, m.Description, strings.Join(names, ","), m.Frequency,
+		m.OldestAvailableDate, m.NewestAvailableDate, m.RefreshedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Write upserts the bars of src.Symbol and records where they came from
+func (d *DB) Write(src api.Source, objs []api.Wiki) error {
+	l, start := logging.OrDiscard(d.Log), time.Now()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addSymbol(tx, src.Symbol); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO bars (symbol, date, day_of_week, open, high, low, close, volume,
+			ex_dividend, split_ratio, adj_open, adj_high, adj_low, adj_close, adj_volume,
+			iso_week, month, quarter, fiscal_period, trading_day, days_since, month_end, quarter_end)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, date) DO UPDATE SET
+			day_of_week = excluded.day_of_week,
+			open = excluded.open,
+			high = excluded.high,
+			low = excluded.low,
+			close = excluded.close,
+			volume = excluded.volume,
+			ex_dividend = excluded.ex_dividend,
+			split_ratio = excluded.split_ratio,
+			adj_open = excluded.adj_open,
+			adj_high = excluded.adj_high,
+			adj_low = excluded.adj_low,
+			adj_close = excluded.adj_close,
+			adj_volume = excluded.adj_volume,
+			iso_week = excluded.iso_week,
+			month = excluded.month,
+			quarter = excluded.quarter,
+			fiscal_period = excluded.fiscal_period,
+			trading_day = excluded.trading_day,
+			days_since = excluded.days_since,
+			month_end = excluded.month_end,
+			quarter_end = excluded.quarter_end`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	n := 0
+	for _, o := range objs {
+		if o.Date == nil {
+			continue
+		}
+		args := []interface{}{src.Symbol, o.Date, o.DayOfWeek, o.Open, o.High, o.Low, o.Close, o.Volume,
+			o.ExDividend, o.SplitRatio, o.AdjOpen, o.AdjHigh, o.AdjLow, o.AdjClose, o.AdjVolume}
+		for _, name := range enrich.Names {
+			args = append(args, o.Fields.Value(name))
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+		n++
+	}
+
+	if src.DBCode != "" {
+		var fetched interface{}
+		if !src.FetchedAt.IsZero() {
+			fetched = src.FetchedAt.UTC().Format(time.RFC3339)
+		}
+		_, err = tx.Exec(`INSERT INTO datasets (db, symbol, source_url, fetched_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (db, symbol) DO UPDATE SET source_url = excluded.source_url, fetched_at = excluded.fetched_at`,
+			src.DBCode, src.Symbol, src.URL, fetched)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.Info("Wrote bars.", logging.Symbol, src.Symbol, logging.Rows, n, logging.Duration, time.Since(start))
+	return nil
+}
+
+// Actions saves the corporate actions of symbol
+func (d *DB) Actions(symbol string, acts []corporate.Action) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addSymbol(tx, symbol); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO actions (symbol, date, type, ratio, amount) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, date, type) DO UPDATE SET ratio = excluded.ratio, amount = excluded.amount`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, a := range acts {
+		var ratio, amount interface{}
+		switch a.Type {
+		case corporate.Split:
+			ratio = a.Ratio
+		case corporate.Dividend:
+			amount = a.Amount
+		}
+		if _, err := stmt.Exec(symbol, a.Date, a.Type, ratio, amount); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addSymbol adds a symbol missing from the input list without a name
+func addSymbol(tx *sql.Tx, symbol string) error {
+	_, err := tx.Exec(`INSERT INTO symbols (symbol) VALUES (?) ON CONFLICT (symbol) DO NOTHING`, symbol)
+	return err
+}
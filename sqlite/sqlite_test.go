@@ -0,0 +1,134 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/enrich"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/sqlite"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DB", func() {
+	var (
+		dir string
+		db  *DB
+		src = api.Source{Symbol: "FB", DBCode: "WIKI", URL: "https://www.quandl.com/api/v3/datasets/WIKI/FB/data.json", FetchedAt: time.Date(2018, 3, 27, 0, 0, 0, 0, time.UTC)}
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "sqlite")
+		Expect(err).Should(BeNil())
+		db, err = Open(filepath.Join(dir, "quandl.db"))
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("should upsert bars on re-fetch", func() {
+		Expect(db.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-03", 2)})).Should(BeNil())
+		Expect(db.Write(src, []api.Wiki{fixture.Wiki("2018-01-03", 3), fixture.Wiki("2018-01-04", 4)})).Should(BeNil())
+
+		var n int
+		var sum float64
+		Expect(db.DB().QueryRow(`SELECT count(*), sum(close) FROM bars WHERE symbol = 'FB'`).Scan(&n, &sum)).Should(BeNil())
+		Expect(n).Should(Equal(3))
+		Expect(sum).Should(Equal(8.0))
+
+		var url, fetched string
+		Expect(db.DB().QueryRow(`SELECT source_url, fetched_at FROM datasets WHERE db = 'WIKI' AND symbol = 'FB'`).Scan(&url, &fetched)).Should(BeNil())
+		Expect(url).Should(Equal(src.URL))
+		Expect(fetched).Should(Equal("2018-03-27T00:00:00Z"))
+	})
+
+	It("should save the enrich fields of bars", func() {
+		e, err := enrich.New(enrich.Options{Fields: []string{enrich.ISOWeek, enrich.MonthEnd}})
+		Expect(err).Should(BeNil())
+		objs := []api.Wiki{fixture.Wiki("2018-01-31", 1), fixture.Wiki("2018-02-01", 2)}
+		Expect(api.EnrichWiki(e, "WIKI", objs)).Should(BeNil())
+		Expect(db.Write(src, objs)).Should(BeNil())
+
+		var week string
+		var monthEnd int
+		var quarter sql.NullInt64
+		Expect(db.DB().QueryRow(`SELECT iso_week, month_end, quarter FROM bars WHERE date = '2018-01-31'`).Scan(&week, &monthEnd, &quarter)).Should(BeNil())
+		Expect(week).Should(Equal("2018-W05"))
+		Expect(monthEnd).Should(Equal(1))
+		Expect(quarter.Valid).Should(BeFalse())
+	})
+
+	It("should add the enrich columns to an older bars table", func() {
+		name := filepath.Join(dir, "old.db")
+		old, err := sql.Open(Driver, name)
+		Expect(err).Should(BeNil())
+		_, err = old.Exec(`CREATE TABLE bars (symbol TEXT NOT NULL, date TEXT NOT NULL, day_of_week TEXT, open REAL, high REAL, low REAL,
+			close REAL, volume REAL, ex_dividend REAL, split_ratio REAL, adj_open REAL, adj_high REAL, adj_low REAL, adj_close REAL,
+			adj_volume REAL, PRIMARY KEY (symbol, date))`)
+		Expect(err).Should(BeNil())
+		Expect(old.Close()).Should(BeNil())
+
+		upgraded, err := Open(name)
+		Expect(err).Should(BeNil())
+		defer upgraded.Close()
+		Expect(upgraded.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1)})).Should(BeNil())
+		var n int
+		Expect(upgraded.DB().QueryRow(`SELECT count(*) FROM bars WHERE iso_week IS NULL`).Scan(&n)).Should(BeNil())
+		Expect(n).Should(Equal(1))
+	})
+
+	It("should join bars with symbols and metadata", func() {
+		Expect(db.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1)})).Should(BeNil())
+		Expect(db.Symbols([]api.List{{Symbol: "FB", Name: "Facebook", Sector: "Information Technology"}})).Should(BeNil())
+		Expect(db.Metadata(&api.Metadata{DatabaseCode: fixture.String("WIKI"), DatasetCode: fixture.String("FB"), Name: fixture.String("Facebook Inc. (FB) Prices"),
+			ColumnNames: []*string{fixture.String("Date"), fixture.String("Close")}})).Should(BeNil())
+
+		var sector, name, columns, url string
+		err := db.DB().QueryRow(`SELECT s.sector, d.name, d.column_names, d.source_url
+			FROM bars b JOIN symbols s USING (symbol) JOIN datasets d USING (symbol)
+			WHERE b.date = '2018-01-02'`).Scan(&sector, &name, &columns, &url)
+		Expect(err).Should(BeNil())
+		Expect(sector).Should(Equal("Information Technology"))
+		Expect(name).Should(Equal("Facebook Inc. (FB) Prices"))
+		Expect(columns).Should(Equal("Date,Close"))
+		Expect(url).Should(Equal(src.URL))
+	})
+
+	It("should upsert corporate actions", func() {
+		acts := []corporate.Action{
+			{Symbol: "AAPL", Date: "2014-06-09", Type: corporate.Split, Ratio: 7},
+			{Symbol: "AAPL", Date: "2014-08-07", Type: corporate.Dividend, Amount: 0.47},
+		}
+		Expect(db.Actions("AAPL", acts)).Should(BeNil())
+		Expect(db.Actions("AAPL", acts[1:])).Should(BeNil())
+
+		var n int
+		var ratio float64
+		Expect(db.DB().QueryRow(`SELECT count(*), sum(ratio) FROM actions WHERE symbol = 'AAPL'`).Scan(&n, &ratio)).Should(BeNil())
+		Expect(n).Should(Equal(2))
+		Expect(ratio).Should(Equal(7.0))
+	})
+
+	It("should keep the data when reopened", func() {
+		Expect(db.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1)})).Should(BeNil())
+		Expect(db.Close()).Should(BeNil())
+
+		var err error
+		db, err = Open(filepath.Join(dir, "quandl.db"))
+		Expect(err).Should(BeNil())
+		var n int
+		Expect(db.DB().QueryRow(`SELECT count(*) FROM bars`).Scan(&n)).Should(BeNil())
+		Expect(n).Should(Equal(1))
+	})
+})
@@ -0,0 +1,145 @@
+package sqlsink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect writes the SQL that differs between databases
+type Dialect interface {
+	// Quote quotes an identifier
+	Quote(name string) string
+	// Placeholder returns the n-th bind parameter, counting from 1
+	Placeholder(n int) string
+	// Type returns the column type of a key, date, text or float column
+	Type(kind Kind) string
+	// Upsert returns the conflict clause updating cols when a row with the
+	// same keys exists
+	Upsert(keys, cols []string) string
+}
+
+// Kind is the kind of value a column holds
+type Kind int
+
+const (
+	Key Kind = iota
+	// Date is the date key, a YYYY-MM-DD string
+	Date
+	Text
+	Float
+)
+
+// Postgres is the dialect of PostgreSQL and TimescaleDB
+var Postgres Dialect = postgres{}
+
+// MySQL is the dialect of MySQL and MariaDB
+var MySQL Dialect = mysql{}
+
+// SQLite is the dialect of SQLite 3.24 and later
+var SQLite Dialect = sqlite{}
+
+// Dialects by name
+var Dialects = map[string]Dialect{
+	"postgres": Postgres,
+	"mysql":    MySQL,
+	"sqlite":   SQLite,
+}
+
+type postgres struct{}
+
+func (postgres) Quote(name string) string { return `"` + strings.Replace(name, `"`, `""`, -1) + `"` }
+
+func (postgres) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgres) Type(kind Kind) string {
+	switch kind {
+	case Date:
+		return "DATE"
+	case Float:
+		return "DOUBLE PRECISION"
+	}
+	return "TEXT"
+}
+
+func (d postgres) Upsert(keys, cols []string) string {
+	return onConflict(d, keys, cols, "EXCLUDED.")
+}
+
+type mysql struct{}
+
+func (mysql) Quote(name string) string { return "`" + strings.Replace(name, "`", "``", -1) + "`" }
+
+func (mysql) Placeholder(int) string { return "?" }
+
+func (mysql) Type(kind Kind) string {
+	switch kind {
+	case Key:
+		// keys need a length in an index
+		return "VARCHAR(64)"
+	case Date:
+		return "DATE"
+	case Float:
+		return "DOUBLE"
+	}
+	return "TEXT"
+}
+
+func (d mysql) Upsert(keys, cols []string) string {
+	set := make([]string, 0, len(cols))
+	for _, c := range cols {
+		if !contains(keys, c) {
+			set = append(set, fmt.Sprintf("%s = VALUES(%s)", d.Quote(c), d.Quote(c)))
+		}
+	}
+	if len(set) == 0 {
+		// nothing to update, keep the row
+		c := d.Quote(keys[0])
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", c, c)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+}
+
+type sqlite struct{}
+
+func (sqlite) Quote(name string) string { return postgres{}.Quote(name) }
+
+func (sqlite) Placeholder(int) string { return "?" }
+
+func (sqlite) Type(kind Kind) string {
+	switch kind {
+	case Float:
+		return "REAL"
+	}
+	return "TEXT"
+}
+
+func (d sqlite) Upsert(keys, cols []string) string {
+	return onConflict(d, keys, cols, "excluded.")
+}
+
+// onConflict is the upsert of postgres and sqlite
+func onConflict(d Dialect, keys, cols []string, excluded string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = d.Quote(k)
+	}
+	set := make([]string, 0, len(cols))
+	for _, c := range cols {
+		if !contains(keys, c) {
+			set = append(set, fmt.Sprintf("%s = %s%s", d.Quote(c), excluded, d.Quote(c)))
+		}
+	}
+	if len(set) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(quoted, ", "))
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quoted, ", "), strings.Join(set, ", "))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
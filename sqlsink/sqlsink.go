@@ -0,0 +1,249 @@
+// Package sqlsink saves data sets to any database/sql database. Each
+// database code gets a table, e.g. wiki or cboe, created on the first write
+// with the columns of the data set and keyed by symbol and date. Rows are
+// inserted in batches and replace rows of the same symbol and date.
+//
+//	db, err := sql.Open("postgres", dsn)
+//	sink := sqlsink.New(db, sqlsink.Postgres)
+//	err = sink.Write(ds.Source, ds.Data.([]api.Wiki))
+package sqlsink
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/logging"
+)
+
+// maxParams keeps a batch within the bind parameter limit of every dialect
+const maxParams = 999
+
+// Sink writes tables to a database. It is an api.Sink and safe for
+// concurrent use.
+type Sink struct {
+	DB      *sql.DB
+	Dialect Dialect
+	// Table names the table of a database code, defaults to its lower case
+	Table func(dbCode string) string
+	// BatchSize is the number of rows per insert, defaults to as many as
+	// fit in 999 bind parameters
+	BatchSize int
+	Log       logging.Logger
+
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+// New returns a sink writing to db in dialect d
+func New(db *sql.DB, d Dialect) *Sink {
+	return &Sink{DB: db, Dialect: d}
+}
+
+// Write saves WIKI rows
+func (s *Sink) Write(src api.Source, objs []api.Wiki) error {
+	t := api.WikiTable(src.Symbol, objs)
+	if src.DBCode != "" {
+		t.DBCode = src.DBCode
+	}
+	return s.WriteTable(t)
+}
+
+// WriteCBOE saves CBOE rows
+func (s *Sink) WriteCBOE(src api.Source, objs []api.CBOE) error {
+	t := api.CBOETable(src.Symbol, objs)
+	if src.DBCode != "" {
+		t.DBCode = src.DBCode
+	}
+	return s.WriteTable(t)
+}
+
+// WriteTable saves the rows of t. Its first column is the date, rows without
+// one are skipped and of rows with the same date the last one is saved, as
+// Postgres cannot update a row twice in one insert.
+func (s *Sink) WriteTable(t *api.Table) error {
+	rows := make([][]interface{}, 0, len(t.Rows))
+	seen := make(map[interface{}]int, len(t.Rows))
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) || row[0] == nil {
+			continue
+		}
+		if i, ok := seen[row[0]]; ok {
+			rows[i] = row
+			continue
+		}
+		seen[row[0]] = len(rows)
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	l, start := logging.OrDiscard(s.Log), time.Now()
+
+	table := strings.ToLower(t.DBCode)
+	if s.Table != nil {
+		table = s.Table(t.DBCode)
+	}
+	if table == "" {
+		return fmt.Errorf("table %s has no database code", t.Symbol)
+	}
+
+	cols := append([]string{"symbol"}, Columns(t.Columns)...)
+	keys := cols[:2]
+	if err := s.create(table, keys, cols, t); err != nil {
+		return err
+	}
+
+	size := s.BatchSize
+	if size <= 0 || size*len(cols) > maxParams {
+		size = maxParams / len(cols)
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := 0; i < len(rows); i += size {
+		end := i + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		args := make([]interface{}, 0, (end-i)*len(cols))
+		for _, row := range rows[i:end] {
+			args = append(args, t.Symbol)
+			args = append(args, row...)
+		}
+		if _, err := tx.Exec(s.insert(table, keys, cols, end-i), args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.Info("Wrote rows.", logging.Symbol, t.Symbol, logging.DBCode, t.DBCode, "table", table,
+		logging.Rows, len(rows), logging.Duration, time.Since(start))
+	return nil
+}
+
+// create creates the table once
+func (s *Sink) create(table string, keys, cols []string, t *api.Table) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created[table] {
+		return nil
+	}
+
+	d := s.Dialect
+	defs := make([]string, 0, len(cols)+1)
+	for i, c := range cols {
+		kind := Key
+		switch {
+		case i == 1:
+			kind = Date
+		case i >= len(keys):
+			kind = kindOf(t, i-1)
+		}
+		def := d.Quote(c) + " " + d.Type(kind)
+		if kind == Key || kind == Date {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = d.Quote(k)
+	}
+	defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quoted, ", ")))
+
+	q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", d.Quote(table), strings.Join(defs, ",\n\t"))
+	if _, err := s.DB.Exec(q); err != nil {
+		return err
+	}
+	if s.created == nil {
+		s.created = map[string]bool{}
+	}
+	s.created[table] = true
+	return nil
+}
+
+// insert returns the upsert of n rows
+func (s *Sink) insert(table string, keys, cols []string, n int) string {
+	d := s.Dialect
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = d.Quote(c)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", d.Quote(table), strings.Join(quoted, ", "))
+	p := 1
+	for r := 0; r < n; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for c := range cols {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.Placeholder(p))
+			p++
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(" ")
+	b.WriteString(d.Upsert(keys, cols))
+	return b.String()
+}
+
+// kindOf returns Text if column i holds strings and Float otherwise
+func kindOf(t *api.Table, i int) Kind {
+	for _, row := range t.Rows {
+		if i >= len(row) || row[i] == nil {
+			continue
+		}
+		if _, ok := row[i].(string); ok {
+			return Text
+		}
+		return Float
+	}
+	return Float
+}
+
+// Columns returns column names as lower snake case identifiers, e.g.
+// "Adj. Close" and "AdjClose" both become adj_close
+func Columns(names []string) []string {
+	cols := make([]string, len(names))
+	for i, name := range names {
+		cols[i] = column(name)
+	}
+	return cols
+}
+
+func column(name string) string {
+	var b strings.Builder
+	rs := []rune(name)
+	for i, r := range rs {
+		switch {
+		case unicode.IsUpper(r):
+			// start a word at an upper case letter after a lower case one
+			if i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
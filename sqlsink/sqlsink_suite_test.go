@@ -0,0 +1,13 @@
+package sqlsink_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSqlsink(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sqlsink Suite")
+}
@@ -0,0 +1,113 @@
+package sqlsink_test
+
+import (
+	"database/sql"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/sqlsink"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	_ "modernc.org/sqlite"
+)
+
+var _ = Describe("Sink", func() {
+	var (
+		db   *sql.DB
+		sink *Sink
+		src  = api.Source{Symbol: "FB", DBCode: "WIKI"}
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("sqlite", ":memory:")
+		Expect(err).Should(BeNil())
+		db.SetMaxOpenConns(1)
+		sink = New(db, SQLite)
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should create the table and upsert wiki rows in batches", func() {
+		sink.BatchSize = 2
+		Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-03", 2), fixture.Wiki("2018-01-04", 3)})).Should(BeNil())
+		Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-04", 4), fixture.Wiki("2018-01-05", 5)})).Should(BeNil())
+
+		var n int
+		var sum float64
+		Expect(db.QueryRow(`SELECT count(*), sum(close) FROM wiki WHERE symbol = 'FB'`).Scan(&n, &sum)).Should(BeNil())
+		Expect(n).Should(Equal(4))
+		Expect(sum).Should(Equal(12.0))
+	})
+
+	It("should save the last of rows with the same date", func() {
+		sink.BatchSize = 2
+		rows := []api.Wiki{fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-03", 2), fixture.Wiki("2018-01-02", 3), fixture.Wiki("2018-01-03", 4), fixture.Wiki("2018-01-04", 5)}
+		Expect(sink.Write(src, rows)).Should(BeNil())
+
+		var n int
+		var sum float64
+		Expect(db.QueryRow(`SELECT count(*), sum(close) FROM wiki WHERE symbol = 'FB'`).Scan(&n, &sum)).Should(BeNil())
+		Expect(n).Should(Equal(3))
+		Expect(sum).Should(Equal(12.0))
+	})
+
+	It("should write cboe rows", func() {
+		settle := 12.5
+		rows := []api.CBOE{{TradeDate: fixture.String("2018-01-02"), Settle: &settle}}
+		Expect(sink.WriteCBOE(api.Source{Symbol: "VX1", DBCode: "CBOE"}, rows)).Should(BeNil())
+
+		var got float64
+		Expect(db.QueryRow(`SELECT settle FROM cboe WHERE symbol = 'VX1' AND trade_date = '2018-01-02'`).Scan(&got)).Should(BeNil())
+		Expect(got).Should(Equal(12.5))
+	})
+
+	It("should write generic tables with text columns", func() {
+		t := &api.Table{
+			Symbol:  "USD",
+			DBCode:  "FRED",
+			Columns: []string{"Date", "Value", "Note"},
+			Rows:    [][]interface{}{{"2018-01-02", 1.5, "a"}, {"2018-01-03", nil, "b"}, {nil, 2.0, "skipped"}},
+		}
+		sink.Table = func(db string) string { return "series_" + db }
+		Expect(sink.WriteTable(t)).Should(BeNil())
+
+		var n int
+		Expect(db.QueryRow(`SELECT count(*) FROM series_FRED WHERE note IN ('a', 'b')`).Scan(&n)).Should(BeNil())
+		Expect(n).Should(Equal(2))
+	})
+
+	Describe("Columns", func() {
+		It("should name columns in snake case", func() {
+			Expect(Columns([]string{"Adj. Close", "AdjClose", "Ex-Dividend", "Prev. Day Open Interest", "EFP", "DayOfWeek", "Trade Date"})).
+				Should(Equal([]string{"adj_close", "adj_close", "ex_dividend", "prev_day_open_interest", "efp", "day_of_week", "trade_date"}))
+		})
+	})
+
+	Describe("Dialects", func() {
+		keys, cols := []string{"symbol", "date"}, []string{"symbol", "date", "close"}
+
+		It("should write the upsert of each database", func() {
+			Expect(Postgres.Upsert(keys, cols)).Should(Equal(`ON CONFLICT ("symbol", "date") DO UPDATE SET "close" = EXCLUDED."close"`))
+			Expect(SQLite.Upsert(keys, cols)).Should(Equal(`ON CONFLICT ("symbol", "date") DO UPDATE SET "close" = excluded."close"`))
+			Expect(MySQL.Upsert(keys, cols)).Should(Equal("ON DUPLICATE KEY UPDATE `close` = VALUES(`close`)"))
+		})
+
+		It("should number postgres placeholders", func() {
+			Expect(Postgres.Placeholder(3)).Should(Equal("$3"))
+			Expect(MySQL.Placeholder(3)).Should(Equal("?"))
+			Expect(MySQL.Type(Key)).Should(Equal("VARCHAR(64)"))
+		})
+
+		It("should type the date key as a date where there is one", func() {
+			Expect(Postgres.Type(Date)).Should(Equal("DATE"))
+			Expect(MySQL.Type(Date)).Should(Equal("DATE"))
+			Expect(SQLite.Type(Date)).Should(Equal("TEXT"))
+			Expect(Postgres.Type(Key)).Should(Equal("TEXT"))
+		})
+	})
+})
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/manifest"
+	"github.com/xitongsys/parquet-go/ParquetFile"
+	"github.com/xitongsys/parquet-go/ParquetWriter"
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+// Compaction layouts
+const (
+	// BySymbol writes output/<SYMBOL>/<SYMBOL>.parquet with a row group per year
+	BySymbol = "symbol"
+	// ByYear writes output/<SYMBOL>/<YYYY>.parquet
+	ByYear = "year"
+)
+
+type CompactOptions struct {
+	// By is BySymbol or ByYear, defaults to BySymbol
+	By string
+	// Keep leaves the daily files in place
+	Keep bool
+}
+
+// Compact merges the files of symbol into parquet files sorted by date. The
+// manifest is updated before the daily files are removed, so an interrupted
+// compaction leaves either the old or the new layout readable.
+func Compact(path, symbol string, opts CompactOptions) ([]string, error) {
+	if opts.By == "" {
+		opts.By = BySymbol
+	}
+	if opts.By != BySymbol && opts.By != ByYear {
+		return nil, fmt.Errorf("unknown compaction layout %q", opts.By)
+	}
+
+	dir := symbolDir(path, symbol)
+	man, err := manifest.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := Read(path, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, nil
+	}
+
+	// group rows by output file, rows are sorted by date
+	groups := map[string][]api.Wiki{}
+	names := []string{}
+	for _, obj := range objs {
+		name := symbol + ".parquet"
+		if opts.By == ByYear {
+			name = (*obj.Date)[:4] + ".parquet"
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], obj)
+	}
+
+	old := map[string]bool{}
+	for name := range man.Files {
+		old[name] = true
+	}
+
+	for _, name := range names {
+		rows := groups[name]
+		full := filepath.Join(dir, name)
+		tmp := atomicfile.TempName(full)
+		err := WriteParquet(tmp, rows)
+		if err == nil {
+			err = atomicfile.Commit(tmp, full)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return nil, err
+		}
+
+		size, sum, err := manifest.SumFile(full)
+		if err != nil {
+			return nil, err
+		}
+		man.Add(name, manifest.Entry{
+			Rows:      len(rows),
+			FirstDate: *rows[0].Date,
+			LastDate:  *rows[len(rows)-1].Date,
+			Size:      size,
+			SHA256:    sum,
+			FetchedAt: man.FetchedAt,
+		})
+		delete(old, name)
+	}
+
+	if opts.Keep {
+		return names, man.Save(dir)
+	}
+
+	// commit the new layout before removing the files it replaces
+	for name := range old {
+		delete(man.Files, name)
+	}
+	if err := man.Save(dir); err != nil {
+		return nil, err
+	}
+	for name := range old {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// daily files the manifest did not know about are merged as well
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range infos {
+		if isDataFile(fi.Name()) && isDaily(fi.Name()) {
+			if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return names, nil
+}
+
+// WriteParquet writes rows to a snappy compressed parquet file, starting a
+// new row group every year so that readers can skip years by their statistics
+func WriteParquet(name string, rows []api.Wiki) error {
+	fw, err := ParquetFile.NewLocalFileWriter(name)
+	if err != nil {
+		return err
+	}
+
+	pw, err := ParquetWriter.NewParquetWriter(fw, new(api.Wiki), 4)
+	if err != nil {
+		fw.Close()
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for i, obj := range rows {
+		if i > 0 && (*rows[i-1].Date)[:4] != (*obj.Date)[:4] {
+			if err = pw.Flush(true); err != nil {
+				break
+			}
+		}
+		if err = pw.Write(obj); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = pw.WriteStop()
+	}
+	if cerr := fw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compact", func() {
+	var (
+		path string
+		dir  string
+		sink *api.JSONFiles
+		src  = api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: time.Now()}
+	)
+
+	dates := func(objs []api.Wiki) []string {
+		out := []string{}
+		for _, obj := range objs {
+			out = append(out, *obj.Date)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "compact")
+		Expect(err).Should(BeNil())
+		dir = filepath.Join(path, "output", "FB")
+		sink = &api.JSONFiles{Path: path}
+
+		err = sink.Write(src, []api.Wiki{fixture.Wiki("2017-12-28", 1), fixture.Wiki("2017-12-29", 2), fixture.Wiki("2018-01-02", 3), fixture.Wiki("2018-01-03", 4)})
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path)
+	})
+
+	Context("by symbol", func() {
+		It("should replace the daily files with one parquet file", func() {
+			names, err := Compact(path, "FB", CompactOptions{})
+			Expect(err).Should(BeNil())
+			Expect(names).Should(Equal([]string{"FB.parquet"}))
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).Should(BeNil())
+			Expect(len(files)).Should(Equal(2))
+
+			objs, err := Read(path, "FB")
+			Expect(err).Should(BeNil())
+			Expect(dates(objs)).Should(Equal([]string{"2017-12-28", "2017-12-29", "2018-01-02", "2018-01-03"}))
+			Expect(*objs[3].Close).Should(Equal(4.0))
+
+			r, err := Verify(path, "FB", VerifyOptions{})
+			Expect(err).Should(BeNil())
+			Expect(r.OK()).Should(BeTrue())
+		})
+
+		It("should only write days missing from the compacted file", func() {
+			_, err := Compact(path, "FB", CompactOptions{})
+			Expect(err).Should(BeNil())
+			Expect(sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-03", 5), fixture.Wiki("2018-01-04", 6)})).Should(BeNil())
+
+			objs, err := Read(path, "FB")
+			Expect(err).Should(BeNil())
+			Expect(dates(objs)).Should(Equal([]string{"2017-12-28", "2017-12-29", "2018-01-02", "2018-01-03", "2018-01-04"}))
+			Expect(*objs[3].Close).Should(Equal(4.0))
+			_, err = os.Stat(filepath.Join(dir, "2018-01-03.json"))
+			Expect(os.IsNotExist(err)).Should(BeTrue())
+		})
+	})
+
+	Context("by year", func() {
+		It("should write a parquet file per year and keep the daily files", func() {
+			names, err := Compact(path, "FB", CompactOptions{By: ByYear, Keep: true})
+			Expect(err).Should(BeNil())
+			Expect(names).Should(Equal([]string{"2017.parquet", "2018.parquet"}))
+
+			_, err = os.Stat(filepath.Join(dir, "2017-12-28.json"))
+			Expect(err).Should(BeNil())
+
+			objs, err := Read(path, "FB")
+			Expect(err).Should(BeNil())
+			Expect(len(objs)).Should(Equal(4))
+		})
+	})
+
+	It("should report the range of a broken compacted file as a gap", func() {
+		_, err := Compact(path, "FB", CompactOptions{})
+		Expect(err).Should(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "FB.parquet"), []byte("broken"), 0644)).Should(BeNil())
+
+		r, err := Verify(path, "FB", VerifyOptions{})
+		Expect(err).Should(BeNil())
+		Expect(r.OK()).Should(BeFalse())
+		Expect(r.Gaps()).Should(Equal([]Range{{Start: "2017-12-28", End: "2018-01-03"}}))
+	})
+
+	It("should reject unknown layouts", func() {
+		_, err := Compact(path, "FB", CompactOptions{By: "month"})
+		Expect(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/metrics"
+)
+
+// Partitioned saves rows under Path in the files named by Layout, e.g.
+// layout.Hive. Rows already saved in a partition are merged by date, a new
+// row replacing a saved one. Parquet partitions are snappy compressed, json
+// partitions hold one object per line. Parquet partitions do not save enrich
+// fields, a warning is logged for rows carrying them.
+type Partitioned struct {
+	Path    string
+	Layout  *layout.Template
+	Log     logging.Logger
+	Metrics metrics.Metrics
+}
+
+func (s *Partitioned) Write(src api.Source, objs []api.Wiki) error {
+	l, m, start := logging.OrDiscard(s.Log), metrics.OrDiscard(s.Metrics), time.Now()
+	if s.Layout.Ext() == ".parquet" && api.Enriched(objs) {
+		l.Warn("Enrich fields are not saved in parquet files.", logging.Symbol, src.Symbol)
+	}
+
+	groups := map[string][]api.Wiki{}
+	for _, obj := range objs {
+		if obj.Date == nil {
+			continue
+		}
+		name := s.Layout.Execute(layout.For(src.DBCode, src.Symbol, *obj.Date))
+		groups[name] = append(groups[name], obj)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		full := filepath.Join(s.Path, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+
+		rows := map[string]api.Wiki{}
+		if _, err := os.Stat(full); err == nil {
+			saved, err := readFile(full)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			for _, obj := range saved {
+				rows[*obj.Date] = obj
+			}
+		}
+		for _, obj := range groups[name] {
+			rows[*obj.Date] = obj
+		}
+
+		tmp := atomicfile.TempName(full)
+		err := writePartition(tmp, s.Layout.Ext(), sorted(rows))
+		if err == nil {
+			err = atomicfile.Commit(tmp, full)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		m.Add(metrics.FilesWritten, 1, metrics.Sink, "partitioned")
+		l.Debug("Write finished.", logging.Symbol, src.Symbol, logging.File, name)
+	}
+	l.Info("Wrote partitions.", logging.Symbol, src.Symbol, logging.Rows, len(objs), "files", len(names), logging.Duration, time.Since(start))
+	return nil
+}
+
+func writePartition(name, ext string, rows []api.Wiki) error {
+	if ext == ".parquet" {
+		return WriteParquet(name, rows)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, obj := range rows {
+		if err = enc.Encode(obj); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Partitions returns the files of the layout t under path that can hold rows
+// of symbol, of every symbol if it is ""
+func Partitions(path string, t *layout.Template, symbol string) ([]string, error) {
+	return partitions(path, t, symbol, "", "")
+}
+
+// partitions returns the files of the layout under path matching symbol and
+// overlapping from to to, any of which may be "". Directories whose values
+// rule them out are not walked.
+func partitions(path string, t *layout.Template, symbol, from, to string) ([]string, error) {
+	keep := func(v layout.Vars) bool {
+		return (symbol == "" || v.Symbol == "" || v.Symbol == symbol) && v.Overlaps(from, to)
+	}
+
+	files := []string{}
+	err := filepath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if fi.IsDir() {
+			if v, ok := t.MatchDir(rel); !ok || !keep(v) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if atomicfile.IsTemp(fi.Name()) {
+			return nil
+		}
+		if v, ok := t.Match(rel); ok && keep(v) {
+			files = append(files, name)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// readPartitions returns the rows of symbol from from to to saved in the
+// layout under path, ordered by date
+func readPartitions(path string, t *layout.Template, symbol, from, to string) ([]api.Wiki, error) {
+	files, err := partitions(path, t, symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]api.Wiki{}
+	for _, name := range files {
+		objs, err := readFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for _, obj := range objs {
+			if (from == "" || *obj.Date >= from) && (to == "" || *obj.Date <= to) {
+				rows[*obj.Date] = obj
+			}
+		}
+	}
+	return sorted(rows), nil
+}
+
+// partitionSymbols returns the symbols saved in the layout under path
+func partitionSymbols(path string, t *layout.Template) ([]string, error) {
+	files, err := partitions(path, t, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	symbols := []string{}
+	for _, name := range files {
+		rel, _ := filepath.Rel(path, name)
+		v, _ := t.Match(filepath.ToSlash(rel))
+		if !seen[v.Symbol] {
+			seen[v.Symbol] = true
+			symbols = append(symbols, v.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
@@ -0,0 +1,74 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	"github.com/twold/go-quandl/layout"
+	. "github.com/twold/go-quandl/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Partitioned", func() {
+	var path string
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "partitioned")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path)
+	})
+
+	for _, text := range []string{layout.Hive, "lake/{{.Symbol}}/{{.Year}}-{{.Month}}.json"} {
+		text := text
+
+		Context(text, func() {
+			It("should merge partitions and prune reads", func() {
+				t, err := layout.Parse(text)
+				Expect(err).Should(BeNil())
+				sink := &Partitioned{Path: path, Layout: t}
+
+				fb := api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: time.Now()}
+				Expect(sink.Write(fb, []api.Wiki{fixture.Wiki("2017-12-29", 1), fixture.Wiki("2018-01-02", 2)})).Should(BeNil())
+				Expect(sink.Write(fb, []api.Wiki{fixture.Wiki("2018-01-02", 3), fixture.Wiki("2018-01-03", 4)})).Should(BeNil())
+				aapl := api.Source{Symbol: "AAPL", DBCode: "WIKI", FetchedAt: time.Now()}
+				Expect(sink.Write(aapl, []api.Wiki{fixture.Wiki("2018-01-02", 5)})).Should(BeNil())
+
+				name := filepath.Join(path, filepath.FromSlash(t.Execute(layout.For("WIKI", "FB", "2018-01-02"))))
+				_, err = os.Stat(name)
+				Expect(err).Should(BeNil())
+
+				r, err := OpenLayout(path, t)
+				Expect(err).Should(BeNil())
+				symbols, err := r.Symbols()
+				Expect(err).Should(BeNil())
+				Expect(symbols).Should(Equal([]string{"AAPL", "FB"}))
+
+				objs, err := r.Load("FB", "", "")
+				Expect(err).Should(BeNil())
+				Expect(len(objs)).Should(Equal(3))
+				Expect(*objs[1].Close).Should(Equal(3.0))
+
+				// a broken partition outside the range is never opened
+				old := filepath.Join(path, filepath.FromSlash(t.Execute(layout.For("WIKI", "FB", "2017-12-29"))))
+				Expect(ioutil.WriteFile(old, []byte("broken"), 0644)).Should(BeNil())
+				objs, err = r.Load("FB", "2018-01-03", "")
+				Expect(err).Should(BeNil())
+				Expect(len(objs)).Should(Equal(1))
+				Expect(*objs[0].Close).Should(Equal(4.0))
+
+				_, err = r.Load("FB", "", "")
+				Expect(err).ShouldNot(BeNil())
+			})
+		})
+	}
+})
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/manifest"
+	"github.com/xitongsys/parquet-go/ParquetFile"
+	"github.com/xitongsys/parquet-go/ParquetReader"
+)
+
+// Read returns every row saved for symbol ordered by date. Daily json and
+// parquet files are merged with compacted parquet files; a daily file wins
+// over a compacted row of the same date as it was written after compaction.
+func Read(path, symbol string) ([]api.Wiki, error) {
+	return read(path, symbol, "", "")
+}
+
+// read returns the rows of symbol from from to to, either may be "". Files
+// whose date or recorded range lies outside are not opened.
+func read(path, symbol, from, to string) ([]api.Wiki, error) {
+	dir := symbolDir(path, symbol)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// a broken manifest only turns off pruning, verify reports it
+	man, err := manifest.Load(dir)
+	if err != nil {
+		man = &manifest.Manifest{}
+	}
+	outside := func(first, last string) bool {
+		return (from != "" && last < from) || (to != "" && first > to)
+	}
+
+	rows := map[string]api.Wiki{}
+	daily := map[string]bool{}
+	for _, fi := range infos {
+		if fi.IsDir() || !isDataFile(fi.Name()) {
+			continue
+		}
+		if date := dateOf(fi.Name()); date != "" && outside(date, date) {
+			continue
+		}
+		if e, ok := man.Files[fi.Name()]; ok && e.FirstDate != "" && outside(e.FirstDate, e.LastDate) {
+			continue
+		}
+
+		objs, err := readFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fi.Name(), err)
+		}
+		isDaily := isDaily(fi.Name())
+		for _, obj := range objs {
+			if outside(*obj.Date, *obj.Date) || (daily[*obj.Date] && !isDaily) {
+				continue
+			}
+			rows[*obj.Date] = obj
+			if isDaily {
+				daily[*obj.Date] = true
+			}
+		}
+	}
+	return sorted(rows), nil
+}
+
+// isDataFile reports whether name holds rows, as opposed to the manifest or
+// a temporary file
+func isDataFile(name string) bool {
+	if name == manifest.Name || atomicfile.IsTemp(name) {
+		return false
+	}
+	ext := filepath.Ext(name)
+	return ext == ".json" || ext == ".parquet"
+}
+
+// isDaily reports whether name is a file of a single day, <YYYY-MM-DD>.<ext>
+func isDaily(name string) bool {
+	return dateOf(name) != ""
+}
+
+// dateOf returns the date of a daily file name, or ""
+func dateOf(name string) string {
+	date := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if len(date) != len(DateLayout) || date[4] != '-' || date[7] != '-' {
+		return ""
+	}
+	return date
+}
+
+// readFile returns the rows of a json or parquet file
+func readFile(name string) ([]api.Wiki, error) {
+	var (
+		objs []api.Wiki
+		err  error
+	)
+	switch filepath.Ext(name) {
+	case ".json":
+		objs, err = readJSON(name)
+	case ".parquet":
+		objs, err = readParquet(name)
+	default:
+		err = fmt.Errorf("unknown file type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range objs {
+		if obj.Date == nil {
+			return nil, fmt.Errorf("missing Date")
+		}
+	}
+	if date := dateOf(name); date != "" && len(objs) == 1 && *objs[0].Date != date {
+		return nil, fmt.Errorf("Date %s does not match file name", *objs[0].Date)
+	}
+	return objs, nil
+}
+
+// readJSON reads a daily file holding one object or a partition holding one
+// object per line
+func readJSON(name string) ([]api.Wiki, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj api.Wiki
+	err = json.Unmarshal(b, &obj)
+	if err == nil {
+		return []api.Wiki{obj}, nil
+	}
+
+	objs := []api.Wiki{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for dec.More() {
+		var obj api.Wiki
+		if dec.Decode(&obj) != nil {
+			// report why the file is not a single object
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	if len(objs) < 2 {
+		return nil, err
+	}
+	return objs, nil
+}
+
+func readParquet(name string) ([]api.Wiki, error) {
+	fr, err := ParquetFile.NewLocalFileReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+
+	pr, err := ParquetReader.NewParquetReader(fr, new(api.Wiki), 4)
+	if err != nil {
+		return nil, err
+	}
+	defer pr.ReadStop()
+
+	objs := make([]api.Wiki, pr.GetNumRows())
+	if err := pr.Read(&objs); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+func sorted(rows map[string]api.Wiki) []api.Wiki {
+	objs := make([]api.Wiki, 0, len(rows))
+	for _, obj := range rows {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool { return *objs[i].Date < *objs[j].Date })
+	return objs
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/manifest"
+)
+
+// Reader loads saved data sets back into memory, whichever sink or
+// compaction layout wrote them
+type Reader struct {
+	path   string
+	layout *layout.Template
+}
+
+// Open returns a reader of the data saved under path/output
+func Open(path string) (*Reader, error) {
+	fi, err := os.Stat(filepath.Join(path, "output"))
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", filepath.Join(path, "output"))
+	}
+	return &Reader{path: path}, nil
+}
+
+// OpenLayout returns a reader of the files saved under path by a
+// Partitioned sink with layout t. Loads only walk the partitions that can
+// hold the symbol and dates asked for.
+func OpenLayout(path string, t *layout.Template) (*Reader, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+	return &Reader{path: path, layout: t}, nil
+}
+
+// Symbols returns the saved symbols
+func (r *Reader) Symbols() ([]string, error) {
+	if r.layout != nil {
+		return partitionSymbols(r.path, r.layout)
+	}
+	return Symbols(r.path)
+}
+
+// Range returns the first and last saved date of symbol, or nil if nothing is
+// saved. It is read from the manifest when there is one.
+func (r *Reader) Range(symbol string) (*Range, error) {
+	if r.layout != nil {
+		return r.scan(symbol)
+	}
+	man, err := manifest.Load(symbolDir(r.path, symbol))
+	if err != nil {
+		return nil, err
+	}
+	if man.FirstDate != "" {
+		return &Range{Start: man.FirstDate, End: man.LastDate}, nil
+	}
+	return r.scan(symbol)
+}
+
+// scan returns the range of symbol by loading all of its rows
+func (r *Reader) scan(symbol string) (*Range, error) {
+	objs, err := r.Load(symbol, "", "")
+	if err != nil || len(objs) == 0 {
+		return nil, err
+	}
+	return &Range{Start: *objs[0].Date, End: *objs[len(objs)-1].Date}, nil
+}
+
+// Ranges returns the saved date range of every symbol
+func (r *Reader) Ranges() (map[string]Range, error) {
+	symbols, err := r.Symbols()
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make(map[string]Range, len(symbols))
+	for _, symbol := range symbols {
+		rg, err := r.Range(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", symbol, err)
+		}
+		if rg != nil {
+			ranges[symbol] = *rg
+		}
+	}
+	return ranges, nil
+}
+
+// Source returns where the saved rows of symbol were fetched from, as far as
+// the manifest knows
+func (r *Reader) Source(symbol string) (api.Source, error) {
+	src := api.Source{Symbol: symbol}
+	if r.layout != nil {
+		return src, nil
+	}
+	man, err := manifest.Load(symbolDir(r.path, symbol))
+	if err != nil {
+		return src, err
+	}
+	src.DBCode, src.URL, src.FetchedAt = man.DBCode, man.SourceURL, man.FetchedAt
+	return src, nil
+}
+
+// Load returns the rows of symbol dated from from to to inclusive, ordered
+// by date. An empty from or to leaves that end open.
+func (r *Reader) Load(symbol, from, to string) ([]api.Wiki, error) {
+	if r.layout != nil {
+		return readPartitions(r.path, r.layout, symbol, from, to)
+	}
+	return read(r.path, symbol, from, to)
+}
+
+// LoadTable is Load returning a table
+func (r *Reader) LoadTable(symbol, from, to string) (*api.Table, error) {
+	objs, err := r.Load(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.WikiTable(symbol, objs), nil
+}
+
+// Actions returns the splits and dividends of symbol with an ex-date from
+// from to to inclusive, ordered by date
+func (r *Reader) Actions(symbol, from, to string) ([]corporate.Action, error) {
+	objs, err := r.Load(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return corporate.Extract(symbol, objs), nil
+}
@@ -0,0 +1,106 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/corporate"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reader", func() {
+	var (
+		path string
+		r    *Reader
+	)
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "reader")
+		Expect(err).Should(BeNil())
+
+		fb := api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: time.Now()}
+		err = (&api.JSONFiles{Path: path}).Write(fb, []api.Wiki{fixture.Wiki("2017-12-28", 1), fixture.Wiki("2017-12-29", 2), fixture.Wiki("2018-01-02", 3)})
+		Expect(err).Should(BeNil())
+		_, err = Compact(path, "FB", CompactOptions{By: ByYear})
+		Expect(err).Should(BeNil())
+		err = (&api.JSONFiles{Path: path}).Write(fb, []api.Wiki{fixture.Wiki("2018-01-03", 4)})
+		Expect(err).Should(BeNil())
+
+		aapl := api.Source{Symbol: "AAPL", DBCode: "WIKI", FetchedAt: time.Now()}
+		err = (&api.ParquetFiles{Path: path}).Write(aapl, []api.Wiki{fixture.Wiki("2018-01-02", 5), fixture.Wiki("2018-01-03", 6)})
+		Expect(err).Should(BeNil())
+
+		r, err = Open(path)
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path)
+	})
+
+	It("should fail without an output folder", func() {
+		_, err := Open(path + "/missing")
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should list symbols and their date ranges", func() {
+		symbols, err := r.Symbols()
+		Expect(err).Should(BeNil())
+		Expect(symbols).Should(Equal([]string{"AAPL", "FB"}))
+
+		ranges, err := r.Ranges()
+		Expect(err).Should(BeNil())
+		Expect(ranges).Should(Equal(map[string]Range{
+			"AAPL": {Start: "2018-01-02", End: "2018-01-03"},
+			"FB":   {Start: "2017-12-28", End: "2018-01-03"},
+		}))
+	})
+
+	It("should load a date range across layouts", func() {
+		objs, err := r.Load("FB", "2017-12-29", "2018-01-03")
+		Expect(err).Should(BeNil())
+		Expect(len(objs)).Should(Equal(3))
+		Expect(*objs[0].Date).Should(Equal("2017-12-29"))
+		Expect(*objs[2].Close).Should(Equal(4.0))
+
+		objs, err = r.Load("AAPL", "", "2018-01-02")
+		Expect(err).Should(BeNil())
+		Expect(len(objs)).Should(Equal(1))
+		Expect(*objs[0].Close).Should(Equal(5.0))
+	})
+
+	It("should load a table", func() {
+		t, err := r.LoadTable("FB", "2018-01-01", "")
+		Expect(err).Should(BeNil())
+		Expect(t.Symbol).Should(Equal("FB"))
+		Expect(t.Columns).Should(Equal(api.WikiColumns))
+		Expect(t.Rows[0][t.Column("Date")]).Should(Equal("2018-01-02"))
+		Expect(t.Rows[1][t.Column("Close")]).Should(Equal(4.0))
+		Expect(t.Rows[1][t.Column("Open")]).Should(BeNil())
+	})
+
+	It("should query corporate actions", func() {
+		split, div := fixture.Wiki("2018-01-04", 2), fixture.Wiki("2018-01-05", 2)
+		ratio, amount := 2.0, 0.5
+		split.SplitRatio, div.ExDividend = &ratio, &amount
+		err := (&api.JSONFiles{Path: path}).Write(api.Source{Symbol: "FB", DBCode: "WIKI"}, []api.Wiki{split, div})
+		Expect(err).Should(BeNil())
+
+		acts, err := r.Actions("FB", "", "")
+		Expect(err).Should(BeNil())
+		Expect(acts).Should(Equal([]corporate.Action{
+			{Symbol: "FB", Date: "2018-01-04", Type: corporate.Split, Ratio: 2},
+			{Symbol: "FB", Date: "2018-01-05", Type: corporate.Dividend, Amount: 0.5},
+		}))
+		acts, err = r.Actions("FB", "2018-01-05", "")
+		Expect(err).Should(BeNil())
+		Expect(len(acts)).Should(Equal(1))
+	})
+})
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/manifest"
+)
+
+// Fetcher returns the rows of symbol from start to end, e.g. an api.Getter
+// created with api.DateRange
+type Fetcher func(symbol, start, end string) (*api.DataSet, error)
+
+// Repair removes the broken files listed in r and fetches the gaps again,
+// writing them with sink. It returns the ranges fetched.
+func Repair(path string, r *Report, fetch Fetcher, sink api.Sink) ([]Range, error) {
+	dir := symbolDir(path, r.Symbol)
+	man, err := manifest.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// drop broken files so that the sink does not skip them
+	for _, p := range r.Problems {
+		switch p.Kind {
+		case Unreadable, Unrecorded, Corrupt, Lost, Leftover:
+			if err := os.Remove(filepath.Join(dir, p.File)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			delete(man.Files, p.File)
+		}
+	}
+	if err := man.Save(dir); err != nil {
+		return nil, err
+	}
+
+	gaps := r.Gaps()
+	for _, g := range gaps {
+		ds, err := fetch(r.Symbol, g.Start, g.End)
+		if err != nil {
+			return nil, err
+		}
+		objs, _ := ds.Data.([]api.Wiki)
+		if err := sink.Write(ds.Source, objs); err != nil {
+			return nil, err
+		}
+	}
+	return gaps, nil
+}
@@ -0,0 +1,308 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/layout"
+	"github.com/twold/go-quandl/logging"
+	"github.com/twold/go-quandl/manifest"
+	"github.com/twold/go-quandl/metrics"
+)
+
+// RevisionDir is the folder under the data path holding one revision log per
+// symbol, <SYMBOL>.ndjson
+const RevisionDir = "revisions"
+
+// Revision records a saved row restated by a later fetch. Revisions are
+// bitemporal: Date is the trading day the values are valid for, KnownFrom
+// and FetchedAt are when the old and the new values were fetched. The values
+// first saved for a date are version 1, every restatement adds one.
+type Revision struct {
+	Symbol    string    `json:"symbol" type:"string"`
+	Date      string    `json:"date" type:"string"`
+	Version   int       `json:"version" type:"int"`
+	Fields    []string  `json:"fields" type:"list"`
+	Old       api.Wiki  `json:"old" type:"struct"`
+	New       api.Wiki  `json:"new" type:"struct"`
+	KnownFrom time.Time `json:"known_from" type:"time"`
+	FetchedAt time.Time `json:"fetched_at" type:"time"`
+}
+
+// Changes returns the columns whose values differ between old and new,
+// named as in api.WikiColumns. Enriched fields are derived and not compared.
+func Changes(old, new api.Wiki) []string {
+	t := api.WikiTable("", []api.Wiki{old, new})
+	fields := []string{}
+	for i, name := range t.Columns[:len(api.WikiColumns)] {
+		if t.Rows[0][i] != t.Rows[1][i] {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// revisionFile returns the revision log of symbol under path
+func revisionFile(path, symbol string) string {
+	return filepath.Join(path, RevisionDir, symbol+".ndjson")
+}
+
+// Revisions returns the revisions recorded for symbol under path, ordered by
+// date and version, or none if there is no log
+func Revisions(path, symbol string) ([]Revision, error) {
+	f, err := os.Open(revisionFile(path, symbol))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	revs := []Revision{}
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var r Revision
+		if err := dec.Decode(&r); err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(f.Name()), err)
+		}
+		revs = append(revs, r)
+	}
+	sort.SliceStable(revs, func(i, j int) bool {
+		if revs[i].Date != revs[j].Date {
+			return revs[i].Date < revs[j].Date
+		}
+		return revs[i].Version < revs[j].Version
+	})
+	return revs, nil
+}
+
+// appendRevisions adds revs to the log of symbol, synced before returning so
+// that the restated rows are only saved once their history is
+func appendRevisions(path, symbol string, revs []Revision) error {
+	name := revisionFile(path, symbol)
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range revs {
+		if err = enc.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// AsOf returns rows as they were known at on: restated values are replaced
+// by the version fetched last before on, and dates after on or first fetched
+// after on are left out. The first fetch of a row without revisions is given
+// by fetched, see FetchedAt; rows it returns the zero time for, or all if it
+// is nil, are taken as known on their date.
+func AsOf(rows []api.Wiki, revs []Revision, fetched func(date string) time.Time, on time.Time) []api.Wiki {
+	byDate := map[string][]Revision{}
+	for _, r := range revs {
+		byDate[r.Date] = append(byDate[r.Date], r)
+	}
+	last := on.UTC().Format(DateLayout)
+
+	out := make([]api.Wiki, 0, len(rows))
+	for _, obj := range rows {
+		if *obj.Date > last {
+			continue
+		}
+		rs := byDate[*obj.Date]
+		if len(rs) == 0 {
+			if fetched != nil && fetched(*obj.Date).After(on) {
+				continue
+			}
+			out = append(out, obj)
+			continue
+		}
+		if !rs[0].KnownFrom.IsZero() && on.Before(rs[0].KnownFrom) {
+			continue
+		}
+		obj = rs[0].Old
+		for _, r := range rs {
+			if r.FetchedAt.After(on) {
+				break
+			}
+			obj = r.New
+		}
+		out = append(out, obj)
+	}
+	return out
+}
+
+// FetchedAt returns a func giving when the saved daily file of a date of
+// symbol under path was fetched, from its manifest entry or else the fetch
+// time of the manifest. It gives the zero time for dates of compacted files,
+// which only record their last fetch, and for partitioned layouts, which have
+// no manifest.
+func FetchedAt(path string, t *layout.Template, symbol string) (func(date string) time.Time, error) {
+	if t != nil {
+		return func(string) time.Time { return time.Time{} }, nil
+	}
+	man, err := manifest.Load(symbolDir(path, symbol))
+	if err != nil {
+		return nil, err
+	}
+	return func(date string) time.Time {
+		for _, ext := range []string{".json", ".parquet"} {
+			if e, ok := man.Files[date+ext]; ok {
+				if e.FetchedAt.IsZero() {
+					return man.FetchedAt
+				}
+				return e.FetchedAt
+			}
+		}
+		return time.Time{}
+	}, nil
+}
+
+// Revisions returns the revisions recorded for symbol
+func (r *Reader) Revisions(symbol string) ([]Revision, error) {
+	return Revisions(r.path, symbol)
+}
+
+// LoadAsOf returns the rows of symbol from from to to as they were known at
+// on, see AsOf
+func (r *Reader) LoadAsOf(symbol, from, to string, on time.Time) ([]api.Wiki, error) {
+	objs, err := r.Load(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+	revs, err := r.Revisions(symbol)
+	if err != nil {
+		return nil, err
+	}
+	fetched, err := FetchedAt(r.path, r.layout, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return AsOf(objs, revs, fetched, on), nil
+}
+
+// Tracker is a sink that compares fetched rows with the rows saved under
+// Path, by Layout if set, and records the restated ones in
+// Path/revisions/<SYMBOL>.ndjson. New and restated rows are then passed to
+// Sink, which must replace saved dates: an api.JSONFiles or api.ParquetFiles
+// with Overwrite set, or a Partitioned sink.
+type Tracker struct {
+	Path    string
+	Layout  *layout.Template
+	Sink    api.Sink
+	Log     logging.Logger
+	Metrics metrics.Metrics
+}
+
+func (s *Tracker) Write(src api.Source, objs []api.Wiki) error {
+	l, m := logging.OrDiscard(s.Log), metrics.OrDiscard(s.Metrics)
+
+	saved, err := s.saved(src.Symbol)
+	if err != nil {
+		return err
+	}
+	logged, err := Revisions(s.Path, src.Symbol)
+	if err != nil {
+		return err
+	}
+	latest := map[string]Revision{}
+	for _, r := range logged {
+		latest[r.Date] = r
+	}
+	knownFrom, err := FetchedAt(s.Path, s.Layout, src.Symbol)
+	if err != nil {
+		return err
+	}
+
+	revs := []Revision{}
+	out := make([]api.Wiki, 0, len(objs))
+	for _, obj := range objs {
+		if obj.Date == nil {
+			continue
+		}
+		old, ok := saved[*obj.Date]
+		if !ok {
+			out = append(out, obj)
+			continue
+		}
+		fields := Changes(old, obj)
+		if len(fields) == 0 {
+			continue
+		}
+		out = append(out, obj)
+
+		// an interrupted write logged this revision but did not save it
+		prev, ok := latest[*obj.Date]
+		if ok && len(Changes(prev.New, obj)) == 0 {
+			continue
+		}
+		r := Revision{
+			Symbol:    src.Symbol,
+			Date:      *obj.Date,
+			Version:   2,
+			Fields:    fields,
+			Old:       old,
+			New:       obj,
+			KnownFrom: knownFrom(*obj.Date),
+			FetchedAt: src.FetchedAt,
+		}
+		if ok {
+			r.Version = prev.Version + 1
+			r.KnownFrom = prev.FetchedAt
+		}
+		revs = append(revs, r)
+	}
+
+	if len(revs) > 0 {
+		if err := appendRevisions(s.Path, src.Symbol, revs); err != nil {
+			return err
+		}
+		m.Add(metrics.RowsRevised, float64(len(revs)), metrics.DB, src.DBCode)
+		l.Info("Recorded revisions.", logging.Symbol, src.Symbol, logging.Rows, len(revs), "first_date", revs[0].Date)
+	}
+	return s.Sink.Write(src, out)
+}
+
+// saved returns the rows saved for symbol by date
+func (s *Tracker) saved(symbol string) (map[string]api.Wiki, error) {
+	var (
+		objs []api.Wiki
+		err  error
+	)
+	if s.Layout != nil {
+		objs, err = readPartitions(s.Path, s.Layout, symbol, "", "")
+	} else {
+		objs, err = Read(s.Path, symbol)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	rows := make(map[string]api.Wiki, len(objs))
+	for _, obj := range objs {
+		rows[*obj.Date] = obj
+	}
+	return rows, nil
+}
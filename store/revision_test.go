@@ -0,0 +1,146 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	"github.com/twold/go-quandl/layout"
+	. "github.com/twold/go-quandl/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Revisions", func() {
+	var (
+		path    string
+		tracker *Tracker
+		day1    = time.Date(2018, 1, 2, 22, 0, 0, 0, time.UTC)
+		day2    = time.Date(2018, 3, 1, 22, 0, 0, 0, time.UTC)
+		day3    = time.Date(2018, 4, 1, 22, 0, 0, 0, time.UTC)
+	)
+
+	src := func(at time.Time) api.Source {
+		return api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: at}
+	}
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "revision")
+		Expect(err).Should(BeNil())
+		tracker = &Tracker{Path: path, Sink: &api.JSONFiles{Path: path, Overwrite: true}}
+
+		err = tracker.Write(src(day1), []api.Wiki{fixture.Wiki("2017-12-29", 1), fixture.Wiki("2018-01-02", 2)})
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path)
+	})
+
+	It("should not record new or unchanged rows", func() {
+		err := tracker.Write(src(day2), []api.Wiki{fixture.Wiki("2017-12-29", 1), fixture.Wiki("2018-01-02", 2), fixture.Wiki("2018-01-03", 3)})
+		Expect(err).Should(BeNil())
+
+		revs, err := Revisions(path, "FB")
+		Expect(err).Should(BeNil())
+		Expect(revs).Should(BeEmpty())
+		objs, err := Read(path, "FB")
+		Expect(err).Should(BeNil())
+		Expect(len(objs)).Should(Equal(3))
+	})
+
+	It("should record restated rows and save the new values", func() {
+		err := tracker.Write(src(day2), []api.Wiki{fixture.Wiki("2017-12-29", 0.5), fixture.Wiki("2018-01-02", 2)})
+		Expect(err).Should(BeNil())
+		err = tracker.Write(src(day3), []api.Wiki{fixture.Wiki("2017-12-29", 0.25)})
+		Expect(err).Should(BeNil())
+
+		revs, err := Revisions(path, "FB")
+		Expect(err).Should(BeNil())
+		Expect(len(revs)).Should(Equal(2))
+		Expect(revs[0].Date).Should(Equal("2017-12-29"))
+		Expect(revs[0].Version).Should(Equal(2))
+		Expect(revs[0].Fields).Should(Equal([]string{"Close", "AdjClose"}))
+		Expect(*revs[0].Old.Close).Should(Equal(1.0))
+		Expect(*revs[0].New.Close).Should(Equal(0.5))
+		Expect(revs[0].KnownFrom.Equal(day1)).Should(BeTrue())
+		Expect(revs[0].FetchedAt.Equal(day2)).Should(BeTrue())
+		Expect(revs[1].Version).Should(Equal(3))
+		Expect(revs[1].KnownFrom.Equal(day2)).Should(BeTrue())
+
+		objs, err := Read(path, "FB")
+		Expect(err).Should(BeNil())
+		Expect(*objs[0].Close).Should(Equal(0.25))
+	})
+
+	It("should not record a revision twice", func() {
+		restated := []api.Wiki{fixture.Wiki("2017-12-29", 0.5)}
+		Expect(tracker.Write(src(day2), restated)).Should(BeNil())
+		Expect(tracker.Write(src(day3), restated)).Should(BeNil())
+
+		revs, err := Revisions(path, "FB")
+		Expect(err).Should(BeNil())
+		Expect(len(revs)).Should(Equal(1))
+	})
+
+	It("should load rows as known on a date", func() {
+		Expect(tracker.Write(src(day2), []api.Wiki{fixture.Wiki("2017-12-29", 0.5), fixture.Wiki("2018-01-03", 3)})).Should(BeNil())
+		Expect(tracker.Write(src(day3), []api.Wiki{fixture.Wiki("2017-12-29", 0.25)})).Should(BeNil())
+
+		r, err := Open(path)
+		Expect(err).Should(BeNil())
+		closes := func(on time.Time) []float64 {
+			objs, err := r.LoadAsOf("FB", "", "", on)
+			Expect(err).Should(BeNil())
+			out := []float64{}
+			for _, obj := range objs {
+				out = append(out, *obj.Close)
+			}
+			return out
+		}
+
+		Expect(closes(day1.Add(-time.Hour))).Should(BeEmpty())
+		Expect(closes(day1)).Should(Equal([]float64{1, 2}))
+		Expect(closes(day2.Add(-time.Hour))).Should(Equal([]float64{1, 2}))
+		Expect(closes(day2)).Should(Equal([]float64{0.5, 2, 3}))
+		Expect(closes(day3)).Should(Equal([]float64{0.25, 2, 3}))
+
+		// without fetch times rows are known on their date
+		objs, err := r.Load("FB", "2018-01-02", "")
+		Expect(err).Should(BeNil())
+		Expect(AsOf(objs, nil, nil, day1.Add(-time.Hour))).Should(HaveLen(1))
+	})
+
+	It("should track partitioned layouts", func() {
+		t, err := layout.Parse(layout.Hive)
+		Expect(err).Should(BeNil())
+		lake := filepath.Join(path, "lake")
+		Expect(os.Mkdir(lake, 0777)).Should(BeNil())
+		part := &Tracker{Path: lake, Layout: t, Sink: &Partitioned{Path: lake, Layout: t}}
+
+		Expect(part.Write(src(day1), []api.Wiki{fixture.Wiki("2017-12-29", 1)})).Should(BeNil())
+		Expect(part.Write(src(day2), []api.Wiki{fixture.Wiki("2017-12-29", 0.5)})).Should(BeNil())
+
+		r, err := OpenLayout(lake, t)
+		Expect(err).Should(BeNil())
+		objs, err := r.LoadAsOf("FB", "", "", day1)
+		Expect(err).Should(BeNil())
+		Expect(*objs[0].Close).Should(Equal(1.0))
+		objs, err = r.Load("FB", "", "")
+		Expect(err).Should(BeNil())
+		Expect(*objs[0].Close).Should(Equal(0.5))
+	})
+
+	It("should list the changed columns", func() {
+		a, b := fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-02", 1)
+		Expect(Changes(a, b)).Should(BeEmpty())
+		v := 100.0
+		b.Volume = &v
+		Expect(Changes(a, b)).Should(Equal([]string{"Volume"}))
+	})
+})
@@ -0,0 +1,89 @@
+// Package store manages the data saved under <path>/output by the api sinks:
+// one folder per symbol holding a file per day and a manifest.
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DateLayout is the date format of file names and the Date field
+const DateLayout = "2006-01-02"
+
+// Range is an inclusive range of dates formatted as YYYY-MM-DD
+type Range struct {
+	Start string `json:"start" type:"string"`
+	End   string `json:"end" type:"string"`
+}
+
+// Symbols returns the symbols saved under path/output
+func Symbols(path string) ([]string, error) {
+	dirs, err := ioutil.ReadDir(filepath.Join(path, "output"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if d.IsDir() {
+			symbols = append(symbols, d.Name())
+		}
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+func symbolDir(path, symbol string) string {
+	return filepath.Join(path, "output", symbol)
+}
+
+// Weekdays returns every Monday to Friday from start to end. It is the
+// default session calendar and does not know about holidays.
+func Weekdays(start, end time.Time) []time.Time {
+	days := []time.Time{}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// merge sorts ranges and joins those that overlap or are at most gap days apart
+func merge(spans []Range, gap int) []Range {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	out := []Range{}
+	for _, r := range spans {
+		if n := len(out); n > 0 && within(out[n-1].End, r.Start, gap) {
+			if r.End > out[n-1].End {
+				out[n-1].End = r.End
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// within reports whether b is at most gap days after a
+func within(a, b string, gap int) bool {
+	if b <= a {
+		return true
+	}
+	ta, err := time.Parse(DateLayout, a)
+	if err != nil {
+		return false
+	}
+	tb, err := time.Parse(DateLayout, b)
+	if err != nil {
+		return false
+	}
+	return tb.Sub(ta) <= time.Duration(gap)*24*time.Hour
+}
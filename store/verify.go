@@ -0,0 +1,232 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+	"github.com/twold/go-quandl/manifest"
+)
+
+// Problem kinds
+const (
+	// Unreadable files do not parse into a Wiki row
+	Unreadable = "unreadable"
+	// Unrecorded files are not in the manifest, e.g. left by an interrupted run
+	Unrecorded = "unrecorded"
+	// Lost files are in the manifest but not on disk
+	Lost = "lost"
+	// Corrupt files differ in size or checksum from the manifest
+	Corrupt = "corrupt"
+	// MissingDay is a trading day without a file
+	MissingDay = "missing_day"
+	// Leftover temporary files of an interrupted write
+	Leftover = "leftover"
+)
+
+// Problem is found in a file or on a date. Problems of compacted files span
+// the dates from Date to End.
+type Problem struct {
+	Kind   string `json:"kind" type:"string"`
+	File   string `json:"file,omitempty" type:"string"`
+	Date   string `json:"date,omitempty" type:"string"`
+	End    string `json:"end,omitempty" type:"string"`
+	Detail string `json:"detail,omitempty" type:"string"`
+}
+
+// Report is the result of verifying one symbol
+type Report struct {
+	Symbol    string    `json:"symbol" type:"string"`
+	Files     int       `json:"files" type:"int"`
+	FirstDate string    `json:"first_date,omitempty" type:"string"`
+	LastDate  string    `json:"last_date,omitempty" type:"string"`
+	Expected  *Range    `json:"expected,omitempty" type:"struct"`
+	Problems  []Problem `json:"problems" type:"list"`
+}
+
+// OK reports whether no problem was found
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Gaps returns the date ranges to fetch again to repair the symbol
+func (r *Report) Gaps() []Range {
+	spans := []Range{}
+	for _, p := range r.Problems {
+		if p.Date == "" || p.Kind == Leftover {
+			continue
+		}
+		end := p.End
+		if end == "" {
+			end = p.Date
+		}
+		spans = append(spans, Range{Start: p.Date, End: end})
+	}
+	// one request per gap, joining gaps over weekends and short holidays
+	return merge(spans, 5)
+}
+
+type VerifyOptions struct {
+	// Meta returns the data set metadata used to extend the expected date
+	// range to the oldest and newest available dates, optional
+	Meta func(symbol string) (*api.Metadata, error)
+	// Sessions returns the trading days from start to end, defaults to Weekdays
+	Sessions func(start, end time.Time) []time.Time
+	// MissingDays reports trading days without a file
+	MissingDays bool
+}
+
+// Verify checks every file of symbol under path/output: that it parses, that
+// it matches the manifest, and optionally that no trading day is missing
+func Verify(path, symbol string, opts VerifyOptions) (*Report, error) {
+	dir := symbolDir(path, symbol)
+	man, err := manifest.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Symbol: symbol, Problems: []Problem{}}
+	add := func(kind, file, date, format string, a ...interface{}) {
+		p := Problem{Kind: kind, File: file, Date: date, Detail: fmt.Sprintf(format, a...)}
+		// files of several days are repaired by fetching their whole range
+		if e, ok := man.Files[file]; ok && dateOf(file) == "" {
+			p.Date, p.End = e.FirstDate, e.LastDate
+		}
+		r.Problems = append(r.Problems, p)
+	}
+
+	have := map[string]bool{}
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || name == manifest.Name {
+			continue
+		}
+		if atomicfile.IsTemp(name) {
+			add(Leftover, name, "", "temporary file of an interrupted write")
+			continue
+		}
+		if !isDataFile(name) {
+			continue
+		}
+		r.Files++
+
+		// dates of compacted files are only known once they are read
+		date := dateOf(name)
+		e, ok := man.Files[name]
+		if !ok {
+			add(Unrecorded, name, date, "not in %s", manifest.Name)
+		} else {
+			size, sum, err := manifest.SumFile(filepath.Join(dir, name))
+			switch {
+			case err != nil:
+				add(Unreadable, name, date, "%v", err)
+				continue
+			case size != e.Size:
+				add(Corrupt, name, date, "size %d, manifest has %d", size, e.Size)
+			case sum != e.SHA256:
+				add(Corrupt, name, date, "checksum does not match manifest")
+			}
+		}
+
+		objs, err := readFile(filepath.Join(dir, name))
+		if err != nil {
+			add(Unreadable, name, date, "%v", err)
+			continue
+		}
+		for _, obj := range objs {
+			d := *obj.Date
+			have[d] = true
+			if r.FirstDate == "" || d < r.FirstDate {
+				r.FirstDate = d
+			}
+			if d > r.LastDate {
+				r.LastDate = d
+			}
+		}
+	}
+
+	for _, name := range man.Names() {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			add(Lost, name, strings.TrimSuffix(name, filepath.Ext(name)), "in %s but not on disk", manifest.Name)
+		}
+	}
+
+	if opts.Meta != nil || opts.MissingDays {
+		expected := Range{Start: r.FirstDate, End: r.LastDate}
+		if opts.Meta != nil {
+			meta, err := opts.Meta(symbol)
+			if err != nil {
+				return nil, err
+			}
+			if meta.OldestAvailableDate != nil && *meta.OldestAvailableDate != "" {
+				expected.Start = *meta.OldestAvailableDate
+			}
+			if meta.NewestAvailableDate != nil && *meta.NewestAvailableDate != "" {
+				expected.End = *meta.NewestAvailableDate
+			}
+		}
+		r.Expected = &expected
+
+		missing, err := missingDays(expected, have, opts.Sessions)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range missing {
+			add(MissingDay, "", d, "no file for trading day")
+		}
+	}
+	return r, nil
+}
+
+// VerifyAll verifies every symbol saved under path
+func VerifyAll(path string, opts VerifyOptions) ([]*Report, error) {
+	symbols, err := Symbols(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, 0, len(symbols))
+	for _, symbol := range symbols {
+		r, err := Verify(path, symbol, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", symbol, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+func missingDays(expected Range, have map[string]bool, sessions func(start, end time.Time) []time.Time) ([]string, error) {
+	if expected.Start == "" || expected.End == "" {
+		return nil, nil
+	}
+	start, err := time.Parse(DateLayout, expected.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(DateLayout, expected.End)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = Weekdays
+	}
+
+	missing := []string{}
+	for _, d := range sessions(start, end) {
+		if s := d.Format(DateLayout); !have[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,92 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verify", func() {
+	var (
+		path string
+		dir  string
+		sink *api.JSONFiles
+		src  = api.Source{Symbol: "FB", DBCode: "WIKI", FetchedAt: time.Now()}
+	)
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "store")
+		Expect(err).Should(BeNil())
+		dir = filepath.Join(path, "output", "FB")
+		sink = &api.JSONFiles{Path: path}
+
+		// Friday 2018-01-05 is left out
+		err = sink.Write(src, []api.Wiki{fixture.Wiki("2018-01-02", 1), fixture.Wiki("2018-01-03", 2), fixture.Wiki("2018-01-04", 3), fixture.Wiki("2018-01-08", 4)})
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path)
+	})
+
+	Context("When the store is intact", func() {
+		It("reports no problems", func() {
+			actual, err := Verify(path, "FB", VerifyOptions{})
+			Expect(err).Should(BeNil())
+			Expect(actual.OK()).Should(BeTrue())
+			Expect(actual.Files).Should(Equal(4))
+			Expect(actual.FirstDate).Should(Equal("2018-01-02"))
+			Expect(actual.LastDate).Should(Equal("2018-01-08"))
+		})
+	})
+
+	Context("When files are damaged or missing", func() {
+		It("reports each problem", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "2018-01-03.json"), []byte(`{"Date": "2018-01-03", "Close": 9}`), 0666)).Should(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "2018-01-09.json"), []byte(`{"Da`), 0666)).Should(BeNil())
+
+			actual, err := Verify(path, "FB", VerifyOptions{MissingDays: true})
+			Expect(err).Should(BeNil())
+			Expect(actual.Problems).Should(ConsistOf(
+				Problem{Kind: Corrupt, File: "2018-01-03.json", Date: "2018-01-03", Detail: "size 34, manifest has 269"},
+				Problem{Kind: Unrecorded, File: "2018-01-09.json", Date: "2018-01-09", Detail: "not in manifest.json"},
+				Problem{Kind: Unreadable, File: "2018-01-09.json", Date: "2018-01-09", Detail: "unexpected end of JSON input"},
+				Problem{Kind: MissingDay, Date: "2018-01-05", Detail: "no file for trading day"},
+			))
+			Expect(actual.Gaps()).Should(Equal([]Range{{Start: "2018-01-03", End: "2018-01-09"}}))
+		})
+	})
+
+	Context("When I repair a symbol", func() {
+		It("fetches the gaps again", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "2018-01-03.json"), []byte(`{"Da`), 0666)).Should(BeNil())
+			r, err := Verify(path, "FB", VerifyOptions{MissingDays: true})
+			Expect(err).Should(BeNil())
+
+			var fetched []Range
+			fetch := func(symbol, start, end string) (*api.DataSet, error) {
+				fetched = append(fetched, Range{Start: start, End: end})
+				return &api.DataSet{
+					Source: src,
+					Data:   []api.Wiki{fixture.Wiki("2018-01-03", 2), fixture.Wiki("2018-01-04", 3), fixture.Wiki("2018-01-05", 5)},
+				}, nil
+			}
+			_, err = Repair(path, r, fetch, sink)
+			Expect(err).Should(BeNil())
+			Expect(fetched).Should(Equal([]Range{{Start: "2018-01-03", End: "2018-01-05"}}))
+
+			actual, err := Verify(path, "FB", VerifyOptions{MissingDays: true})
+			Expect(err).Should(BeNil())
+			Expect(actual.Problems).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,301 @@
+// Package xlsx writes tables as an Excel workbook, with one sheet per symbol
+// or, given the sectors of the input list, one sheet per sector. Dates are
+// written as date cells and values as numbers, so that spreadsheets sort and
+// chart them without conversion, and the header row stays frozen on top.
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/atomicfile"
+)
+
+// DateLayout is the format of the date column of tables
+const DateLayout = "2006-01-02"
+
+// DateFormat is the number format of date cells
+const DateFormat = "yyyy-mm-dd"
+
+// Other is the sheet of symbols without a sector
+const Other = "Other"
+
+// maxName is the longest sheet name excel accepts
+const maxName = 31
+
+// Options control the sheets and columns of a workbook
+type Options struct {
+	// symbol to sector; when set there is one sheet per sector, with a
+	// Symbol column first
+	Sectors map[string]string
+
+	// only the date and the adjusted columns
+	Adjusted bool
+}
+
+// Sectors returns the sector of every symbol of list
+func Sectors(list []api.List) map[string]string {
+	m := make(map[string]string, len(list))
+	for _, item := range list {
+		m[item.Symbol] = item.Sector
+	}
+	return m
+}
+
+// Adjusted returns the indexes of the date column and of the adjusted
+// columns of t, 'AdjClose' or 'Adj. Close'
+func Adjusted(t *api.Table) []int {
+	if len(t.Columns) == 0 {
+		return nil
+	}
+	idx := []int{0}
+	for i, name := range t.Columns[1:] {
+		if strings.HasPrefix(name, "Adj") {
+			idx = append(idx, i+1)
+		}
+	}
+	return idx
+}
+
+type sheet struct {
+	name   string
+	tables []*api.Table
+}
+
+// sheets groups tables by symbol or sector, in the order they come
+func sheets(tables []*api.Table, o Options) []*sheet {
+	var out []*sheet
+	byName := map[string]*sheet{}
+	for _, t := range tables {
+		name := t.Symbol
+		if o.Sectors != nil {
+			name = o.Sectors[t.Symbol]
+			if name == "" {
+				name = Other
+			}
+		}
+		s, ok := byName[name]
+		if !ok {
+			s = &sheet{name: name}
+			byName[name] = s
+			out = append(out, s)
+		}
+		s.tables = append(s.tables, t)
+	}
+	return out
+}
+
+// SheetName returns name without the characters excel rejects in sheet
+// names, cut to 31 characters
+func SheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	for utf8.RuneCountInString(name) > maxName {
+		_, n := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-n]
+	}
+	if name == "" {
+		name = "_"
+	}
+	return name
+}
+
+// unique returns SheetName(name), with a number when it is already taken
+func unique(name string, taken map[string]bool) string {
+	name = SheetName(name)
+	base := name
+	for i := 2; taken[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = base
+		for utf8.RuneCountInString(name)+len(suffix) > maxName {
+			_, n := utf8.DecodeLastRuneInString(name)
+			name = name[:len(name)-n]
+		}
+		name += suffix
+	}
+	taken[strings.ToLower(name)] = true
+	return name
+}
+
+// Write writes tables to w as a workbook. Tables on the same sheet must have
+// the same columns.
+func Write(w io.Writer, tables []*api.Table, o Options) error {
+	if len(tables) == 0 {
+		return fmt.Errorf("no tables")
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+
+	header, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	format := DateFormat
+	date, err := f.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	if err != nil {
+		return err
+	}
+
+	first := f.GetSheetName(0)
+	taken := map[string]bool{}
+	for _, s := range sheets(tables, o) {
+		name := unique(s.name, taken)
+		if strings.EqualFold(name, first) {
+			if err := f.SetSheetName(first, name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(name); err != nil {
+			return err
+		}
+		if err := writeSheet(f, name, s.tables, o, header, date); err != nil {
+			return fmt.Errorf("%s: %v", s.name, err)
+		}
+	}
+	if !taken[strings.ToLower(first)] {
+		if err := f.DeleteSheet(first); err != nil {
+			return err
+		}
+	}
+	f.SetActiveSheet(0)
+	return f.Write(w)
+}
+
+func writeSheet(f *excelize.File, name string, tables []*api.Table, o Options, header, date int) error {
+	sw, err := f.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	err = sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return err
+	}
+
+	idx := columns(tables[0], o)
+	var cols []string
+	if o.Sectors != nil {
+		cols = append(cols, "Symbol")
+	}
+	for _, i := range idx {
+		cols = append(cols, tables[0].Columns[i])
+	}
+	for _, t := range tables[1:] {
+		if !equal(t.Columns, tables[0].Columns) {
+			return fmt.Errorf("table %s has other columns than table %s", t.Symbol, tables[0].Symbol)
+		}
+	}
+
+	dateCol := 1
+	if o.Sectors != nil {
+		dateCol = 2
+	}
+	if err := sw.SetColWidth(dateCol, dateCol, 12); err != nil {
+		return err
+	}
+	cells := make([]interface{}, len(cols))
+	for i, c := range cols {
+		cells[i] = excelize.Cell{StyleID: header, Value: c}
+	}
+	if err := sw.SetRow("A1", cells); err != nil {
+		return err
+	}
+
+	row := 2
+	for _, t := range tables {
+		for _, r := range t.Rows {
+			if len(r) != len(t.Columns) {
+				return fmt.Errorf("%s: row has %d values, want %d", t.Symbol, len(r), len(t.Columns))
+			}
+			cells = cells[:0]
+			if o.Sectors != nil {
+				cells = append(cells, t.Symbol)
+			}
+			for _, i := range idx {
+				cells = append(cells, cell(r[i], i == 0, date))
+			}
+			cell, err := excelize.CoordinatesToCellName(1, row)
+			if err != nil {
+				return err
+			}
+			if err := sw.SetRow(cell, cells); err != nil {
+				return err
+			}
+			row++
+		}
+	}
+	return sw.Flush()
+}
+
+// columns returns the indexes of the columns of t to write
+func columns(t *api.Table, o Options) []int {
+	if o.Adjusted {
+		return Adjusted(t)
+	}
+	idx := make([]int, len(t.Columns))
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// cell returns v as a date cell for the date column, as a number or text
+// otherwise
+func cell(v interface{}, isDate bool, date int) interface{} {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(string); ok && isDate {
+		if t, err := time.Parse(DateLayout, s); err == nil {
+			return excelize.Cell{StyleID: date, Value: t}
+		}
+	}
+	return v
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteFile writes tables to the workbook name, atomically
+func WriteFile(name string, tables []*api.Table, o Options) error {
+	tmp := atomicfile.TempName(name)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = Write(f, tables, o)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = atomicfile.Commit(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
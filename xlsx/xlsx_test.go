@@ -0,0 +1,139 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/twold/go-quandl/api"
+	"github.com/twold/go-quandl/internal/fixture"
+	. "github.com/twold/go-quandl/xlsx"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func open(tables []*api.Table, o Options) *excelize.File {
+	var buf bytes.Buffer
+	Expect(Write(&buf, tables, o)).Should(BeNil())
+	f, err := excelize.OpenReader(&buf)
+	Expect(err).Should(BeNil())
+	return f
+}
+
+var _ = Describe("Xlsx", func() {
+	fb := api.WikiTable("FB", []api.Wiki{fixture.Wiki("2018-01-02", 1.5), fixture.Wiki("2018-01-03", 2)})
+	aapl := api.WikiTable("AAPL", []api.Wiki{fixture.Wiki("1980-12-12", 0.5)})
+	xom := api.WikiTable("XOM", []api.Wiki{fixture.Wiki("2018-01-02", 85)})
+
+	It("should write one sheet per symbol", func() {
+		f := open([]*api.Table{fb, aapl}, Options{})
+		defer f.Close()
+		Expect(f.GetSheetList()).Should(Equal([]string{"FB", "AAPL"}))
+
+		rows, err := f.GetRows("FB")
+		Expect(err).Should(BeNil())
+		Expect(rows).Should(HaveLen(3))
+		Expect(rows[0]).Should(Equal(api.WikiColumns[:len(rows[0])]))
+		Expect(rows[1][0]).Should(Equal("2018-01-02"))
+
+		id, err := f.GetCellStyle("FB", "A2")
+		Expect(err).Should(BeNil())
+		style, err := f.GetStyle(id)
+		Expect(err).Should(BeNil())
+		Expect(*style.CustomNumFmt).Should(Equal(DateFormat))
+		v, err := f.GetCellValue("FB", "A2", excelize.Options{RawCellValue: true})
+		Expect(err).Should(BeNil())
+		Expect(v).Should(Equal("43102"))
+		v, err = f.GetCellValue("FB", "F3", excelize.Options{RawCellValue: true})
+		Expect(err).Should(BeNil())
+		Expect(v).Should(Equal("2"))
+
+		typ, err := f.GetCellType("FB", "B2")
+		Expect(err).Should(BeNil())
+		Expect(typ).Should(Equal(excelize.CellTypeInlineString))
+	})
+
+	It("should freeze the header row", func() {
+		f := open([]*api.Table{fb}, Options{})
+		defer f.Close()
+		panes, err := f.GetPanes("FB")
+		Expect(err).Should(BeNil())
+		Expect(panes.Freeze).Should(BeTrue())
+		Expect(panes.YSplit).Should(Equal(1))
+		Expect(panes.TopLeftCell).Should(Equal("A2"))
+	})
+
+	It("should write one sheet per sector", func() {
+		list := []api.List{
+			{Symbol: "FB", Sector: "Technology"},
+			{Symbol: "AAPL", Sector: "Technology"},
+		}
+		f := open([]*api.Table{fb, xom, aapl}, Options{Sectors: Sectors(list)})
+		defer f.Close()
+		Expect(f.GetSheetList()).Should(Equal([]string{"Technology", Other}))
+
+		rows, err := f.GetRows("Technology")
+		Expect(err).Should(BeNil())
+		Expect(rows).Should(HaveLen(4))
+		Expect(rows[0][:2]).Should(Equal([]string{"Symbol", "Date"}))
+		Expect(rows[1][0]).Should(Equal("FB"))
+		Expect(rows[3][:2]).Should(Equal([]string{"AAPL", "1980-12-12"}))
+
+		rows, err = f.GetRows(Other)
+		Expect(err).Should(BeNil())
+		Expect(rows[1][0]).Should(Equal("XOM"))
+	})
+
+	It("should keep only the adjusted columns", func() {
+		f := open([]*api.Table{fb}, Options{Adjusted: true})
+		defer f.Close()
+		rows, err := f.GetRows("FB")
+		Expect(err).Should(BeNil())
+		Expect(rows[0]).Should(Equal([]string{"Date", "AdjOpen", "AdjHigh", "AdjLow", "AdjClose", "AdjVolume"}))
+		Expect(rows[2]).Should(Equal([]string{"2018-01-03", "", "", "", "2"}))
+
+		ds := &api.Table{Columns: []string{"Date", "Open", "Adj. Close"}}
+		Expect(Adjusted(ds)).Should(Equal([]int{0, 2}))
+	})
+
+	It("should clean sheet names", func() {
+		Expect(SheetName("BRK/B")).Should(Equal("BRK_B"))
+		Expect(SheetName("[a]:b?*")).Should(Equal("_a__b__"))
+		Expect(SheetName("Consumer Discretionary and Staples Sector")).Should(HaveLen(31))
+
+		t := api.WikiTable("A/B", []api.Wiki{fixture.Wiki("2018-01-02", 1)})
+		u := api.WikiTable("A:B", []api.Wiki{fixture.Wiki("2018-01-02", 1)})
+		f := open([]*api.Table{t, u}, Options{})
+		defer f.Close()
+		Expect(f.GetSheetList()).Should(Equal([]string{"A_B", "A_B (2)"}))
+	})
+
+	It("should reject tables with other columns on the same sheet", func() {
+		raw := &api.Table{Symbol: "VX1", Columns: []string{"Trade Date", "Settle"}}
+		var buf bytes.Buffer
+		err := Write(&buf, []*api.Table{fb, raw}, Options{Sectors: map[string]string{}})
+		Expect(err).ShouldNot(BeNil())
+		Expect(Write(&buf, nil, Options{})).ShouldNot(BeNil())
+	})
+
+	It("should write a file atomically", func() {
+		dir, err := ioutil.TempDir("", "xlsx")
+		Expect(err).Should(BeNil())
+		defer os.RemoveAll(dir)
+
+		name := filepath.Join(dir, "wiki.xlsx")
+		Expect(WriteFile(name, []*api.Table{fb, aapl}, Options{})).Should(BeNil())
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).Should(BeNil())
+		Expect(files).Should(HaveLen(1))
+
+		f, err := excelize.OpenFile(name)
+		Expect(err).Should(BeNil())
+		defer f.Close()
+		Expect(f.GetSheetList()).Should(HaveLen(2))
+	})
+})